@@ -0,0 +1,46 @@
+// Package app позволяет встроить auth-service в другой процесс
+// (см. модуль single-node), не открывая внутренние пакеты.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"auth-service/internal/config"
+	"auth-service/internal/handlers"
+	"auth-service/internal/storage"
+	"auth-service/internal/tools"
+	"common/audit"
+	"common/auth"
+	"common/health"
+	"common/ratelimit"
+)
+
+// Config — настройки auth-service; встраивающий процесс загружает их сам
+// и проверяет через Validate.
+type Config = config.Config
+
+// NewSQLiteHandler собирает обработчики auth-service поверх уже открытой
+// SQLite-базы, применяя миграции пользователей и журнала аудита. Счетчики лимитов
+// хранятся в памяти.
+func NewSQLiteHandler(ctx context.Context, db *sql.DB, cfg *Config, checks *health.Checks) (http.Handler, error) {
+	if err := tools.Configure(cfg); err != nil {
+		return nil, err
+	}
+
+	users, err := storage.NewSQLiteStore(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	auditStore, err := audit.NewSQLiteStore(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := handlers.NewLimiter(ratelimit.NewMemory(), cfg.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	recorder := audit.NewRecorder(auditStore, cfg.RateLimit.TrustProxy)
+	return handlers.NewServer(users, checks, auth.New(cfg.JWTSecret), limiter, recorder).Routes(), nil
+}
@@ -1,18 +1,219 @@
-package main
-
-import (
-	"log"
-	"net/http"
-	"auth-service/internal/handlers"
-)
-
-func main() {
-	http.HandleFunc("/api/auth/register", handlers.RegisterHandler)
-	http.HandleFunc("/api/auth/login", handlers.LoginHandler)
-	http.HandleFunc("/api/auth/logout", handlers.LogoutHandler)
-	http.HandleFunc("/api/auth/me", handlers.MeHandler)
-	http.HandleFunc("/health", handlers.HealthHandler)
-
-	log.Println(" Auth service starting on port 8080...")
-	log.Fatal(http.ListenAndServe(":8080", nil))
-}
\ No newline at end of file
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/handlers"
+	"auth-service/internal/storage"
+	"auth-service/internal/tools"
+	"common/audit"
+	"common/auth"
+	commonconfig "common/config"
+	"common/health"
+	"common/httpserver"
+	"common/logging"
+	"common/tracing"
+	"common/metrics"
+	"common/migrate"
+	"common/ratelimit"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+// Каждая проверка /readyz должна уложиться в timeoutSeconds пробы k8s
+const healthCheckTimeout = 2 * time.Second
+
+func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to YAML config file")
+	printConfig := flag.Bool("print-config", false, "print effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *printConfig {
+		if err := commonconfig.Dump(os.Stdout, cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	logging.Setup("auth-service", cfg.Logging)
+
+	// auth-service migrate [audit] status|up|down|to N
+	if flag.Arg(0) == "migrate" {
+		runMigrations(context.Background(), cfg, flag.Args()[1:])
+		return
+	}
+
+	if err := tools.Configure(cfg); err != nil {
+		fatal("Error configuring auth service", err)
+	}
+
+	// SIGTERM приходит от k8s при rolling update: дожидаемся текущих запросов и закрываем соединения с базой
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, "auth-service", cfg.Tracing)
+	if err != nil {
+		fatal("Error setting up tracing", err)
+	}
+	// Выполняется последним: спаны дописываются после остановки сервера и закрытия базы
+	defer shutdownTracing(context.Background())
+
+	checks := health.New(healthCheckTimeout)
+	checks.Add("shutdown", health.ShuttingDown(ctx))
+	authn := auth.New(cfg.JWTSecret)
+	checks.Add("jwt_secret", authn.Check)
+
+	users, auditStore, closeStore := openUserStore(ctx, cfg, checks)
+	defer closeStore()
+
+	// Счетчики лимитов общие для реплик через Redis; без него каждая реплика считает сама
+	limitStore := ratelimit.Store(ratelimit.NewMemory())
+	if cfg.Storage == "postgres" {
+		rdb := newRedisClient(cfg.Redis)
+		defer rdb.Close()
+		checks.AddOptional("redis", func(ctx context.Context) error { return rdb.Ping(ctx).Err() })
+		limitStore = ratelimit.WithFallback(ratelimit.NewRedis(rdb, "auth"), limitStore)
+	}
+	limiter, err := handlers.NewLimiter(limitStore, cfg.RateLimit)
+	if err != nil {
+		fatal("Error configuring rate limits", err)
+	}
+	recorder := audit.NewRecorder(auditStore, cfg.RateLimit.TrustProxy)
+	server := handlers.NewServer(users, checks, authn, limiter, recorder)
+
+	slog.Info("Auth service starting", "port", cfg.Port)
+	handler := tracing.Middleware("auth-service", metrics.Middleware(logging.Middleware(server.Routes())))
+	if err := httpserver.Run(ctx, fmt.Sprintf(":%d", cfg.Port), handler, cfg.HTTP); err != nil {
+		slog.Error("Auth service error", "error", err)
+		return
+	}
+	slog.Info("Auth service stopped")
+}
+
+// fatal пишет ошибку в журнал и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// openUserStore выбирает хранилище по AUTH_STORAGE: postgres (по умолчанию), sqlite или memory.
+// Журнал аудита живет в той же базе. Последним значением возвращается функция, закрывающая
+// соединения; проверка базы добавляется в checks.
+func openUserStore(ctx context.Context, cfg *config.Config, checks *health.Checks) (storage.UserStore, audit.Store, func()) {
+	switch cfg.Storage {
+	case "postgres":
+		pool, err := storage.NewPostgresPool(ctx, cfg.Database.DSN())
+		if err != nil {
+			fatal("Error connecting to database", err)
+		}
+
+		// Реплики мигрируют под advisory-блокировкой; MIGRATE_ON_START=false
+		// оставляет миграции отдельному запуску `migrate up`
+		if cfg.MigrateOnStart {
+			for _, load := range []func(*pgxpool.Pool) (*migrate.Migrator, error){storage.PostgresMigrator, audit.PostgresMigrator} {
+				migrator, err := load(pool)
+				if err != nil {
+					fatal("Error loading migrations", err)
+				}
+				if err := migrator.Up(ctx); err != nil {
+					fatal("Error migrating database", err)
+				}
+			}
+		}
+		checks.Add("postgres", pool.Ping)
+		if err := metrics.RegisterPool(pool); err != nil {
+			slog.Warn("Error registering pool metrics", "error", err)
+		}
+		return storage.NewPostgresStore(pool), audit.NewPostgresStore(pool), pool.Close
+	case "sqlite":
+		db, err := storage.OpenSQLite(ctx, cfg.SQLitePath)
+		if err != nil {
+			fatal("Error opening sqlite database", err)
+		}
+		users, err := storage.NewSQLiteStore(ctx, db)
+		if err != nil {
+			fatal("Error preparing sqlite database", err)
+		}
+		auditStore, err := audit.NewSQLiteStore(ctx, db)
+		if err != nil {
+			fatal("Error preparing sqlite database", err)
+		}
+		checks.Add("sqlite", db.PingContext)
+		slog.Info("Using sqlite user store", "path", cfg.SQLitePath)
+		return users, auditStore, func() { db.Close() }
+	default:
+		slog.Warn("Using in-memory user store, data will be lost on restart")
+		return storage.NewMemoryStore(), audit.NewMemoryStore(), func() {}
+	}
+}
+
+// newRedisClient создает клиент Redis для счетчиков лимитов. Соединение
+// устанавливается лениво, так что недоступный Redis не мешает старту.
+func newRedisClient(cfg commonconfig.Redis) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	if err := redisotel.InstrumentTracing(rdb); err != nil {
+		slog.Warn("Failed to instrument Redis tracing", "error", err)
+	}
+	return rdb
+}
+
+// runMigrations выполняет подкоманду migrate для хранилища из AUTH_STORAGE;
+// `migrate audit ...` управляет схемой общего журнала аудита.
+func runMigrations(ctx context.Context, cfg *config.Config, args []string) {
+	postgresMigrator, sqliteMigrator := storage.PostgresMigrator, storage.SQLiteMigrator
+	if len(args) > 0 && args[0] == "audit" {
+		postgresMigrator, sqliteMigrator = audit.PostgresMigrator, audit.SQLiteMigrator
+		args = args[1:]
+	}
+
+	var migrator *migrate.Migrator
+	switch cfg.Storage {
+	case "postgres":
+		pool, err := storage.NewPostgresPool(ctx, cfg.Database.DSN())
+		if err != nil {
+			fatal("Error connecting to database", err)
+		}
+		defer pool.Close()
+
+		migrator, err = postgresMigrator(pool)
+		if err != nil {
+			fatal("Error loading migrations", err)
+		}
+	case "sqlite":
+		db, err := storage.OpenSQLite(ctx, cfg.SQLitePath)
+		if err != nil {
+			fatal("Error opening sqlite database", err)
+		}
+		defer db.Close()
+
+		migrator, err = sqliteMigrator(db)
+		if err != nil {
+			fatal("Error loading migrations", err)
+		}
+	default:
+		fatal("Error running migrations", fmt.Errorf("AUTH_STORAGE %q has no migrations", cfg.Storage))
+	}
+
+	if err := migrate.RunCommand(ctx, migrator, args, os.Stdout); err != nil {
+		fatal("Migration failed", err)
+	}
+}
@@ -0,0 +1,133 @@
+// Package config описывает настройки auth-service. Загружается один раз при старте.
+package config
+
+import (
+	"common/config"
+)
+
+type Config struct {
+	Port           int    `yaml:"port" env:"PORT" default:"8080"`
+	Storage        string `yaml:"storage" env:"AUTH_STORAGE" default:"postgres"`
+	SQLitePath     string `yaml:"sqlite_path" env:"SQLITE_PATH" default:"notes-manager.db"`
+	MigrateOnStart bool   `yaml:"migrate_on_start" env:"MIGRATE_ON_START" default:"true"`
+	JWTSecret      string `yaml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+
+	HTTP     config.HTTP     `yaml:"http"`
+	Tracing  config.Tracing  `yaml:"tracing"`
+	Logging  config.Logging  `yaml:"logging"`
+	Database config.Database `yaml:"database"`
+	// Redis хранит счетчики ограничения частоты запросов; используется вместе с Postgres
+	Redis        config.Redis     `yaml:"redis"`
+	RateLimit    config.RateLimit `yaml:"rate_limit"`
+	Registration Registration     `yaml:"registration"`
+	Password     Password         `yaml:"password"`
+}
+
+type Registration struct {
+	// open, invite или closed
+	Mode string `yaml:"mode" env:"REGISTRATION_MODE" default:"open"`
+	// Ограничения для приглашений обычных пользователей
+	InviteMaxUses     int32 `yaml:"invite_max_uses" env:"INVITE_USER_MAX_USES" default:"1"`
+	InviteMaxTTLHours int   `yaml:"invite_max_ttl_hours" env:"INVITE_USER_MAX_TTL_HOURS" default:"168"`
+}
+
+type Password struct {
+	// argon2id или bcrypt; хеши другого алгоритма продолжают проверяться
+	Algorithm  string `yaml:"algorithm" env:"PASSWORD_HASH_ALGORITHM" default:"argon2id"`
+	BcryptCost int    `yaml:"bcrypt_cost" env:"BCRYPT_COST" default:"10"`
+	Argon2     Argon2 `yaml:"argon2"`
+
+	MinLength     int  `yaml:"min_length" env:"PASSWORD_MIN_LENGTH" default:"8"`
+	MaxLength     int  `yaml:"max_length" env:"PASSWORD_MAX_LENGTH" default:"128"`
+	MinClasses    int  `yaml:"min_classes" env:"PASSWORD_MIN_CLASSES" default:"2"`
+	AllowUserInfo bool `yaml:"allow_user_info" env:"PASSWORD_ALLOW_USER_INFO" default:"false"`
+	// Каталог диапазонов HIBP XXXXX.txt или один отсортированный файл SHA1:COUNT
+	BreachFile     string `yaml:"breach_file" env:"PASSWORD_BREACH_FILE"`
+	BreachMinCount int    `yaml:"breach_min_count" env:"PASSWORD_BREACH_MIN_COUNT" default:"1"`
+}
+
+type Argon2 struct {
+	MemoryKB    uint32 `yaml:"memory_kb" env:"ARGON2_MEMORY_KB" default:"65536"`
+	Iterations  uint32 `yaml:"iterations" env:"ARGON2_ITERATIONS" default:"3"`
+	Parallelism uint8  `yaml:"parallelism" env:"ARGON2_PARALLELISM" default:"2"`
+	SaltLength  uint32 `yaml:"salt_length" env:"ARGON2_SALT_LENGTH" default:"16"`
+	KeyLength   uint32 `yaml:"key_length" env:"ARGON2_KEY_LENGTH" default:"32"`
+}
+
+// Load читает конфигурацию из path (может быть пустым), .env и окружения
+// и проверяет ее. Все ошибки возвращаются одним config.Errors.
+func Load(path string) (*Config, error) {
+	var cfg Config
+	if err := config.Load(&cfg, path); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) Validate() error {
+	var errs config.Errors
+
+	config.ValidatePort(&errs, c.Port)
+	c.HTTP.Validate(&errs)
+	c.Tracing.Validate(&errs)
+	c.Logging.Validate(&errs)
+	c.RateLimit.Validate(&errs)
+	if c.JWTSecret == "" {
+		errs.Addf("JWT_SECRET is required")
+	}
+
+	switch c.Storage {
+	case "postgres":
+		c.Database.Validate(&errs)
+		c.Redis.Validate(&errs)
+	case "sqlite":
+		if c.SQLitePath == "" {
+			errs.Addf("SQLITE_PATH is required for sqlite storage")
+		}
+	case "memory":
+	default:
+		errs.Addf("AUTH_STORAGE must be postgres, sqlite or memory, got %q", c.Storage)
+	}
+
+	switch c.Registration.Mode {
+	case "open", "invite", "closed":
+	default:
+		errs.Addf("REGISTRATION_MODE must be open, invite or closed, got %q", c.Registration.Mode)
+	}
+	if c.Registration.InviteMaxUses <= 0 {
+		errs.Addf("INVITE_USER_MAX_USES must be positive")
+	}
+	if c.Registration.InviteMaxTTLHours <= 0 {
+		errs.Addf("INVITE_USER_MAX_TTL_HOURS must be positive")
+	}
+
+	p := c.Password
+	switch p.Algorithm {
+	case "argon2id", "bcrypt":
+	default:
+		errs.Addf("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt, got %q", p.Algorithm)
+	}
+	if p.BcryptCost < 4 || p.BcryptCost > 31 {
+		errs.Addf("BCRYPT_COST must be between 4 and 31, got %d", p.BcryptCost)
+	}
+	if p.Argon2.MemoryKB < 8*uint32(p.Argon2.Parallelism) || p.Argon2.Iterations == 0 || p.Argon2.Parallelism == 0 {
+		errs.Addf("ARGON2_* parameters are invalid: memory must be at least 8 KiB per thread, iterations and parallelism positive")
+	}
+	if p.Argon2.SaltLength < 8 || p.Argon2.KeyLength < 16 {
+		errs.Addf("ARGON2_SALT_LENGTH must be at least 8 and ARGON2_KEY_LENGTH at least 16")
+	}
+	if p.MinLength <= 0 || p.MaxLength < p.MinLength {
+		errs.Addf("PASSWORD_MIN_LENGTH must be positive and not greater than PASSWORD_MAX_LENGTH")
+	}
+	if p.MinClasses < 0 || p.MinClasses > 4 {
+		errs.Addf("PASSWORD_MIN_CLASSES must be between 0 and 4, got %d", p.MinClasses)
+	}
+	if p.BreachMinCount <= 0 {
+		errs.Addf("PASSWORD_BREACH_MIN_COUNT must be positive")
+	}
+
+	return errs.Err()
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"common/apierror"
+	"common/audit"
+	"common/auth"
+)
+
+// auditExportLimit — сколько последних событий попадает в CSV, если limit не задан
+const auditExportLimit = 100000
+
+// AuditHandler отдает события аккаунта текущего пользователя, в том числе
+// действия администратора над ним и изменения его заметок.
+func (s *Server) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	f, ok := parseAuditFilter(w, r)
+	if !ok {
+		return
+	}
+	f.UserID = auth.FromContext(r.Context()).UserID
+	s.writeAuditEvents(w, r, f)
+}
+
+// AdminAuditHandler отдает события всех пользователей с фильтрами
+func (s *Server) AdminAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	f, ok := parseAuditFilter(w, r)
+	if !ok {
+		return
+	}
+	s.writeAuditEvents(w, r, f)
+}
+
+func parseAuditFilter(w http.ResponseWriter, r *http.Request) (audit.Filter, bool) {
+	q := r.URL.Query()
+	f, err := audit.ParseFilter(q)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err.Error()))
+		return audit.Filter{}, false
+	}
+	if q.Get("format") == "csv" && q.Get("limit") == "" {
+		f.Limit = auditExportLimit
+	}
+	return f, true
+}
+
+// writeAuditEvents отвечает JSON-страницей событий или, при format=csv, файлом CSV
+func (s *Server) writeAuditEvents(w http.ResponseWriter, r *http.Request, f audit.Filter) {
+	events, err := s.audit.Query(r.Context(), f)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error fetching audit events", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error fetching audit events"))
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit.csv"`)
+		if err := audit.WriteCSV(w, events); err != nil {
+			slog.WarnContext(r.Context(), "Error writing audit export", "error", err)
+		}
+		return
+	}
+
+	if events == nil {
+		events = []audit.Event{}
+	}
+	resp := map[string]interface{}{"events": events}
+	// Полная страница — возможно, есть события старше; их отдает следующий запрос с before
+	if len(events) == f.Limit {
+		resp["next_before"] = events[len(events)-1].ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -1,137 +1,185 @@
-package handlers
-
-import (
-	"encoding/json"
-	"log"
-	"net/http"
-
-	"auth-service/internal/models"
-	"auth-service/internal/storage"
-	"auth-service/internal/tools"
-)
-
-func RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var data struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		log.Println("Error decoding request body:", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-
-	hashedPassword, err := tools.PasswordToHash(data.Password)
-	if err != nil {
-		log.Println("Error hashing password:", err)
-		http.Error(w, "Error registering user", http.StatusInternalServerError)
-		return
-	}
-
-	
-	user := models.User{
-		Username: data.Username,
-		Email:    data.Email,
-		Password: hashedPassword,
-	}
-
-
-	id, err := storage.AddUser(r.Context(), user)
-	if err != nil {
-		log.Println("Error adding user:", err)
-		http.Error(w, "Error registering user", http.StatusInternalServerError)
-		return
-	}
-
-	
-	tools.MakeCookieAfterLogin(w, id, data.Username, data.Email)
-}
-
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var data struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		log.Println("Error decoding request body:", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-
-	user, err := storage.GetUserByEmail(r.Context(), data.Email)
-	if err != nil {
-		log.Println("Error retrieving user:", err)
-		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
-		return
-	}
-
-
-	if !tools.ValidatePassword(data.Password, user.Password) {
-		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
-		return
-	}
-
-	
-	tools.MakeCookieAfterLogin(w, user.ID, user.Username, user.Email)
-}
-
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    "",
-		HttpOnly: true,
-		Path:     "/",
-		MaxAge:   -1,
-	})
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Logged out successfully",
-	})
-}
-
-func MeHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := tools.GetUserFromToken(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
-	})
-}
-
-func HealthHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
\ No newline at end of file
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"auth-service/internal/metrics"
+	"auth-service/internal/models"
+	"auth-service/internal/storage"
+	"auth-service/internal/tools"
+	"auth-service/internal/validation"
+	"common/apierror"
+	"common/audit"
+	"common/auth"
+	"common/logging"
+)
+
+func (s *Server) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	var data struct {
+		Username   string `json:"username"`
+		Email      string `json:"email"`
+		Password   string `json:"password"`
+		InviteCode string `json:"invite_code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		slog.WarnContext(r.Context(), "Error decoding request body", "error", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return
+	}
+
+	var fieldErrors []validation.FieldError
+	fieldErrors = append(fieldErrors, validation.ValidateUsername(data.Username)...)
+	fieldErrors = append(fieldErrors, validation.ValidateEmail(data.Email)...)
+
+	if err := tools.CheckPasswordPolicy(data.Password, data.Username, data.Email); err != nil {
+		passwordErrors, ok := passwordFieldErrors("password", err)
+		if !ok {
+			slog.ErrorContext(r.Context(), "Error checking password policy", "error", err)
+			apierror.Write(w, r, apierror.Internal("Error registering user"))
+			return
+		}
+		fieldErrors = append(fieldErrors, passwordErrors...)
+	}
+
+	if len(fieldErrors) > 0 {
+		writeValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	mode := tools.GetRegistrationMode()
+	if mode == tools.RegistrationClosed {
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, codeRegistrationClosed, "Registration is closed"))
+		return
+	}
+
+	hashedPassword, err := tools.PasswordToHash(data.Password)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error hashing password", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error registering user"))
+		return
+	}
+
+	
+	user := models.User{
+		Username: data.Username,
+		Email:    data.Email,
+		Password: hashedPassword,
+	}
+
+
+	// Без приглашения можно зарегистрировать только первого пользователя (администратора);
+	// хранилище проверяет это в одной транзакции со вставкой
+	created, err := s.users.AddUser(r.Context(), user, data.InviteCode, mode == tools.RegistrationInvite)
+	if err != nil {
+		writeStorageError(w, r, err, "Error registering user")
+		return
+	}
+	logging.AddAttrs(r.Context(), "user_id", created.ID)
+
+	
+	s.startSession(w, r, created)
+}
+
+func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	var data struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		slog.WarnContext(r.Context(), "Error decoding request body", "error", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return
+	}
+
+
+	user, err := s.users.GetUserByEmail(r.Context(), data.Email)
+	if err != nil {
+		reason := "unknown_user"
+		if !errors.Is(err, storage.ErrNotFound) {
+			slog.ErrorContext(r.Context(), "Error retrieving user", "error", err)
+			reason = "error"
+		}
+		metrics.LoginFailed(reason)
+		s.audit.Record(r, audit.Event{Action: audit.ActionLoginFailed, Detail: reason})
+		apierror.Write(w, r, apierror.New(http.StatusUnauthorized, codeInvalidCredentials, "Invalid email or password"))
+		return
+	}
+	logging.AddAttrs(r.Context(), "user_id", user.ID)
+
+
+	ok, needsRehash := tools.VerifyPassword(data.Password, user.Password)
+	if !ok {
+		metrics.LoginFailed("wrong_password")
+		s.audit.Record(r, audit.Event{Action: audit.ActionLoginFailed, UserID: user.ID, Detail: "wrong_password"})
+		apierror.Write(w, r, apierror.New(http.StatusUnauthorized, codeInvalidCredentials, "Invalid email or password"))
+		return
+	}
+
+	// Пароль верный — заодно обновляем хеш до текущего алгоритма и параметров
+	if needsRehash {
+		if hashed, err := tools.PasswordToHash(data.Password); err != nil {
+			slog.ErrorContext(r.Context(), "Error rehashing password", "error", err)
+		} else if err := s.users.UpdateUserPassword(r.Context(), user.ID, hashed); err != nil {
+			slog.ErrorContext(r.Context(), "Error updating password hash", "error", err)
+		}
+	}
+
+	metrics.LoginSucceeded()
+	s.audit.Record(r, audit.Event{Action: audit.ActionLogin, ActorID: user.ID, UserID: user.ID})
+	s.startSession(w, r, user)
+}
+
+func (s *Server) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    "",
+		HttpOnly: true,
+		Path:     "/",
+		MaxAge:   -1,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
+func (s *Server) MeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	user := auth.FromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       user.UserID,
+		"username": user.Username,
+		"email":    user.Email,
+		"role":     user.Role(),
+		"roles":    user.Roles,
+		"scopes":   user.Scopes,
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"auth-service/internal/password"
+	"auth-service/internal/storage"
+	"auth-service/internal/validation"
+	"common/apierror"
+)
+
+const (
+	codeRegistrationClosed = "registration_closed"
+	codeInviteRequired     = "invite_required"
+	codeInvalidInvite      = "invalid_invite"
+	codeInvalidCredentials = "invalid_credentials"
+)
+
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, fields []validation.FieldError) {
+	apierror.Write(w, r, apierror.Validation("Request validation failed", fields))
+}
+
+// writeStorageError переводит ошибки хранилища в HTTP-статусы; все, что не
+// является известной ошибкой, логируется и отдается как 500 с message.
+func writeStorageError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var conflict *storage.ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		apierror.Write(w, r, apierror.Conflict("User already exists").WithDetails([]validation.FieldError{{
+			Field:   conflict.Field,
+			Code:    "taken",
+			Message: conflict.Field + " is already taken",
+		}}))
+	case errors.Is(err, storage.ErrConflict):
+		apierror.Write(w, r, apierror.Conflict("User already exists"))
+	case errors.Is(err, storage.ErrNotFound):
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+	case errors.Is(err, storage.ErrInviteRequired):
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, codeInviteRequired, "Invite code required"))
+	case errors.Is(err, storage.ErrInvalidInvite):
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, codeInvalidInvite, "Invalid or expired invite code"))
+	default:
+		slog.ErrorContext(r.Context(), message, "error", err)
+		apierror.Write(w, r, apierror.Internal(message))
+	}
+}
+
+// passwordFieldErrors превращает нарушения политики паролей в ошибки поля field.
+// Если err не *password.PolicyError, возвращается ok == false.
+func passwordFieldErrors(field string, err error) ([]validation.FieldError, bool) {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return nil, false
+	}
+
+	fields := make([]validation.FieldError, len(policyErr.Violations))
+	for i, v := range policyErr.Violations {
+		fields[i] = validation.FieldError{
+			Field:   field,
+			Code:    v.Code,
+			Message: field + " " + v.Message,
+		}
+	}
+	return fields, true
+}
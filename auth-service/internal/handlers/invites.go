@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/tools"
+	"common/apierror"
+	"common/auth"
+)
+
+func (s *Server) InvitesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.ListInvitesHandler(w, r)
+	case http.MethodPost:
+		s.CreateInviteHandler(w, r)
+	default:
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+	}
+}
+
+func (s *Server) CreateInviteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	user := auth.FromContext(r.Context())
+
+	var req models.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.WarnContext(r.Context(), "Error decoding request body", "error", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return
+	}
+
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+	if req.MaxUses < 0 || req.ExpiresInHours < 0 {
+		apierror.Write(w, r, apierror.BadRequest("max_uses and expires_in_hours must be positive"))
+		return
+	}
+
+	// Обычные пользователи ограничены по числу использований и сроку жизни,
+	// администраторы могут создавать бессрочные многоразовые приглашения
+	if !user.HasRole(auth.RoleAdmin) {
+		maxUses, maxTTLHours := tools.GetInviteLimits()
+		if req.MaxUses > maxUses {
+			apierror.Write(w, r, apierror.Forbidden("max_uses exceeds the allowed limit"))
+			return
+		}
+		if req.ExpiresInHours == 0 {
+			req.ExpiresInHours = maxTTLHours
+		}
+		if req.ExpiresInHours > maxTTLHours {
+			apierror.Write(w, r, apierror.Forbidden("expires_in_hours exceeds the allowed limit"))
+			return
+		}
+	}
+
+	code, err := tools.GenerateInviteCode()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error generating invite code", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error creating invite"))
+		return
+	}
+
+	invite := models.Invite{
+		Code:      code,
+		CreatedBy: user.UserID,
+		MaxUses:   req.MaxUses,
+	}
+	if req.ExpiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	created, err := s.users.CreateInvite(r.Context(), invite)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error creating invite", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error creating invite"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (s *Server) ListInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	user := auth.FromContext(r.Context())
+
+	invites, err := s.users.GetUserInvites(r.Context(), user.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error fetching invites", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error fetching invites"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"invites": invites,
+	})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"auth-service/internal/tools"
+	"common/apierror"
+	"common/audit"
+	"common/auth"
+)
+
+func (s *Server) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	var data struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		slog.WarnContext(r.Context(), "Error decoding request body", "error", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return
+	}
+
+	user, err := s.users.GetUserByID(r.Context(), auth.FromContext(r.Context()).UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error retrieving user", "error", err)
+		apierror.Write(w, r, apierror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	if !tools.ValidatePassword(data.CurrentPassword, user.Password) {
+		apierror.Write(w, r, apierror.Forbidden("Current password is incorrect"))
+		return
+	}
+
+	if err := tools.CheckPasswordPolicy(data.NewPassword, user.Username, user.Email); err != nil {
+		writePasswordPolicyError(w, r, err)
+		return
+	}
+
+	if !s.setPassword(w, r, user.ID, data.NewPassword) {
+		return
+	}
+	s.audit.Record(r, audit.Event{Action: audit.ActionPasswordChange, UserID: user.ID})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password changed successfully",
+	})
+}
+
+// AdminResetPasswordHandler позволяет администратору задать пользователю новый пароль.
+func (s *Server) AdminResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	var data struct {
+		Email       string `json:"email"`
+		NewPassword string `json:"new_password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		slog.WarnContext(r.Context(), "Error decoding request body", "error", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+		return
+	}
+
+	user, err := s.users.GetUserByEmail(r.Context(), data.Email)
+	if err != nil {
+		writeStorageError(w, r, err, "Error retrieving user")
+		return
+	}
+
+	if err := tools.CheckPasswordPolicy(data.NewPassword, user.Username, user.Email); err != nil {
+		writePasswordPolicyError(w, r, err)
+		return
+	}
+
+	if !s.setPassword(w, r, user.ID, data.NewPassword) {
+		return
+	}
+	s.audit.Record(r, audit.Event{Action: audit.ActionPasswordReset, UserID: user.ID})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password reset successfully",
+	})
+}
+
+func (s *Server) setPassword(w http.ResponseWriter, r *http.Request, userID int32, newPassword string) bool {
+	hashedPassword, err := tools.PasswordToHash(newPassword)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error hashing password", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error updating password"))
+		return false
+	}
+
+	if err := s.users.UpdateUserPassword(r.Context(), userID, hashedPassword); err != nil {
+		slog.ErrorContext(r.Context(), "Error updating password", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error updating password"))
+		return false
+	}
+	return true
+}
+
+func writePasswordPolicyError(w http.ResponseWriter, r *http.Request, err error) {
+	if fields, ok := passwordFieldErrors("new_password", err); ok {
+		writeValidationErrors(w, r, fields)
+		return
+	}
+
+	slog.ErrorContext(r.Context(), "Error checking password policy", "error", err)
+	apierror.Write(w, r, apierror.Internal("Error checking password"))
+}
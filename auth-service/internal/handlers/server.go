@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"auth-service/internal/storage"
+	"common/audit"
+	"common/auth"
+	"common/config"
+	"common/health"
+	"common/metrics"
+	"common/ratelimit"
+	"common/route"
+)
+
+// rateLimits — встроенные лимиты маршрутов; остальные получают RATE_LIMIT_DEFAULT.
+// Регистрация и вход ограничены строже, чтобы затруднить перебор паролей и спам.
+var rateLimits = map[string]config.Rate{
+	"/api/auth/register":             {Requests: 10, Window: time.Hour},
+	"/api/auth/login":                {Requests: 10, Window: time.Minute},
+	"/api/auth/password":             {Requests: 5, Window: time.Minute},
+	"/api/auth/admin/reset-password": {Requests: 10, Window: time.Minute},
+	"/healthz":                       {},
+	"/readyz":                        {},
+	"/health":                        {},
+	"/metrics":                       {},
+}
+
+// Server держит зависимости обработчиков; хранилище пользователей передается
+// снаружи (Postgres, SQLite или память).
+type Server struct {
+	users   storage.UserStore
+	health  *health.Checks
+	authn   *auth.Authenticator
+	limiter *ratelimit.Limiter
+	audit   *audit.Recorder
+}
+
+func NewServer(users storage.UserStore, checks *health.Checks, authn *auth.Authenticator, limiter *ratelimit.Limiter, recorder *audit.Recorder) *Server {
+	return &Server{users: users, health: checks, authn: authn, limiter: limiter, audit: recorder}
+}
+
+// NewLimiter создает ограничитель частоты запросов с лимитами auth-service:
+// вошедшие пользователи считаются по id из токена, остальные — по IP.
+func NewLimiter(store ratelimit.Store, cfg config.RateLimit) (*ratelimit.Limiter, error) {
+	return ratelimit.New(store, cfg, rateLimits, auth.RateLimitKey)
+}
+
+func (s *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/register", s.RegisterHandler)
+	mux.HandleFunc("/api/auth/login", s.LoginHandler)
+	mux.HandleFunc("/api/auth/logout", s.LogoutHandler)
+	mux.HandleFunc("/api/auth/me", auth.Require(s.MeHandler))
+	mux.HandleFunc("/api/auth/invites", auth.Require(s.InvitesHandler))
+	mux.HandleFunc("/api/auth/password", auth.Require(s.ChangePasswordHandler))
+	mux.HandleFunc("/api/auth/admin/reset-password", auth.RequireRole(auth.RoleAdmin, s.AdminResetPasswordHandler))
+	mux.HandleFunc("/api/auth/audit", auth.Require(s.AuditHandler))
+	mux.HandleFunc("/api/auth/admin/audit", auth.RequireRole(auth.RoleAdmin, s.AdminAuditHandler))
+	mux.HandleFunc("/healthz", s.health.Liveness)
+	mux.HandleFunc("/readyz", s.health.Readiness)
+	// Старый адрес оставлен для совместимости с существующими проверками
+	mux.HandleFunc("/health", s.health.Liveness)
+	mux.Handle("/metrics", metrics.Handler())
+	// Пользователь нужен ограничителю частоты, поэтому токен проверяется раньше.
+	// Ограничитель передает mux тот же запрос, так что маршрут виден route.Record
+	return s.authn.Middleware(route.Record(s.limiter.Middleware(mux)))
+}
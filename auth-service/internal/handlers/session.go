@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth-service/internal/models"
+	"common/apierror"
+	"common/auth"
+)
+
+const sessionTTL = 24 * time.Hour
+
+// startSession выпускает токен сессии для user и ставит его в cookie
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, user *models.User) {
+	token, err := s.authn.Issue(auth.Principal{
+		UserID:   user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Roles:    []string{user.Role},
+		Scopes:   auth.SessionScopes,
+	}, sessionTTL)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error signing token", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error signing token"))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    token,
+		HttpOnly: true,
+		Path:     "/",
+		MaxAge:   int(sessionTTL / time.Second),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Login successful",
+		"user_id":  user.ID,
+		"username": user.Username,
+		"role":     user.Role,
+	})
+}
@@ -0,0 +1,21 @@
+// Package metrics — метрики auth-service. HTTP-метрики и пул pgx в common/metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var loginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "auth_login_attempts_total",
+	Help: "Login attempts by result (success, failure) and failure reason.",
+}, []string{"result", "reason"})
+
+func LoginSucceeded() {
+	loginAttempts.WithLabelValues("success", "").Inc()
+}
+
+// LoginFailed: reason — unknown_user, wrong_password или error
+func LoginFailed(reason string) {
+	loginAttempts.WithLabelValues("failure", reason).Inc()
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type Invite struct {
+	ID        int32      `json:"id"`
+	Code      string     `json:"code"`
+	CreatedBy int32      `json:"created_by"`
+	MaxUses   int32      `json:"max_uses"`
+	Uses      int32      `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type CreateInviteRequest struct {
+	MaxUses        int32 `json:"max_uses"`
+	ExpiresInHours int   `json:"expires_in_hours"`
+}
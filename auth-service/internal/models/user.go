@@ -1,8 +1,16 @@
-package models
-
-type User struct {
-    ID       int32  `json:"id"`
-    Username string `json:"username"`
-    Email    string `json:"email"`
-    Password string `json:"password"`
+package models
+
+import "common/auth"
+
+const (
+    RoleUser  = auth.RoleUser
+    RoleAdmin = auth.RoleAdmin
+)
+
+type User struct {
+    ID       int32  `json:"id"`
+    Username string `json:"username"`
+    Email    string `json:"email"`
+    Password string `json:"password"`
+    Role     string `json:"role"`
 }
\ No newline at end of file
@@ -0,0 +1,114 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Малые параметры, чтобы тесты не считали по 64 МБ на хеш
+var testArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHash(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2Params)
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Hash = %q, want PHC string with the configured parameters", encoded)
+	}
+
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		t.Fatalf("decodeArgon2id: %v", err)
+	}
+	if params != testArgon2Params || len(salt) != 16 || len(key) != 32 {
+		t.Errorf("decoded %+v with %d-byte salt and %d-byte key, want %+v", params, len(salt), len(key), testArgon2Params)
+	}
+
+	if ok, err := h.Verify("correct horse", encoded); !ok || err != nil {
+		t.Errorf("Verify(correct) = %v, %v; want true", ok, err)
+	}
+	if ok, err := h.Verify("wrong horse", encoded); ok || err != nil {
+		t.Errorf("Verify(wrong) = %v, %v; want false", ok, err)
+	}
+	if other, _ := h.Hash("correct horse"); other == encoded {
+		t.Error("two hashes of one password are equal: salt is not random")
+	}
+}
+
+func TestDecodeArgon2idInvalid(t *testing.T) {
+	b64 := func(n int) string {
+		return base64.RawStdEncoding.EncodeToString(make([]byte, n))
+	}
+	salt, key := b64(16), b64(32)
+	tests := []struct {
+		name, encoded string
+	}{
+		{"bcrypt hash", "$2a$10$abcdefghijklmnopqrstuv"},
+		{"argon2i", "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key},
+		{"missing hash", "$argon2id$v=19$m=64,t=1,p=1$" + salt},
+		{"bad base64 salt", "$argon2id$v=19$m=64,t=1,p=1$!!!!$" + key},
+		{"bad base64 key", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + key[:len(key)-1] + "*"},
+		{"padded base64", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "==$" + key},
+		{"wrong version", "$argon2id$v=16$m=64,t=1,p=1$" + salt + "$" + key},
+		{"no version", "$argon2id$m=64,t=1,p=1$" + salt + "$" + key + "$"},
+		{"parameters out of order", "$argon2id$v=19$t=1,m=64,p=1$" + salt + "$" + key},
+		{"trailing garbage", "$argon2id$v=19$m=64,t=1,p=1x$" + salt + "$" + key},
+		{"negative memory", "$argon2id$v=19$m=-64,t=1,p=1$" + salt + "$" + key},
+		{"memory below 8 KiB per thread", "$argon2id$v=19$m=15,t=1,p=2$" + salt + "$" + key},
+		{"memory too large", "$argon2id$v=19$m=4194305,t=1,p=1$" + salt + "$" + key},
+		{"memory beyond uint32", "$argon2id$v=19$m=4294967296,t=1,p=1$" + salt + "$" + key},
+		{"zero iterations", "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key},
+		{"too many iterations", "$argon2id$v=19$m=64,t=1025,p=1$" + salt + "$" + key},
+		{"zero parallelism", "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key},
+		{"parallelism beyond uint8", "$argon2id$v=19$m=4096,t=1,p=256$" + salt + "$" + key},
+		{"short salt", "$argon2id$v=19$m=64,t=1,p=1$" + b64(4) + "$" + key},
+		{"short key", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + b64(8)},
+		{"key too long", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + b64(2048)},
+	}
+	h := NewArgon2idHasher(testArgon2Params)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := decodeArgon2id(tt.encoded); !errors.Is(err, ErrMalformedHash) {
+				t.Errorf("decodeArgon2id(%q): err = %v, want ErrMalformedHash", tt.encoded, err)
+			}
+			if ok, err := h.Verify("password", tt.encoded); ok || err == nil {
+				t.Errorf("Verify(%q) = %v, %v; want an error", tt.encoded, ok, err)
+			}
+			if !h.NeedsRehash(tt.encoded) {
+				t.Errorf("NeedsRehash(%q) = false, want true", tt.encoded)
+			}
+		})
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	encoded, err := NewArgon2idHasher(testArgon2Params).Hash("password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	tests := []struct {
+		name   string
+		change func(p *Argon2Params)
+		want   bool
+	}{
+		{"same parameters", func(p *Argon2Params) {}, false},
+		{"more memory", func(p *Argon2Params) { p.Memory *= 2 }, true},
+		{"more iterations", func(p *Argon2Params) { p.Iterations++ }, true},
+		{"more threads", func(p *Argon2Params) { p.Parallelism++ }, true},
+		{"longer salt", func(p *Argon2Params) { p.SaltLength = 32 }, true},
+		{"longer key", func(p *Argon2Params) { p.KeyLength = 64 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := testArgon2Params
+			tt.change(&params)
+			if got := NewArgon2idHasher(params).NeedsRehash(encoded); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
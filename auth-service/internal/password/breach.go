@@ -0,0 +1,217 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// FileBreachChecker проверяет пароли по локальной копии HIBP Pwned Passwords,
+// не загружая ее в память: полный корпус занимает десятки гигабайт. Путь —
+// либо каталог файлов диапазонов XXXXX.txt со строками "SUFFIX:COUNT", как
+// отдает range API (k-anonymity: читается только файл с 5-символьным
+// префиксом SHA-1 пароля), либо один файл "SHA1:COUNT", отсортированный по
+// хешу, в котором поиск двоичный. Оба формата выдает haveibeenpwned-downloader.
+type FileBreachChecker struct {
+	path     string
+	ranges   bool
+	minCount int
+}
+
+// Длина префикса SHA-1 в имени файла диапазона
+const rangePrefixLen = 5
+
+// OpenBreachFile открывает каталог диапазонов или отсортированный файл;
+// хеши, встреченные реже minCount раз, не считаются утекшими.
+func OpenBreachFile(path string, minCount int) (*FileBreachChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening breach file: %w", err)
+	}
+	checker := &FileBreachChecker{path: path, ranges: info.IsDir(), minCount: minCount}
+	if checker.ranges {
+		return checker, nil
+	}
+
+	// Первая строка проверяется сразу, чтобы файл не того формата был виден при запуске
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening breach file: %w", err)
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error reading breach file: %w", err)
+	}
+	if _, _, err := parseBreachLine(line, sha1.Size*2); err != nil {
+		return nil, fmt.Errorf("breach file line 1: %w", err)
+	}
+	return checker, nil
+}
+
+// Layout — формат копии для журнала: ranges или sorted
+func (c *FileBreachChecker) Layout() string {
+	if c.ranges {
+		return "ranges"
+	}
+	return "sorted"
+}
+
+func (c *FileBreachChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	var count int
+	var found bool
+	var err error
+	if c.ranges {
+		count, found, err = c.lookupRange(hash)
+	} else {
+		count, found, err = c.lookupSorted(hash)
+	}
+	if err != nil {
+		return false, err
+	}
+	return found && count >= c.minCount, nil
+}
+
+// lookupRange читает файл диапазона с префиксом хеша. Файла нет — в копии
+// нет и хешей с таким префиксом.
+func (c *FileBreachChecker) lookupRange(hash string) (int, bool, error) {
+	name := filepath.Join(c.path, hash[:rangePrefixLen]+".txt")
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("error opening breach range: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		suffix, count, err := parseBreachLine(scanner.Text(), sha1.Size*2-rangePrefixLen)
+		if err != nil {
+			return 0, false, fmt.Errorf("breach range %s line %d: %w", filepath.Base(name), line, err)
+		}
+		if suffix == hash[rangePrefixLen:] {
+			return count, true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, false, fmt.Errorf("error reading breach range: %w", err)
+	}
+	return 0, false, nil
+}
+
+// lookupSorted ищет хеш двоичным поиском по смещениям в файле: находит
+// наименьшее смещение, после которого первая строка не меньше хеша
+func (c *FileBreachChecker) lookupSorted(hash string) (int, bool, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return 0, false, fmt.Errorf("error opening breach file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, false, fmt.Errorf("error reading breach file: %w", err)
+	}
+
+	lo, hi := int64(0), info.Size()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, ok, err := lineAfter(f, mid, info.Size())
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			hi = mid
+			continue
+		}
+		lineHash, _, err := parseBreachLine(line, sha1.Size*2)
+		if err != nil {
+			return 0, false, fmt.Errorf("breach file near offset %d: %w", mid, err)
+		}
+		if lineHash >= hash {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	line, ok, err := lineAfter(f, lo, info.Size())
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	lineHash, count, err := parseBreachLine(line, sha1.Size*2)
+	if err != nil {
+		return 0, false, fmt.Errorf("breach file near offset %d: %w", lo, err)
+	}
+	return count, lineHash == hash, nil
+}
+
+// lineAfter возвращает первую строку, начинающуюся не раньше offset;
+// ok == false, если такой строки нет
+func lineAfter(f *os.File, offset, size int64) (string, bool, error) {
+	start := offset
+	if start > 0 {
+		// Смещение могло попасть в середину строки: начинаем с символа перед
+		// ним и пропускаем остаток строки
+		start--
+	}
+	r := bufio.NewReaderSize(io.NewSectionReader(f, start, size-start), 256)
+	if offset > 0 {
+		if _, err := r.ReadString('\n'); err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("error reading breach file: %w", err)
+		}
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", false, fmt.Errorf("error reading breach file: %w", err)
+	}
+	// Пустой остаток бывает только после перевода строки в конце файла
+	if line == "" {
+		return "", false, nil
+	}
+	return line, true, nil
+}
+
+// parseBreachLine разбирает "HASH:COUNT" с хешем из hashLen hex-символов.
+// Строка без счетчика считается встреченной сколько угодно раз.
+func parseBreachLine(line string, hashLen int) (string, int, error) {
+	hash, countStr, hasCount := strings.Cut(strings.TrimSpace(line), ":")
+	hash = strings.ToUpper(hash)
+	if len(hash) != hashLen || strings.IndexFunc(hash, notHexDigit) >= 0 {
+		return "", 0, errors.New("invalid SHA-1 hash")
+	}
+	if !hasCount {
+		return hash, math.MaxInt, nil
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count < 0 {
+		return "", 0, errors.New("invalid count")
+	}
+	return hash, count, nil
+}
+
+func notHexDigit(r rune) bool {
+	return !('0' <= r && r <= '9' || 'A' <= r && r <= 'F')
+}
@@ -0,0 +1,166 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func writeBreachFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pwned-passwords.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// breachPasswords возвращает пароли, упорядоченные по SHA-1, как строки в файле
+func breachPasswords(n int) []string {
+	passwords := make([]string, n)
+	for i := range passwords {
+		passwords[i] = fmt.Sprintf("password%d", i)
+	}
+	slices.SortFunc(passwords, func(a, b string) int {
+		return strings.Compare(sha1Hex(a), sha1Hex(b))
+	})
+	return passwords
+}
+
+func TestFileBreachCheckerSorted(t *testing.T) {
+	passwords := breachPasswords(40)
+	// В файл не попадают наименьший, средний и наибольший хеши:
+	// поиск должен промахнуться до первой строки, между строками и после последней
+	missing := map[string]bool{passwords[0]: true, passwords[20]: true, passwords[39]: true}
+	var stored []string
+	for _, p := range passwords {
+		if !missing[p] {
+			stored = append(stored, p)
+		}
+	}
+	lines := func(format func(hash string, i int) string) []string {
+		var out []string
+		for i, p := range stored {
+			out = append(out, format(sha1Hex(p), i))
+		}
+		return out
+	}
+	withCount := func(hash string, i int) string { return fmt.Sprintf("%s:%d", hash, i+1) }
+
+	tests := []struct {
+		name, content string
+	}{
+		{"trailing newline", strings.Join(lines(withCount), "\n") + "\n"},
+		{"no trailing newline", strings.Join(lines(withCount), "\n")},
+		{"CRLF", strings.Join(lines(withCount), "\r\n") + "\r\n"},
+		{"lowercase hex", strings.Join(lines(func(hash string, i int) string {
+			return strings.ToLower(withCount(hash, i))
+		}), "\n")},
+		{"without counts", strings.Join(lines(func(hash string, i int) string { return hash }), "\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := OpenBreachFile(writeBreachFile(t, tt.content), 1)
+			if err != nil {
+				t.Fatalf("OpenBreachFile: %v", err)
+			}
+			if checker.Layout() != "sorted" {
+				t.Errorf("Layout = %q, want sorted", checker.Layout())
+			}
+			for _, p := range passwords {
+				breached, err := checker.IsBreached(p)
+				if err != nil {
+					t.Fatalf("IsBreached(%q): %v", p, err)
+				}
+				if breached == missing[p] {
+					t.Errorf("IsBreached(%q) = %v, want %v", p, breached, !missing[p])
+				}
+			}
+		})
+	}
+}
+
+func TestFileBreachCheckerSortedSingleLine(t *testing.T) {
+	for _, content := range []string{sha1Hex("password") + ":3", sha1Hex("password") + ":3\n"} {
+		checker, err := OpenBreachFile(writeBreachFile(t, content), 1)
+		if err != nil {
+			t.Fatalf("OpenBreachFile: %v", err)
+		}
+		if breached, err := checker.IsBreached("password"); !breached || err != nil {
+			t.Errorf("IsBreached(password) in %q = %v, %v; want true", content, breached, err)
+		}
+		if breached, err := checker.IsBreached("another"); breached || err != nil {
+			t.Errorf("IsBreached(another) in %q = %v, %v; want false", content, breached, err)
+		}
+	}
+}
+
+func TestFileBreachCheckerMinCount(t *testing.T) {
+	passwords := breachPasswords(3)
+	var content strings.Builder
+	for i, p := range passwords {
+		fmt.Fprintf(&content, "%s:%d\n", sha1Hex(p), (i+1)*10)
+	}
+	checker, err := OpenBreachFile(writeBreachFile(t, content.String()), 20)
+	if err != nil {
+		t.Fatalf("OpenBreachFile: %v", err)
+	}
+	for i, p := range passwords {
+		want := (i+1)*10 >= 20
+		if breached, err := checker.IsBreached(p); breached != want || err != nil {
+			t.Errorf("IsBreached(%q) with count %d = %v, %v; want %v", p, (i+1)*10, breached, err, want)
+		}
+	}
+}
+
+func TestFileBreachCheckerRanges(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("password")
+	content := "0000000000000000000000000000000000A:1\n" + strings.ToLower(hash[rangePrefixLen:]) + ":5\r\n"
+	if err := os.WriteFile(filepath.Join(dir, hash[:rangePrefixLen]+".txt"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checker, err := OpenBreachFile(dir, 1)
+	if err != nil {
+		t.Fatalf("OpenBreachFile: %v", err)
+	}
+	if checker.Layout() != "ranges" {
+		t.Errorf("Layout = %q, want ranges", checker.Layout())
+	}
+	if breached, err := checker.IsBreached("password"); !breached || err != nil {
+		t.Errorf("IsBreached(password) = %v, %v; want true", breached, err)
+	}
+	// Для другого префикса файла нет
+	if breached, err := checker.IsBreached("another"); breached || err != nil {
+		t.Errorf("IsBreached(another) = %v, %v; want false", breached, err)
+	}
+}
+
+func TestOpenBreachFileInvalid(t *testing.T) {
+	tests := []struct {
+		name, content string
+	}{
+		{"empty", ""},
+		{"short hash", "ABCDEF:1\n"},
+		{"not hex", strings.Repeat("G", 40) + ":1\n"},
+		{"bad count", sha1Hex("password") + ":many\n"},
+		{"negative count", sha1Hex("password") + ":-1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenBreachFile(writeBreachFile(t, tt.content), 1); err == nil {
+				t.Errorf("OpenBreachFile(%q) succeeded, want an error", tt.content)
+			}
+		})
+	}
+}
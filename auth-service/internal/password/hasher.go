@@ -0,0 +1,59 @@
+package password
+
+import "errors"
+
+var (
+	ErrUnknownFormat = errors.New("unknown password hash format")
+	ErrMalformedHash = errors.New("malformed password hash")
+)
+
+// Hasher — алгоритм хеширования паролей. Хеши кодируются в PHC-формате
+// ($id$params$salt$hash), для bcrypt — в его собственном модульном формате.
+type Hasher interface {
+	ID() string
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	// Supports сообщает, умеет ли алгоритм проверять данный хеш
+	Supports(encoded string) bool
+	// NeedsRehash сообщает, что хеш создан этим алгоритмом, но с устаревшими параметрами
+	NeedsRehash(encoded string) bool
+}
+
+// Manager хеширует новые пароли предпочтительным алгоритмом и проверяет
+// хеши любого из зарегистрированных алгоритмов.
+type Manager struct {
+	preferred Hasher
+	hashers   []Hasher
+}
+
+func NewManager(preferred Hasher, legacy ...Hasher) *Manager {
+	return &Manager{
+		preferred: preferred,
+		hashers:   append([]Hasher{preferred}, legacy...),
+	}
+}
+
+func (m *Manager) Hash(password string) (string, error) {
+	return m.preferred.Hash(password)
+}
+
+// Verify проверяет пароль и сообщает, нужно ли пересчитать хеш
+// предпочтительным алгоритмом (другой алгоритм или устаревшие параметры).
+func (m *Manager) Verify(password, encoded string) (ok bool, needsRehash bool, err error) {
+	for _, h := range m.hashers {
+		if !h.Supports(encoded) {
+			continue
+		}
+
+		ok, err := h.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+
+		if h.ID() != m.preferred.ID() {
+			return true, true, nil
+		}
+		return true, h.NeedsRehash(encoded), nil
+	}
+	return false, false, ErrUnknownFormat
+}
@@ -0,0 +1,58 @@
+package password
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestManagerVerify(t *testing.T) {
+	argon := NewArgon2idHasher(testArgon2Params)
+	oldParams := testArgon2Params
+	oldParams.Iterations = 2
+	oldArgon := NewArgon2idHasher(oldParams)
+	bcryptHasher := NewBcryptHasher(bcrypt.MinCost)
+	oldBcrypt := NewBcryptHasher(bcrypt.MinCost + 1)
+
+	hash := func(h Hasher) string {
+		encoded, err := h.Hash("password")
+		if err != nil {
+			t.Fatalf("%s Hash: %v", h.ID(), err)
+		}
+		return encoded
+	}
+	argonHash, oldArgonHash := hash(argon), hash(oldArgon)
+	bcryptHash, oldBcryptHash := hash(bcryptHasher), hash(oldBcrypt)
+
+	preferArgon := NewManager(argon, bcryptHasher)
+	preferBcrypt := NewManager(bcryptHasher, argon)
+	tests := []struct {
+		name       string
+		manager    *Manager
+		password   string
+		encoded    string
+		ok, rehash bool
+		err        error
+	}{
+		{"argon2id, current parameters", preferArgon, "password", argonHash, true, false, nil},
+		{"bcrypt to argon2id", preferArgon, "password", bcryptHash, true, true, nil},
+		{"argon2id with old parameters", preferArgon, "password", oldArgonHash, true, true, nil},
+		{"argon2id to bcrypt", preferBcrypt, "password", argonHash, true, true, nil},
+		{"bcrypt, current cost", preferBcrypt, "password", bcryptHash, true, false, nil},
+		{"bcrypt with old cost", preferBcrypt, "password", oldBcryptHash, true, true, nil},
+		// Хеш пересчитывается только после верного пароля
+		{"wrong password, legacy hash", preferArgon, "wrong", bcryptHash, false, false, nil},
+		{"wrong password, old parameters", preferArgon, "wrong", oldArgonHash, false, false, nil},
+		{"unknown format", preferArgon, "password", "$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA", false, false, ErrUnknownFormat},
+		{"malformed argon2id", preferArgon, "password", "$argon2id$v=19$m=64,t=0,p=1$c2FsdA$aGFzaA", false, false, ErrMalformedHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := tt.manager.Verify(tt.password, tt.encoded)
+			if ok != tt.ok || rehash != tt.rehash || !errors.Is(err, tt.err) {
+				t.Errorf("Verify = %v, %v, %v; want %v, %v, %v", ok, rehash, err, tt.ok, tt.rehash, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// MinClasses — сколько классов символов (строчные, заглавные, цифры, прочие) должно встретиться
+	MinClasses       int
+	DisallowUserInfo bool
+	Breaches         BreachChecker
+}
+
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return "password does not meet policy: " + strings.Join(msgs, "; ")
+}
+
+// Check проверяет пароль и возвращает *PolicyError со всеми нарушениями сразу.
+// username и email нужны для запрета паролей, содержащих данные пользователя.
+func (p Policy) Check(password, username, email string) error {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{"too_short", fmt.Sprintf("must be at least %d characters long", p.MinLength)})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{"too_long", fmt.Sprintf("must be at most %d characters long", p.MaxLength)})
+	}
+
+	if classes := countClasses(password); classes < p.MinClasses {
+		violations = append(violations, Violation{"too_simple", fmt.Sprintf("must contain at least %d of: lowercase, uppercase, digits, symbols", p.MinClasses)})
+	}
+
+	if p.DisallowUserInfo && containsUserInfo(password, username, email) {
+		violations = append(violations, Violation{"contains_user_info", "must not contain your username or email"})
+	}
+
+	if p.Breaches != nil {
+		breached, err := p.Breaches.IsBreached(password)
+		if err != nil {
+			return fmt.Errorf("error checking breached passwords: %w", err)
+		}
+		if breached {
+			violations = append(violations, Violation{"breached", "appears in a known data breach, choose another one"})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func countClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	count := 0
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			count++
+		}
+	}
+	return count
+}
+
+func containsUserInfo(password, username, email string) bool {
+	lowered := strings.ToLower(password)
+
+	candidates := []string{strings.ToLower(username)}
+	if local, _, ok := strings.Cut(strings.ToLower(email), "@"); ok {
+		candidates = append(candidates, local)
+	}
+
+	for _, c := range candidates {
+		// Слишком короткие имена дают ложные срабатывания
+		if len(c) >= 3 && strings.Contains(lowered, c) {
+			return true
+		}
+	}
+	return false
+}
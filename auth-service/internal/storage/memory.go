@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"auth-service/internal/models"
+)
+
+// MemoryStore хранит пользователей и приглашения в памяти процесса.
+type MemoryStore struct {
+	mu           sync.RWMutex
+	users        map[int32]models.User
+	invites      map[string]models.Invite
+	nextUserID   int32
+	nextInviteID int32
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:   make(map[int32]models.User),
+		invites: make(map[string]models.Invite),
+	}
+}
+
+func (s *MemoryStore) AddUser(ctx context.Context, user models.User, inviteCode string, inviteOnly bool) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Username == user.Username {
+			return nil, &ConflictError{Field: "username"}
+		}
+		if existing.Email == user.Email {
+			return nil, &ConflictError{Field: "email"}
+		}
+	}
+
+	if inviteOnly && inviteCode == "" && len(s.users) > 0 {
+		return nil, ErrInviteRequired
+	}
+	if inviteCode != "" {
+		invite, ok := s.invites[inviteCode]
+		if !ok || invite.Uses >= invite.MaxUses || (invite.ExpiresAt != nil && !invite.ExpiresAt.After(time.Now())) {
+			return nil, ErrInvalidInvite
+		}
+		invite.Uses++
+		s.invites[inviteCode] = invite
+	}
+
+	user.Role = models.RoleUser
+	if len(s.users) == 0 {
+		user.Role = models.RoleAdmin
+	}
+
+	s.nextUserID++
+	user.ID = s.nextUserID
+	s.users[user.ID] = user
+	return &user, nil
+}
+
+func (s *MemoryStore) HasUsers(ctx context.Context) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.users) > 0, nil
+}
+
+func (s *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) GetUserByID(ctx context.Context, id int32) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (s *MemoryStore) UpdateUserPassword(ctx context.Context, id int32, hashedPassword string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Password = hashedPassword
+	s.users[id] = user
+	return nil
+}
+
+func (s *MemoryStore) CreateInvite(ctx context.Context, invite models.Invite) (*models.Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.invites[invite.Code]; ok {
+		return nil, ErrConflict
+	}
+
+	s.nextInviteID++
+	invite.ID = s.nextInviteID
+	invite.Uses = 0
+	invite.CreatedAt = time.Now()
+	s.invites[invite.Code] = invite
+	return &invite, nil
+}
+
+func (s *MemoryStore) GetUserInvites(ctx context.Context, userID int32) ([]models.Invite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invites := []models.Invite{}
+	for _, invite := range s.invites {
+		if invite.CreatedBy == userID {
+			invites = append(invites, invite)
+		}
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].ID > invites[j].ID
+	})
+	return invites, nil
+}
@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+-- Админом становится первый зарегистрированный, но в существующей базе он
+-- уже есть: без этого в ней не осталось бы ни одного админа
+UPDATE users SET role = 'admin'
+WHERE id = (SELECT MIN(id) FROM users)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin');
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"auth-service/internal/models"
+)
+
+const uniqueViolationCode = "23505"
+
+// Имена ограничений, которые Postgres создает для UNIQUE-колонок таблицы users
+var uniqueConstraintFields = map[string]string{
+	"users_username_key": "username",
+	"users_email_key":    "email",
+}
+
+func mapUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		if field, ok := uniqueConstraintFields[pgErr.ConstraintName]; ok {
+			return &ConflictError{Field: field}
+		}
+		return ErrConflict
+	}
+	return err
+}
+
+// Ключ advisory-блокировки Postgres, под которой регистрации идут по одной:
+// иначе две первые регистрации могли бы обе увидеть пустую таблицу
+var registrationLockID = func() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("user_registration"))
+	return int64(h.Sum64())
+}()
+
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+func (s *PostgresStore) AddUser(ctx context.Context, user models.User, inviteCode string, inviteOnly bool) (*models.User, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", registrationLockID); err != nil {
+		return nil, fmt.Errorf("error locking registration: %w", err)
+	}
+	// Под блокировкой каждый запрос READ COMMITTED видит всех, кто зарегистрировался раньше
+	var hasUsers bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users)").Scan(&hasUsers); err != nil {
+		return nil, fmt.Errorf("error checking users: %w", err)
+	}
+	if inviteOnly && inviteCode == "" && hasUsers {
+		return nil, ErrInviteRequired
+	}
+
+	if inviteCode != "" {
+		var inviteID int32
+		err := tx.QueryRow(ctx,
+			`UPDATE invites SET uses = uses + 1
+			 WHERE code = $1 AND uses < max_uses AND (expires_at IS NULL OR expires_at > NOW())
+			 RETURNING id`,
+			inviteCode).Scan(&inviteID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidInvite
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error redeeming invite: %w", err)
+		}
+	}
+
+	user.Role = models.RoleUser
+	if !hasUsers {
+		user.Role = models.RoleAdmin
+	}
+	err = tx.QueryRow(ctx,
+		"INSERT INTO users (username, email, password, role) VALUES ($1, $2, $3, $4) RETURNING id",
+		user.Username, user.Email, user.Password, user.Role).Scan(&user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting user: %w", mapUniqueViolation(err))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing user: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *PostgresStore) HasUsers(ctx context.Context) (bool, error) {
+	var exists bool
+	err := s.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users)").Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking users: %w", err)
+	}
+	return exists, nil
+}
+
+func (s *PostgresStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := s.pool.QueryRow(ctx,
+		"SELECT id, username, email, password, role FROM users WHERE email = $1",
+		email).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user by email: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *PostgresStore) GetUserByID(ctx context.Context, id int32) (*models.User, error) {
+	var user models.User
+	err := s.pool.QueryRow(ctx,
+		"SELECT id, username, email, password, role FROM users WHERE id = $1",
+		id).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user by id: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *PostgresStore) UpdateUserPassword(ctx context.Context, id int32, hashedPassword string) error {
+	result, err := s.pool.Exec(ctx,
+		"UPDATE users SET password = $1 WHERE id = $2",
+		hashedPassword, id)
+	if err != nil {
+		return fmt.Errorf("error updating password: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) CreateInvite(ctx context.Context, invite models.Invite) (*models.Invite, error) {
+	err := s.pool.QueryRow(ctx,
+		"INSERT INTO invites (code, created_by, max_uses, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		invite.Code, invite.CreatedBy, invite.MaxUses, invite.ExpiresAt).Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting invite: %w", err)
+	}
+	return &invite, nil
+}
+
+func (s *PostgresStore) GetUserInvites(ctx context.Context, userID int32) ([]models.Invite, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT id, code, created_by, max_uses, uses, expires_at, created_at FROM invites WHERE created_by = $1 ORDER BY created_at DESC, id DESC",
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []models.Invite{}
+	for rows.Next() {
+		var invite models.Invite
+		err := rows.Scan(&invite.ID, &invite.Code, &invite.CreatedBy, &invite.MaxUses, &invite.Uses, &invite.ExpiresAt, &invite.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invite: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
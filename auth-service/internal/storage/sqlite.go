@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"auth-service/internal/models"
+)
+
+// OpenSQLite открывает (или создает) файл базы для однобинарного режима.
+// WAL и busy_timeout позволяют нескольким соединениям писать без "database is locked".
+func OpenSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error connecting to sqlite database: %w", err)
+	}
+	return db, nil
+}
+
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(ctx context.Context, db *sql.DB) (*SQLiteStore, error) {
+	migrator, err := SQLiteMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrator.Up(ctx); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// mapSQLiteUniqueViolation разбирает "UNIQUE constraint failed: users.email"
+func mapSQLiteUniqueViolation(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return err
+	}
+
+	for _, field := range []string{"username", "email"} {
+		if strings.Contains(sqliteErr.Error(), "users."+field) {
+			return &ConflictError{Field: field}
+		}
+	}
+	return ErrConflict
+}
+
+func (s *SQLiteStore) AddUser(ctx context.Context, user models.User, inviteCode string, inviteOnly bool) (*models.User, error) {
+	err := s.immediateTx(ctx, func(conn *sql.Conn) error {
+		var hasUsers bool
+		if err := conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users)").Scan(&hasUsers); err != nil {
+			return fmt.Errorf("error checking users: %w", err)
+		}
+		if inviteOnly && inviteCode == "" && hasUsers {
+			return ErrInviteRequired
+		}
+
+		if inviteCode != "" {
+			var inviteID int32
+			err := conn.QueryRowContext(ctx,
+				`UPDATE invites SET uses = uses + 1
+				 WHERE code = ? AND uses < max_uses AND (expires_at IS NULL OR expires_at > ?)
+				 RETURNING id`,
+				inviteCode, time.Now().UTC()).Scan(&inviteID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidInvite
+			}
+			if err != nil {
+				return fmt.Errorf("error redeeming invite: %w", err)
+			}
+		}
+
+		user.Role = models.RoleUser
+		if !hasUsers {
+			user.Role = models.RoleAdmin
+		}
+		err := conn.QueryRowContext(ctx,
+			"INSERT INTO users (username, email, password, role) VALUES (?, ?, ?, ?) RETURNING id",
+			user.Username, user.Email, user.Password, user.Role).Scan(&user.ID)
+		if err != nil {
+			return fmt.Errorf("error inserting user: %w", mapSQLiteUniqueViolation(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// immediateTx выполняет fn в транзакции BEGIN IMMEDIATE: блокировка на запись
+// берется сразу, поэтому проверка "есть ли пользователи" и вставка не
+// перемежаются с чужой регистрацией. database/sql так начинать транзакции не
+// умеет, поэтому она ведется вручную на выделенном соединении.
+func (s *SQLiteStore) immediateTx(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	if err := fn(conn); err != nil {
+		conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) HasUsers(ctx context.Context) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users)").Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking users: %w", err)
+	}
+	return exists, nil
+}
+
+func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, username, email, password, role FROM users WHERE email = ?",
+		email).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user by email: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *SQLiteStore) GetUserByID(ctx context.Context, id int32) (*models.User, error) {
+	var user models.User
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, username, email, password, role FROM users WHERE id = ?",
+		id).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user by id: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *SQLiteStore) UpdateUserPassword(ctx context.Context, id int32, hashedPassword string) error {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE users SET password = ? WHERE id = ?",
+		hashedPassword, id)
+	if err != nil {
+		return fmt.Errorf("error updating password: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) CreateInvite(ctx context.Context, invite models.Invite) (*models.Invite, error) {
+	// Время храним в UTC, чтобы строковое сравнение expires_at в AddUser было корректным
+	invite.CreatedAt = time.Now().UTC()
+	var expiresAt *time.Time
+	if invite.ExpiresAt != nil {
+		utc := invite.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO invites (code, created_by, max_uses, expires_at, created_at) VALUES (?, ?, ?, ?, ?) RETURNING id",
+		invite.Code, invite.CreatedBy, invite.MaxUses, expiresAt, invite.CreatedAt).Scan(&invite.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting invite: %w", err)
+	}
+	return &invite, nil
+}
+
+func (s *SQLiteStore) GetUserInvites(ctx context.Context, userID int32) ([]models.Invite, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, code, created_by, max_uses, uses, expires_at, created_at FROM invites WHERE created_by = ? ORDER BY created_at DESC, id DESC",
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []models.Invite{}
+	for rows.Next() {
+		var invite models.Invite
+		err := rows.Scan(&invite.ID, &invite.Code, &invite.CreatedBy, &invite.MaxUses, &invite.Uses, &invite.ExpiresAt, &invite.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invite: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
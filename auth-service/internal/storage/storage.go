@@ -1,87 +1,68 @@
-package storage
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"os"
-	"sync"
-
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
-
-	"auth-service/internal/models"
-)
-
-var (
-	dbPool *pgxpool.Pool
-	once   sync.Once
-)
-
-func initDB() {
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Error loading .env: %s", err)
-		return
-	}
-
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), 
-		os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), 
-		os.Getenv("DB_NAME"), os.Getenv("DB_SSLMODE"))
-
-	config, err := pgxpool.ParseConfig(connStr)
-	if err != nil {
-		log.Fatalf("Error parsing database config: %s", err)
-	}
-
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
-	if err != nil {
-		log.Fatalf("Error creating database pool: %s", err)
-	}
-
-	dbPool = pool
-	log.Println("Database connection established")
-}
-
-
-func AddUser(ctx context.Context, user models.User) (int32, error) {
-	once.Do(initDB)
-	
-	var id int32
-	err := dbPool.QueryRow(ctx, 
-		"INSERT INTO users (username, email, password) VALUES ($1, $2, $3) RETURNING id",
-		user.Username, user.Email, user.Password).Scan(&id)
-		
-	if err != nil {
-		return 0, fmt.Errorf("error inserting user: %w", err)
-	}
-	return id, nil
-}
-
-func GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	once.Do(initDB)
-	
-	var user models.User
-	err := dbPool.QueryRow(ctx, 
-		"SELECT id, username, email, password FROM users WHERE email = $1", 
-		email).Scan(&user.ID, &user.Username, &user.Email, &user.Password)
-		
-	if err != nil {
-		return nil, fmt.Errorf("error fetching user by email: %w", err)
-	}
-	return &user, nil
-}
-
-func GetUserByID(ctx context.Context, id int32) (*models.User, error) {
-	once.Do(initDB)
-	
-	var user models.User
-	err := dbPool.QueryRow(ctx, 
-		"SELECT id, username, email, password FROM users WHERE id = $1", 
-		id).Scan(&user.ID, &user.Username, &user.Email, &user.Password)
-		
-	if err != nil {
-		return nil, fmt.Errorf("error fetching user by id: %w", err)
-	}
-	return &user, nil
-}
\ No newline at end of file
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"auth-service/internal/models"
+)
+
+var (
+	ErrInvalidInvite = errors.New("invalid or expired invite code")
+	// ErrInviteRequired — без приглашения можно создать только первого пользователя
+	ErrInviteRequired = errors.New("invite code required")
+	ErrConflict       = errors.New("conflict")
+	ErrNotFound       = errors.New("user not found")
+)
+
+// ConflictError сообщает, какое уникальное поле пользователя уже занято.
+type ConflictError struct {
+	Field string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s already taken", e.Field)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// UserStore — хранилище пользователей и приглашений. Приглашения живут
+// рядом с пользователями, потому что погашаются в одной транзакции с регистрацией.
+type UserStore interface {
+	// AddUser создает пользователя. Если передан inviteCode, приглашение
+	// погашается атомарно с вставкой. Первый пользователь становится администратором.
+	// При inviteOnly без приглашения создается только первый пользователь, иначе
+	// ErrInviteRequired. Регистрации выполняются по одной, поэтому двух первых не бывает.
+	AddUser(ctx context.Context, user models.User, inviteCode string, inviteOnly bool) (*models.User, error)
+	HasUsers(ctx context.Context) (bool, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id int32) (*models.User, error)
+	UpdateUserPassword(ctx context.Context, id int32, hashedPassword string) error
+
+	CreateInvite(ctx context.Context, invite models.Invite) (*models.Invite, error)
+	GetUserInvites(ctx context.Context, userID int32) ([]models.Invite, error)
+}
+
+func NewPostgresPool(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing database config: %w", err)
+	}
+	// Каждый запрос становится дочерним спаном текущей трассировки
+	config.ConnConfig.Tracer = otelpgx.NewTracer()
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating database pool: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Database connection established")
+	return pool, nil
+}
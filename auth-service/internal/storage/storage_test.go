@@ -0,0 +1,75 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"auth-service/internal/storage"
+	"auth-service/internal/storage/storagetest"
+)
+
+// postgresDSNEnv — база для проверок Postgres; без нее они пропускаются.
+// Таблицы пользователей очищаются перед каждой проверкой, поэтому база нужна отдельная.
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+func TestMemoryStore(t *testing.T) {
+	storagetest.RunUserStore(t, func(t *testing.T) storage.UserStore {
+		return storage.NewMemoryStore()
+	})
+}
+
+func TestSQLiteStore(t *testing.T) {
+	storagetest.RunUserStore(t, func(t *testing.T) storage.UserStore {
+		ctx := context.Background()
+		db, err := storage.OpenSQLite(ctx, filepath.Join(t.TempDir(), "auth.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		store, err := storage.NewSQLiteStore(ctx, db)
+		if err != nil {
+			t.Fatalf("NewSQLiteStore: %v", err)
+		}
+		return store
+	})
+}
+
+func TestPostgresStore(t *testing.T) {
+	pool := newPostgresPool(t)
+	storagetest.RunUserStore(t, func(t *testing.T) storage.UserStore {
+		_, err := pool.Exec(context.Background(), "TRUNCATE users, invites RESTART IDENTITY CASCADE")
+		if err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return storage.NewPostgresStore(pool)
+	})
+}
+
+func newPostgresPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skip(postgresDSNEnv + " is not set")
+	}
+	ctx := context.Background()
+	pool, err := storage.NewPostgresPool(ctx, dsn)
+	if err != nil {
+		t.Fatalf("NewPostgresPool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := storage.PostgresMigrator(pool)
+	if err != nil {
+		t.Fatalf("PostgresMigrator: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return pool
+}
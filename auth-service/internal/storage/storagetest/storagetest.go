@@ -0,0 +1,304 @@
+// Package storagetest содержит общий набор проверок, которому должна
+// соответствовать каждая реализация storage.UserStore.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/storage"
+)
+
+// Factory возвращает пустое хранилище (без пользователей и приглашений).
+type Factory func(t *testing.T) storage.UserStore
+
+func RunUserStore(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store storage.UserStore)
+	}{
+		{"AddAndGet", testAddAndGet},
+		{"FirstUserIsAdmin", testFirstUserIsAdmin},
+		{"Conflicts", testConflicts},
+		{"NotFound", testNotFound},
+		{"UpdatePassword", testUpdatePassword},
+		{"Invites", testInvites},
+		{"InviteLimits", testInviteLimits},
+		{"InviteOnly", testInviteOnly},
+		{"ConcurrentFirstUsers", testConcurrentFirstUsers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newUser(name string) models.User {
+	return models.User{
+		Username: name,
+		Email:    name + "@example.com",
+		Password: "hash-of-" + name,
+	}
+}
+
+func mustAdd(t *testing.T, store storage.UserStore, user models.User, inviteCode string) *models.User {
+	t.Helper()
+
+	created, err := store.AddUser(context.Background(), user, inviteCode, false)
+	if err != nil {
+		t.Fatalf("AddUser(%s): %v", user.Username, err)
+	}
+	if created.ID <= 0 {
+		t.Fatalf("AddUser returned id %d, want positive", created.ID)
+	}
+	return created
+}
+
+func mustInvite(t *testing.T, store storage.UserStore, code string, createdBy int32, maxUses int32, expiresAt *time.Time) *models.Invite {
+	t.Helper()
+
+	invite, err := store.CreateInvite(context.Background(), models.Invite{
+		Code:      code,
+		CreatedBy: createdBy,
+		MaxUses:   maxUses,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("CreateInvite(%s): %v", code, err)
+	}
+	return invite
+}
+
+func testAddAndGet(t *testing.T, store storage.UserStore) {
+	ctx := context.Background()
+
+	has, err := store.HasUsers(ctx)
+	if err != nil || has {
+		t.Fatalf("HasUsers on empty store = %v, %v; want false, nil", has, err)
+	}
+
+	alice := mustAdd(t, store, newUser("alice"), "")
+
+	byEmail, err := store.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	byID, err := store.GetUserByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+
+	for _, got := range []*models.User{byEmail, byID} {
+		if got.ID != alice.ID || got.Username != "alice" || got.Email != "alice@example.com" ||
+			got.Password != "hash-of-alice" || got.Role != alice.Role {
+			t.Errorf("got %+v, want %+v", got, alice)
+		}
+	}
+
+	has, err = store.HasUsers(ctx)
+	if err != nil || !has {
+		t.Errorf("HasUsers after AddUser = %v, %v; want true, nil", has, err)
+	}
+}
+
+func testFirstUserIsAdmin(t *testing.T, store storage.UserStore) {
+	first := mustAdd(t, store, newUser("first"), "")
+	second := mustAdd(t, store, newUser("second"), "")
+
+	if first.Role != models.RoleAdmin {
+		t.Errorf("first user role = %q, want %q", first.Role, models.RoleAdmin)
+	}
+	if second.Role != models.RoleUser {
+		t.Errorf("second user role = %q, want %q", second.Role, models.RoleUser)
+	}
+}
+
+func testConflicts(t *testing.T, store storage.UserStore) {
+	ctx := context.Background()
+	mustAdd(t, store, newUser("taken"), "")
+
+	sameName := newUser("taken")
+	sameName.Email = "other@example.com"
+	sameEmail := newUser("other")
+	sameEmail.Email = "taken@example.com"
+
+	for field, user := range map[string]models.User{"username": sameName, "email": sameEmail} {
+		_, err := store.AddUser(ctx, user, "", false)
+
+		var conflict *storage.ConflictError
+		if !errors.As(err, &conflict) {
+			t.Errorf("duplicate %s: err = %v, want *ConflictError", field, err)
+			continue
+		}
+		if conflict.Field != field {
+			t.Errorf("duplicate %s: conflict field = %q", field, conflict.Field)
+		}
+		if !errors.Is(err, storage.ErrConflict) {
+			t.Errorf("duplicate %s: err does not wrap ErrConflict", field)
+		}
+	}
+}
+
+func testNotFound(t *testing.T, store storage.UserStore) {
+	ctx := context.Background()
+
+	if _, err := store.GetUserByEmail(ctx, "nobody@example.com"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetUserByEmail: err = %v, want ErrNotFound", err)
+	}
+	if _, err := store.GetUserByID(ctx, 987654); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetUserByID: err = %v, want ErrNotFound", err)
+	}
+	if err := store.UpdateUserPassword(ctx, 987654, "x"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("UpdateUserPassword: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testUpdatePassword(t *testing.T, store storage.UserStore) {
+	ctx := context.Background()
+	user := mustAdd(t, store, newUser("bob"), "")
+
+	if err := store.UpdateUserPassword(ctx, user.ID, "new-hash"); err != nil {
+		t.Fatalf("UpdateUserPassword: %v", err)
+	}
+
+	got, err := store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.Password != "new-hash" {
+		t.Errorf("password = %q, want %q", got.Password, "new-hash")
+	}
+}
+
+func testInvites(t *testing.T, store storage.UserStore) {
+	ctx := context.Background()
+	admin := mustAdd(t, store, newUser("admin"), "")
+	other := mustAdd(t, store, newUser("other"), "")
+
+	expires := time.Now().Add(time.Hour)
+	first := mustInvite(t, store, "code-first", admin.ID, 2, &expires)
+	second := mustInvite(t, store, "code-second", admin.ID, 1, nil)
+	mustInvite(t, store, "code-other", other.ID, 1, nil)
+
+	if first.ID <= 0 || first.Uses != 0 || first.CreatedAt.IsZero() {
+		t.Errorf("CreateInvite = %+v, want id, zero uses and created_at", first)
+	}
+
+	invites, err := store.GetUserInvites(ctx, admin.ID)
+	if err != nil {
+		t.Fatalf("GetUserInvites: %v", err)
+	}
+	if len(invites) != 2 || invites[0].ID != second.ID || invites[1].ID != first.ID {
+		t.Fatalf("GetUserInvites = %+v, want [%d %d] newest first", invites, second.ID, first.ID)
+	}
+	if invites[1].ExpiresAt == nil || !invites[1].ExpiresAt.Truncate(time.Second).Equal(expires.Truncate(time.Second)) {
+		t.Errorf("ExpiresAt = %v, want %v", invites[1].ExpiresAt, expires)
+	}
+	if invites[0].ExpiresAt != nil {
+		t.Errorf("ExpiresAt = %v, want nil", invites[0].ExpiresAt)
+	}
+
+	mustAdd(t, store, newUser("invited"), "code-first")
+
+	invites, err = store.GetUserInvites(ctx, admin.ID)
+	if err != nil {
+		t.Fatalf("GetUserInvites: %v", err)
+	}
+	if invites[1].Uses != 1 {
+		t.Errorf("uses after redemption = %d, want 1", invites[1].Uses)
+	}
+
+	empty, err := store.GetUserInvites(ctx, 987654)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("GetUserInvites for unknown user = %v, %v; want empty", empty, err)
+	}
+}
+
+func testInviteLimits(t *testing.T, store storage.UserStore) {
+	ctx := context.Background()
+	admin := mustAdd(t, store, newUser("admin"), "")
+
+	expired := time.Now().Add(-time.Minute)
+	mustInvite(t, store, "code-once", admin.ID, 1, nil)
+	mustInvite(t, store, "code-expired", admin.ID, 5, &expired)
+
+	mustAdd(t, store, newUser("lucky"), "code-once")
+
+	cases := map[string]string{
+		"exhausted": "code-once",
+		"expired":   "code-expired",
+		"unknown":   "code-missing",
+	}
+	for name, code := range cases {
+		_, err := store.AddUser(ctx, newUser("late-"+name), code, false)
+		if !errors.Is(err, storage.ErrInvalidInvite) {
+			t.Errorf("%s invite: err = %v, want ErrInvalidInvite", name, err)
+		}
+		if _, err := store.GetUserByEmail(ctx, "late-"+name+"@example.com"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s invite: user was created despite invalid invite", name)
+		}
+	}
+}
+
+func testInviteOnly(t *testing.T, store storage.UserStore) {
+	ctx := context.Background()
+
+	admin, err := store.AddUser(ctx, newUser("admin"), "", true)
+	if err != nil {
+		t.Fatalf("first user without invite: %v", err)
+	}
+	if admin.Role != models.RoleAdmin {
+		t.Errorf("first user role = %q, want %q", admin.Role, models.RoleAdmin)
+	}
+
+	if _, err := store.AddUser(ctx, newUser("uninvited"), "", true); !errors.Is(err, storage.ErrInviteRequired) {
+		t.Errorf("second user without invite: err = %v, want ErrInviteRequired", err)
+	}
+	if _, err := store.GetUserByEmail(ctx, "uninvited@example.com"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("user was created without invite")
+	}
+
+	mustInvite(t, store, "code-join", admin.ID, 1, nil)
+	if _, err := store.AddUser(ctx, newUser("invited"), "code-join", true); err != nil {
+		t.Errorf("user with invite: %v", err)
+	}
+}
+
+// Одновременные первые регистрации: администратором становится ровно один
+func testConcurrentFirstUsers(t *testing.T, store storage.UserStore) {
+	const n = 8
+	var wg sync.WaitGroup
+	users := make([]*models.User, n)
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			users[i], errs[i] = store.AddUser(context.Background(), newUser(fmt.Sprintf("racer%d", i)), "", true)
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			created++
+			if users[i].Role != models.RoleAdmin {
+				t.Errorf("racer%d role = %q, want %q", i, users[i].Role, models.RoleAdmin)
+			}
+		case !errors.Is(err, storage.ErrInviteRequired):
+			t.Errorf("racer%d: err = %v, want nil or ErrInviteRequired", i, err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d users registered without invite, want 1", created)
+	}
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+
+	"auth-service/internal/config"
+	"auth-service/internal/password"
+)
+
+var (
+	passwordManager *password.Manager
+	passwordPolicy  password.Policy
+)
+
+// configurePasswords собирает хешер (Algorithm — основной, второй алгоритм
+// продолжает проверять старые хеши) и политику паролей.
+func configurePasswords(cfg config.Password) error {
+	params := password.Argon2Params{
+		Memory:      cfg.Argon2.MemoryKB,
+		Iterations:  cfg.Argon2.Iterations,
+		Parallelism: cfg.Argon2.Parallelism,
+		SaltLength:  cfg.Argon2.SaltLength,
+		KeyLength:   cfg.Argon2.KeyLength,
+	}
+
+	argon := password.NewArgon2idHasher(params)
+	bcryptHasher := password.NewBcryptHasher(cfg.BcryptCost)
+
+	if cfg.Algorithm == "bcrypt" {
+		passwordManager = password.NewManager(bcryptHasher, argon)
+	} else {
+		passwordManager = password.NewManager(argon, bcryptHasher)
+	}
+
+	policy := password.Policy{
+		MinLength:        cfg.MinLength,
+		MaxLength:        cfg.MaxLength,
+		MinClasses:       cfg.MinClasses,
+		DisallowUserInfo: !cfg.AllowUserInfo,
+	}
+
+	if cfg.BreachFile != "" {
+		checker, err := password.OpenBreachFile(cfg.BreachFile, cfg.BreachMinCount)
+		if err != nil {
+			return fmt.Errorf("error opening PASSWORD_BREACH_FILE: %w", err)
+		}
+		slog.Info("Using breached password hashes", "layout", checker.Layout(), "path", cfg.BreachFile)
+		policy.Breaches = checker
+	}
+
+	passwordPolicy = policy
+	return nil
+}
+
+// CheckPasswordPolicy проверяет новый пароль при регистрации, смене и сбросе.
+func CheckPasswordPolicy(newPassword, username, email string) error {
+	return passwordPolicy.Check(newPassword, username, email)
+}
@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	RegistrationOpen   = "open"
+	RegistrationInvite = "invite"
+	RegistrationClosed = "closed"
+)
+
+// GetRegistrationMode возвращает REGISTRATION_MODE; по умолчанию регистрация открыта.
+func GetRegistrationMode() string {
+	return settings.Registration.Mode
+}
+
+// GetInviteLimits возвращает ограничения для приглашений обычных пользователей:
+// максимальное число использований и срок жизни в часах.
+func GetInviteLimits() (maxUses int32, maxTTLHours int) {
+	return settings.Registration.InviteMaxUses, settings.Registration.InviteMaxTTLHours
+}
+
+func GenerateInviteCode() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
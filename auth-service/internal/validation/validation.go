@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Лимиты совпадают с VARCHAR в таблице users (миграция 0001_create_users)
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 50
+	EmailMaxLength    = 100
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func ValidateUsername(username string) []FieldError {
+	var errs []FieldError
+
+	length := utf8.RuneCountInString(username)
+	switch {
+	case strings.TrimSpace(username) == "":
+		errs = append(errs, FieldError{"username", "required", "username is required"})
+		return errs
+	case length < UsernameMinLength:
+		errs = append(errs, FieldError{"username", "too_short", "username must be at least 3 characters long"})
+	case length > UsernameMaxLength:
+		errs = append(errs, FieldError{"username", "too_long", "username must be at most 50 characters long"})
+	}
+
+	if !usernamePattern.MatchString(username) {
+		errs = append(errs, FieldError{"username", "invalid_characters", "username may contain only latin letters, digits, '.', '_' and '-'"})
+	}
+	return errs
+}
+
+func ValidateEmail(email string) []FieldError {
+	if strings.TrimSpace(email) == "" {
+		return []FieldError{{"email", "required", "email is required"}}
+	}
+	if len(email) > EmailMaxLength {
+		return []FieldError{{"email", "too_long", "email must be at most 100 characters long"}}
+	}
+
+	// Отбрасываем формы вроде "Name <a@b.c>", которые ParseAddress тоже принимает
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		return []FieldError{{"email", "invalid_format", "email is not a valid address"}}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package apierror
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+)
+
+// Error — единый формат ошибки обоих сервисов:
+// {"error": {"code": ..., "message": ..., "details": ..., "request_id": ...}}
+type Error struct {
+	Status    int    `json:"-"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// WithDetails возвращает копию ошибки с дополнительными данными (например, ошибками полей).
+func (e *Error) WithDetails(details any) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func Validation(message string, details any) *Error {
+	return New(http.StatusBadRequest, CodeValidationFailed, message).WithDetails(details)
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func MethodNotAllowed() *Error {
+	return New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+func TooManyRequests(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeRateLimited, message)
+}
+
+func Internal(message string) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message)
+}
+
+type envelope struct {
+	Error *Error `json:"error"`
+}
+
+// Write отправляет ошибку в формате конверта. Request ID берется из запроса
+// или ответа, а если его нет — генерируется и возвращается в заголовке.
+func Write(w http.ResponseWriter, r *http.Request, e *Error) {
+	c := *e
+	c.RequestID = RequestID(w, r)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(RequestIDHeader, c.RequestID)
+	w.WriteHeader(c.Status)
+	json.NewEncoder(w).Encode(envelope{Error: &c})
+}
+
+func RequestID(w http.ResponseWriter, r *http.Request) string {
+	if id := w.Header().Get(RequestIDHeader); id != "" {
+		return id
+	}
+	if r != nil {
+		if id := r.Header.Get(RequestIDHeader); id != "" {
+			return id
+		}
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
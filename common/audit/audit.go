@@ -0,0 +1,167 @@
+// Package audit — журнал событий безопасности и изменений данных. Оба сервиса
+// дописывают события в общую таблицу audit_events; изменять и удалять записи
+// запрещено на уровне базы.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"common/auth"
+	"common/logging"
+	"common/ratelimit"
+)
+
+const (
+	ActionLogin          = "user.login"
+	ActionLoginFailed    = "user.login_failed"
+	ActionPasswordChange = "user.password_change"
+	ActionPasswordReset  = "user.password_reset"
+	ActionNoteCreate     = "note.create"
+	ActionNoteUpdate     = "note.update"
+	ActionNoteDelete     = "note.delete"
+	ActionNoteShare      = "note.share"
+	ActionNoteUnshare    = "note.unshare"
+	ActionNoteCollabJoin = "note.collab_join"
+)
+
+// Event — запись журнала. ActorID — кто совершил действие (0 — аноним,
+// например неудачный вход), UserID — чей аккаунт или чьи данные затронуты;
+// по нему пользователь видит события своего аккаунта.
+type Event struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	ActorID   int32     `json:"actor_id,omitempty"`
+	UserID    int32     `json:"user_id,omitempty"`
+	TargetID  string    `json:"target_id,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	RequestID string    `json:"request_id"`
+}
+
+// Filter отбирает события; нулевые поля не ограничивают выборку.
+// События возвращаются от новых к старым, BeforeID продолжает предыдущую страницу.
+type Filter struct {
+	UserID   int32
+	ActorID  int32
+	Action   string
+	From     time.Time
+	To       time.Time
+	BeforeID int64
+	Limit    int
+}
+
+type Store interface {
+	Append(ctx context.Context, e Event) (int64, error)
+	Query(ctx context.Context, f Filter) ([]Event, error)
+}
+
+// Recorder дописывает события HTTP-запросов, заполняя время, IP,
+// User-Agent, request id и, если не задан, актора из токена.
+type Recorder struct {
+	store      Store
+	trustProxy bool
+}
+
+// NewRecorder создает Recorder; trustProxy разрешает брать IP из X-Real-IP,
+// как и ограничителю частоты запросов.
+func NewRecorder(store Store, trustProxy bool) *Recorder {
+	return &Recorder{store: store, trustProxy: trustProxy}
+}
+
+// Record сохраняет событие запроса r. Ошибка записи не прерывает запрос:
+// действие уже выполнено, поэтому она только пишется в журнал сервиса.
+func (rec *Recorder) Record(r *http.Request, e Event) {
+	rec.record(r.Context(), e, rec.client(r))
+}
+
+// RecordContext — Record для кода, у которого нет запроса, например хранилища.
+// IP и User-Agent берутся из контекста, подготовленного Middleware; вне
+// запроса они остаются пустыми.
+func (rec *Recorder) RecordContext(ctx context.Context, e Event) {
+	info, _ := ctx.Value(clientKey{}).(clientInfo)
+	rec.record(ctx, e, info)
+}
+
+func (rec *Recorder) record(ctx context.Context, e Event, info clientInfo) {
+	e.Time = time.Now().UTC()
+	if e.ActorID == 0 {
+		e.ActorID, _ = ctx.Value(actorKey{}).(int32)
+	}
+	if e.ActorID == 0 {
+		if p := auth.FromContext(ctx); p != nil {
+			e.ActorID = p.UserID
+		}
+	}
+	if e.Detail == "" {
+		e.Detail = DetailFromContext(ctx)
+	}
+	e.IP, e.UserAgent = info.ip, info.userAgent
+	e.RequestID = logging.RequestID(ctx)
+
+	// Событие пишется и тогда, когда клиент уже отключился
+	ctx = context.WithoutCancel(ctx)
+	if _, err := rec.store.Append(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "Error recording audit event", "action", e.Action, "error", err)
+	}
+}
+
+type clientKey struct{}
+
+type clientInfo struct {
+	ip        string
+	userAgent string
+}
+
+func (rec *Recorder) client(r *http.Request) clientInfo {
+	return clientInfo{ip: ratelimit.ClientIP(r, rec.trustProxy), userAgent: truncate(r.UserAgent(), maxUserAgent)}
+}
+
+// Middleware запоминает в контексте IP и User-Agent запроса для RecordContext
+func (rec *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientKey{}, rec.client(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type (
+	actorKey  struct{}
+	detailKey struct{}
+)
+
+// WithActor задает актора событий, записанных с ctx, когда действие
+// выполняется не от имени токена запроса, например фоновым сохранением
+func WithActor(ctx context.Context, actorID int32) context.Context {
+	return context.WithValue(ctx, actorKey{}, actorID)
+}
+
+// WithDetail задает Detail по умолчанию для событий, записанных с ctx:
+// так хранилище отмечает, откуда пришло изменение
+func WithDetail(ctx context.Context, detail string) context.Context {
+	return context.WithValue(ctx, detailKey{}, detail)
+}
+
+// DetailFromContext возвращает Detail, заданный WithDetail
+func DetailFromContext(ctx context.Context) string {
+	detail, _ := ctx.Value(detailKey{}).(string)
+	return detail
+}
+
+func (rec *Recorder) Query(ctx context.Context, f Filter) ([]Event, error) {
+	return rec.store.Query(ctx, f)
+}
+
+const maxUserAgent = 512
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
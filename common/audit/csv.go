@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{"id", "time", "action", "actor_id", "user_id", "target_id", "detail", "ip", "user_agent", "request_id"}
+
+// WriteCSV выгружает события в CSV с заголовком
+func WriteCSV(w io.Writer, events []Event) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range events {
+		err := cw.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.Time.UTC().Format(time.RFC3339Nano),
+			e.Action,
+			formatID(e.ActorID),
+			formatID(e.UserID),
+			csvCell(e.TargetID),
+			csvCell(e.Detail),
+			csvCell(e.IP),
+			csvCell(e.UserAgent),
+			csvCell(e.RequestID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatID(id int32) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(int(id))
+}
+
+// csvCell не дает табличным редакторам выполнить значение как формулу:
+// User-Agent и другие поля приходят от клиента.
+func csvCell(s string) string {
+	if s == "" {
+		return s
+	}
+	switch s[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + s
+	}
+	return s
+}
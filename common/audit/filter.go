@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+var ErrInvalidFilter = errors.New("invalid audit filter")
+
+// ParseFilter читает фильтр из параметров запроса: user_id, actor_id, action,
+// from и to (RFC 3339), before и limit.
+func ParseFilter(q url.Values) (Filter, error) {
+	f := Filter{Action: q.Get("action"), Limit: DefaultLimit}
+
+	var err error
+	if f.UserID, err = parseID(q, "user_id"); err != nil {
+		return Filter{}, err
+	}
+	if f.ActorID, err = parseID(q, "actor_id"); err != nil {
+		return Filter{}, err
+	}
+	if f.From, err = parseTime(q, "from"); err != nil {
+		return Filter{}, err
+	}
+	if f.To, err = parseTime(q, "to"); err != nil {
+		return Filter{}, err
+	}
+	if v := q.Get("before"); v != "" {
+		if f.BeforeID, err = strconv.ParseInt(v, 10, 64); err != nil || f.BeforeID <= 0 {
+			return Filter{}, fmt.Errorf("%w: before must be a positive id", ErrInvalidFilter)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit <= 0 || f.Limit > MaxLimit {
+			return Filter{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, MaxLimit)
+		}
+	}
+	return f, nil
+}
+
+func parseID(q url.Values, name string) (int32, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: %s must be a positive id", ErrInvalidFilter, name)
+	}
+	return int32(id), nil
+}
+
+func parseTime(q url.Values, name string) (time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be an RFC 3339 time", ErrInvalidFilter, name)
+	}
+	return t.UTC(), nil
+}
+
+// where собирает условие WHERE и его параметры; placeholder возвращает
+// n-й параметр (с единицы) в синтаксисе СУБД.
+func (f Filter) where(placeholder func(n int) string) (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, cond+placeholder(len(args)))
+	}
+
+	if f.UserID != 0 {
+		add("user_id = ", f.UserID)
+	}
+	if f.ActorID != 0 {
+		add("actor_id = ", f.ActorID)
+	}
+	if f.Action != "" {
+		add("action = ", f.Action)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= ", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		add("created_at < ", f.To.UTC())
+	}
+	if f.BeforeID != 0 {
+		add("id < ", f.BeforeID)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// limit возвращает LIMIT для запроса; Limit 0 означает выборку без ограничения
+func (f Filter) limit() string {
+	if f.Limit <= 0 {
+		return ""
+	}
+	return " LIMIT " + strconv.Itoa(f.Limit)
+}
+
+// matches — тот же фильтр для хранилища в памяти
+func (f Filter) matches(e Event) bool {
+	switch {
+	case f.UserID != 0 && e.UserID != f.UserID:
+		return false
+	case f.ActorID != 0 && e.ActorID != f.ActorID:
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case !f.From.IsZero() && e.Time.Before(f.From):
+		return false
+	case !f.To.IsZero() && !e.Time.Before(f.To):
+		return false
+	case f.BeforeID != 0 && e.ID >= f.BeforeID:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore хранит события в памяти процесса — для локального запуска
+// без базы; события разных процессов не видны друг другу.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	events []Event
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Append(ctx context.Context, e Event) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.ID = int64(len(s.events)) + 1
+	s.events = append(s.events, e)
+	return e.ID, nil
+}
+
+func (s *MemoryStore) Query(ctx context.Context, f Filter) ([]Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []Event
+	for i := len(s.events) - 1; i >= 0; i-- {
+		if f.Limit > 0 && len(events) == f.Limit {
+			break
+		}
+		if f.matches(s.events[i]) {
+			events = append(events, s.events[i])
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"database/sql"
+	"embed"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"common/migrate"
+)
+
+// Журнал общий для сервисов, поэтому у его схемы своя таблица версий:
+// ее применяет любой из сервисов, а advisory-блокировка не дает сделать это дважды.
+const migrationsTable = "audit_schema_migrations"
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+func PostgresMigrator(pool *pgxpool.Pool) (*migrate.Migrator, error) {
+	migrations, err := migrate.Load(migrationFiles, "migrations/postgres")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(migrate.Postgres(pool, migrationsTable), migrations), nil
+}
+
+func SQLiteMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	migrations, err := migrate.Load(migrationFiles, "migrations/sqlite")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(migrate.SQLite(db, migrationsTable), migrations), nil
+}
@@ -0,0 +1,29 @@
+-- Журнал аудита, общий для auth-service и notes-service. 0 в actor_id и
+-- user_id означает отсутствие пользователя (например, вход с неизвестной почтой).
+CREATE TABLE audit_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    actor_id INTEGER NOT NULL DEFAULT 0,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    target_id VARCHAR(64) NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_audit_events_user_id ON audit_events(user_id, id DESC);
+CREATE INDEX idx_audit_events_actor_id ON audit_events(actor_id, id DESC);
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+
+-- Журнал только дополняется: изменить или удалить запись нельзя даже сервисам
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
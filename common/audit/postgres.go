@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStore создает хранилище поверх пула сервиса; схему готовит PostgresMigrator.
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+const eventColumns = "id, created_at, action, actor_id, user_id, target_id, detail, ip, user_agent, request_id"
+
+func (s *PostgresStore) Append(ctx context.Context, e Event) (int64, error) {
+	var id int64
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO audit_events (created_at, action, actor_id, user_id, target_id, detail, ip, user_agent, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		e.Time, e.Action, e.ActorID, e.UserID, e.TargetID, e.Detail, e.IP, e.UserAgent, e.RequestID).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting audit event: %w", err)
+	}
+	return id, nil
+}
+
+func (s *PostgresStore) Query(ctx context.Context, f Filter) ([]Event, error) {
+	where, args := f.where(func(n int) string { return fmt.Sprintf("$%d", n) })
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+eventColumns+" FROM audit_events"+where+" ORDER BY id DESC"+f.limit(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching audit events: %w", err)
+	}
+
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Event, error) {
+		var e Event
+		err := row.Scan(&e.ID, &e.Time, &e.Action, &e.ActorID, &e.UserID, &e.TargetID, &e.Detail, &e.IP, &e.UserAgent, &e.RequestID)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning audit events: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SQLiteStore хранит события в SQLite-файле однонодового режима
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore применяет миграции журнала и создает хранилище. Файл открывает
+// вызывающий сервис вместе с драйвером.
+func NewSQLiteStore(ctx context.Context, db *sql.DB) (*SQLiteStore, error) {
+	migrator, err := SQLiteMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrator.Up(ctx); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Append(ctx context.Context, e Event) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO audit_events (created_at, action, actor_id, user_id, target_id, detail, ip, user_agent, request_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		e.Time.UTC(), e.Action, e.ActorID, e.UserID, e.TargetID, e.Detail, e.IP, e.UserAgent, e.RequestID).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting audit event: %w", err)
+	}
+	return id, nil
+}
+
+func (s *SQLiteStore) Query(ctx context.Context, f Filter) ([]Event, error) {
+	where, args := f.where(func(int) string { return "?" })
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+eventColumns+" FROM audit_events"+where+" ORDER BY id DESC"+f.limit(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		err := rows.Scan(&e.ID, &e.Time, &e.Action, &e.ActorID, &e.UserID, &e.TargetID, &e.Detail, &e.IP, &e.UserAgent, &e.RequestID)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning audit event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
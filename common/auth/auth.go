@@ -0,0 +1,182 @@
+// Package auth — общая аутентификация сервисов. auth-service выпускает JWT
+// (HS256, общий секрет JWT_SECRET), а Middleware обоих сервисов проверяет его
+// один раз на запрос и кладет в контекст Principal; обработчики токен не разбирают.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+
+	ScopeNotesRead  = "notes:read"
+	ScopeNotesWrite = "notes:write"
+)
+
+// SessionScopes получает токен входа через браузер. Токенам, выпущенным
+// до появления scope, достаются они же.
+var SessionScopes = []string{ScopeNotesRead, ScopeNotesWrite}
+
+var ErrNoToken = errors.New("no token provided")
+
+// Principal — аутентифицированный пользователь текущего запроса
+type Principal struct {
+	UserID   int32
+	Username string
+	Email    string
+	Roles    []string
+	Scopes   []string
+	// jti; пустой у токенов, выпущенных до его появления
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Role возвращает основную роль: admin, если она есть, иначе user
+func (p *Principal) Role() string {
+	if p.HasRole(RoleAdmin) {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает пользователя запроса или nil для анонимного запроса.
+// В обработчиках за Require результат всегда не nil.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Authenticator выпускает и проверяет токены
+type Authenticator struct {
+	secret []byte
+}
+
+func New(secret string) *Authenticator {
+	return &Authenticator{secret: []byte(secret)}
+}
+
+// Check — проверка для /readyz: без секрета токены не выпустить и не проверить
+func (a *Authenticator) Check(ctx context.Context) error {
+	if len(a.secret) == 0 {
+		return errors.New("JWT secret is not configured")
+	}
+	return nil
+}
+
+// Issue выпускает токен для p на ttl, назначая ему новый TokenID
+func (a *Authenticator) Issue(p Principal, ttl time.Duration) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("error generating token id: %w", err)
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":      hex.EncodeToString(id),
+		"id":       p.UserID,
+		"username": p.Username,
+		"email":    p.Email,
+		// role оставлен для клиентов, читающих его из старых токенов
+		"role":  p.Role(),
+		"roles": p.Roles,
+		"scope": strings.Join(p.Scopes, " "),
+		"iat":   now.Unix(),
+		"exp":   now.Add(ttl).Unix(),
+	})
+	return token.SignedString(a.secret)
+}
+
+// Parse проверяет подпись и срок токена и собирает Principal из claims
+func (a *Authenticator) Parse(tokenString string) (*Principal, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return a.secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+
+	userID, err := claimUserID(claims["id"])
+	if err != nil {
+		return nil, err
+	}
+	p := &Principal{UserID: userID}
+	p.Username, _ = claims["username"].(string)
+	p.Email, _ = claims["email"].(string)
+	p.TokenID, _ = claims["jti"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		p.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, r := range roles {
+			if role, ok := r.(string); ok {
+				p.Roles = append(p.Roles, role)
+			}
+		}
+	}
+	// В токенах без ролей есть только role, а в самых старых нет и его
+	if role, ok := claims["role"].(string); ok && !p.HasRole(role) {
+		p.Roles = append(p.Roles, role)
+	}
+	if len(p.Roles) == 0 {
+		p.Roles = []string{RoleUser}
+	}
+
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = slices.Clone(SessionScopes)
+	}
+	return p, nil
+}
+
+func claimUserID(v interface{}) (int32, error) {
+	switch id := v.(type) {
+	case float64:
+		return int32(id), nil
+	case string:
+		n, err := strconv.ParseInt(id, 10, 32)
+		if err != nil {
+			return 0, errors.New("invalid user ID format")
+		}
+		return int32(n), nil
+	case nil:
+		return 0, errors.New("user ID not found in token")
+	default:
+		return 0, errors.New("unexpected user ID type")
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"common/apierror"
+	"common/logging"
+)
+
+// CookieName — cookie с токеном сессии браузера
+const CookieName = "token"
+
+// TokenFromRequest берет токен из заголовка Authorization: Bearer или из cookie
+func TokenFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); len(h) > 7 && h[:7] == "Bearer " {
+		return h[7:]
+	}
+	if cookie, err := r.Cookie(CookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// Middleware проверяет токен запроса и кладет Principal в контекст. Запрос без
+// токена или с недействительным токеном проходит дальше анонимным: решение
+// принимают Require* на конкретных маршрутах.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := TokenFromRequest(r)
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p, err := a.Parse(token)
+		if err != nil {
+			slog.DebugContext(r.Context(), "Ignoring invalid token", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logging.AddAttrs(r.Context(), "user_id", p.UserID)
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+// Require пропускает только аутентифицированные запросы, остальным — 401
+func Require(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if FromContext(r.Context()) == nil {
+			apierror.Write(w, r, apierror.Unauthorized("Unauthorized"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequireRole требует роль, например admin; без нее — 403
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return Require(func(w http.ResponseWriter, r *http.Request) {
+		if !FromContext(r.Context()).HasRole(role) {
+			apierror.Write(w, r, apierror.Forbidden("Forbidden"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+// RequireScope требует scope токена, например notes:write; без него — 403
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return Require(func(w http.ResponseWriter, r *http.Request) {
+		if !FromContext(r.Context()).HasScope(scope) {
+			apierror.Write(w, r, apierror.Forbidden("Token lacks scope "+scope))
+			return
+		}
+		next(w, r)
+	})
+}
+
+// RateLimitKey — ключ ratelimit: вошедший пользователь считается по id,
+// анонимный (пустая строка) — по IP.
+func RateLimitKey(r *http.Request) string {
+	if p := FromContext(r.Context()); p != nil {
+		return fmt.Sprintf("user:%d", p.UserID)
+	}
+	return ""
+}
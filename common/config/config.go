@@ -0,0 +1,202 @@
+// Package config загружает типизированную конфигурацию сервисов.
+//
+// Поля структуры описываются тегами:
+//
+//	yaml:"..."    ключ в YAML-файле (вложенные структуры — вложенные секции)
+//	env:"..."     переменная окружения; KEY_FILE читает значение из файла (Docker/k8s secrets)
+//	default:"..." значение по умолчанию
+//	secret:"true" значение скрывается в Dump
+//
+// Порядок приоритета: значения по умолчанию, затем YAML-файл, затем .env,
+// затем переменные окружения.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Errors собирает все ошибки конфигурации, чтобы сервис сообщил о них разом при старте.
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+// Addf добавляет ошибку валидации. Повторы пропускаются: одна переменная
+// окружения может заполнять поля нескольких секций.
+func (e *Errors) Addf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	for _, existing := range *e {
+		if existing == msg {
+			return
+		}
+	}
+	*e = append(*e, msg)
+}
+
+// Err возвращает nil, если ошибок нет
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Load заполняет dst (указатель на структуру). path — необязательный YAML-файл.
+// .env в текущем каталоге тоже необязателен и не перекрывает уже заданные переменные.
+func Load(dst any, path string) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return errors.New("config: destination must be a pointer to struct")
+	}
+
+	var errs Errors
+	walk(v.Elem(), "", func(field reflect.Value, sf reflect.StructField, _ string) {
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			if err := setValue(field, def); err != nil {
+				errs.Addf("%s: bad default %q: %s", sf.Name, def, err)
+			}
+		}
+	})
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, dst); err != nil {
+			return fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	}
+
+	godotenv.Load()
+
+	walk(v.Elem(), "", func(field reflect.Value, sf reflect.StructField, _ string) {
+		key := sf.Tag.Get("env")
+		if key == "" {
+			return
+		}
+
+		raw, ok, err := lookupEnv(key)
+		if err != nil {
+			errs.Addf("%s: %s", key, err)
+			return
+		}
+		if !ok {
+			return
+		}
+		if err := setValue(field, raw); err != nil {
+			errs.Addf("%s: invalid value %q: %s", key, raw, err)
+		}
+	})
+
+	return errs.Err()
+}
+
+// lookupEnv читает KEY, а если задан KEY_FILE — содержимое этого файла
+func lookupEnv(key string) (string, bool, error) {
+	if file := os.Getenv(key + "_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("error reading %s_FILE: %w", key, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+	v, ok := os.LookupEnv(key)
+	return v, ok, nil
+}
+
+// Dump печатает итоговую конфигурацию в YAML, заменяя секреты на "********".
+func Dump(w io.Writer, cfg any) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	// Работаем с копией, чтобы не испортить настоящую конфигурацию
+	copied := reflect.New(v.Type()).Elem()
+	copied.Set(v)
+	walk(copied, "", func(field reflect.Value, sf reflect.StructField, _ string) {
+		if sf.Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "" {
+			field.SetString("********")
+		}
+	})
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(copied.Interface()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// walk обходит листовые поля, спускаясь во вложенные структуры
+func walk(v reflect.Value, prefix string, fn func(reflect.Value, reflect.StructField, string)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		field := v.Field(i)
+		name := prefix + sf.Name
+		if field.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Duration(0)) {
+			walk(field, name+".", fn)
+			continue
+		}
+		fn(field, sf, name)
+	}
+}
+
+func setValue(field reflect.Value, raw string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Общие секции, которые используют оба сервиса.
+
+type Database struct {
+	Host     string `yaml:"host" env:"DB_HOST" default:"localhost"`
+	Port     int    `yaml:"port" env:"DB_PORT" default:"5432"`
+	User     string `yaml:"user" env:"DB_USER" default:"postgres"`
+	Password string `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" env:"DB_NAME" default:"notes_manager"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE" default:"disable"`
+}
+
+func (d Database) DSN() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     fmt.Sprintf("%s:%d", d.Host, d.Port),
+		Path:     d.Name,
+		RawQuery: "sslmode=" + url.QueryEscape(d.SSLMode),
+	}
+	return u.String()
+}
+
+func (d Database) Validate(errs *Errors) {
+	if d.Host == "" {
+		errs.Addf("DB_HOST is required")
+	}
+	if d.Port <= 0 || d.Port > 65535 {
+		errs.Addf("DB_PORT must be between 1 and 65535, got %d", d.Port)
+	}
+	if d.Name == "" {
+		errs.Addf("DB_NAME is required")
+	}
+	switch d.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs.Addf("DB_SSLMODE %q is not a valid sslmode", d.SSLMode)
+	}
+}
+
+type Redis struct {
+	Addr     string `yaml:"addr" env:"REDIS_ADDR" default:"redis:6379"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	DB       int    `yaml:"db" env:"REDIS_DB" default:"0"`
+}
+
+func (r Redis) Validate(errs *Errors) {
+	if r.Addr == "" {
+		errs.Addf("REDIS_ADDR is required")
+	}
+	if r.DB < 0 {
+		errs.Addf("REDIS_DB must not be negative")
+	}
+}
+
+// ValidatePort проверяет порт HTTP-сервера
+func ValidatePort(errs *Errors, port int) {
+	if port <= 0 || port > 65535 {
+		errs.Addf("PORT must be between 1 and 65535, got %d", port)
+	}
+}
+
+// HTTP — таймауты HTTP-сервера. ShutdownTimeout должен быть меньше
+// terminationGracePeriodSeconds в k8s, иначе под убьют посреди дренажа.
+type HTTP struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" default:"5s"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" default:"15s"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" default:"30s"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" default:"120s"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT" default:"20s"`
+}
+
+func (h HTTP) Validate(errs *Errors) {
+	for name, d := range map[string]time.Duration{
+		"HTTP_READ_HEADER_TIMEOUT": h.ReadHeaderTimeout,
+		"HTTP_READ_TIMEOUT":        h.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":       h.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        h.IdleTimeout,
+		"HTTP_SHUTDOWN_TIMEOUT":    h.ShutdownTimeout,
+	} {
+		if d <= 0 {
+			errs.Addf("%s must be positive", name)
+		}
+	}
+}
+
+// Tracing — экспорт трассировок OpenTelemetry. Адрес коллектора и заголовки
+// OTLP-экспортер читает сам из стандартных OTEL_EXPORTER_OTLP_*.
+type Tracing struct {
+	// none, otlp или stdout (для локальной отладки)
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" default:"none"`
+	// Доля трассировок, начатых этим сервисом; входящий traceparent решает за нас
+	SampleRatio float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG" default:"1"`
+}
+
+func (t Tracing) Validate(errs *Errors) {
+	switch t.Exporter {
+	case "none", "otlp", "stdout":
+	default:
+		errs.Addf("OTEL_TRACES_EXPORTER must be none, otlp or stdout, got %q", t.Exporter)
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		errs.Addf("OTEL_TRACES_SAMPLER_ARG must be between 0 and 1, got %g", t.SampleRatio)
+	}
+}
+
+// Logging — уровень и формат журналов сервиса
+type Logging struct {
+	// debug, info, warn или error
+	Level string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	// json для сборщиков логов, text — для чтения в терминале
+	Format string `yaml:"format" env:"LOG_FORMAT" default:"json"`
+}
+
+func (l Logging) Validate(errs *Errors) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		errs.Addf("LOG_LEVEL must be debug, info, warn or error, got %q", l.Level)
+	}
+	switch l.Format {
+	case "json", "text":
+	default:
+		errs.Addf("LOG_FORMAT must be json or text, got %q", l.Format)
+	}
+}
+
+// SlogLevel возвращает уровень для slog; вызывается после Validate
+func (l Logging) SlogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(l.Level))
+	return level
+}
+
+// RateLimit — ограничение частоты запросов на пользователя (по JWT) или IP.
+// Лимит записывается как "N/окно", например "60/1m"; "off" снимает ограничение.
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED" default:"true"`
+	// Для маршрутов без отдельного правила
+	Default string `yaml:"default" env:"RATE_LIMIT_DEFAULT" default:"300/1m"`
+	// Правила через запятую поверх встроенных в сервис: "/api/notes=60/1m,/api/notes/search=off".
+	// Слева шаблон маршрута ServeMux.
+	Routes string `yaml:"routes" env:"RATE_LIMIT_ROUTES"`
+	// Брать адрес клиента из X-Real-IP, который выставляет nginx. Включать только
+	// за прокси: без него клиент подставит в заголовок любой адрес.
+	TrustProxy bool `yaml:"trust_proxy" env:"RATE_LIMIT_TRUST_PROXY"`
+}
+
+// Rate — не больше Requests запросов за Window; нулевое значение — без ограничения
+type Rate struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (r Rate) Unlimited() bool {
+	return r.Requests == 0
+}
+
+func (r RateLimit) Validate(errs *Errors) {
+	if _, err := ParseRate(r.Default); err != nil {
+		errs.Addf("RATE_LIMIT_DEFAULT: %v", err)
+	}
+	if _, err := ParseRateRoutes(r.Routes); err != nil {
+		errs.Addf("RATE_LIMIT_ROUTES: %v", err)
+	}
+}
+
+// ParseRate разбирает "N/окно" или "off"
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return Rate{}, nil
+	}
+
+	n, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("rate %q must look like 60/1m or off", s)
+	}
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests <= 0 {
+		return Rate{}, fmt.Errorf("rate %q must have a positive request count", s)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d < time.Second {
+		return Rate{}, fmt.Errorf("rate %q must have a window of at least 1s", s)
+	}
+	return Rate{Requests: requests, Window: d}, nil
+}
+
+// ParseRateRoutes разбирает "шаблон=лимит,..." в карту по шаблону маршрута
+func ParseRateRoutes(s string) (map[string]Rate, error) {
+	routes := make(map[string]Rate)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pattern, spec, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(pattern) == "" {
+			return nil, fmt.Errorf("rule %q must look like /api/notes=60/1m", item)
+		}
+		rate, err := ParseRate(spec)
+		if err != nil {
+			return nil, err
+		}
+		routes[strings.TrimSpace(pattern)] = rate
+	}
+	return routes, nil
+}
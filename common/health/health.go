@@ -0,0 +1,150 @@
+// Package health — пробы liveness (/healthz) и readiness (/readyz).
+//
+// Liveness отвечает, жив ли процесс, и не трогает зависимости: иначе
+// падение Postgres привело бы к перезапуску всех подов по кругу.
+// Readiness проверяет зависимости и снимает под с балансировки, пока они недоступны.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CheckFunc возвращает ошибку, если зависимость недоступна
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	critical bool
+}
+
+type Checks struct {
+	timeout time.Duration
+	mu      sync.RWMutex
+	checks  []check
+}
+
+// New создает набор проверок; каждая проверка ограничена timeout.
+func New(timeout time.Duration) *Checks {
+	return &Checks{timeout: timeout}
+}
+
+// Add регистрирует обязательную проверку: ее провал делает сервис неготовым.
+func (c *Checks) Add(name string, fn CheckFunc) {
+	c.add(check{name: name, fn: fn, critical: true})
+}
+
+// AddOptional регистрирует проверку зависимости, без которой сервис работает
+// в деградированном режиме (например, кэш): провал виден в ответе, но /readyz остается 200.
+func (c *Checks) AddOptional(name string, fn CheckFunc) {
+	c.add(check{name: name, fn: fn, critical: false})
+}
+
+func (c *Checks) add(ch check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, ch)
+}
+
+// ShuttingDown — проверка, которая проваливается после отмены ctx.
+// Под перестает быть готовым сразу после SIGTERM, еще до остановки сервера.
+func ShuttingDown(ctx context.Context) CheckFunc {
+	return func(context.Context) error {
+		if ctx.Err() != nil {
+			return errors.New("shutting down")
+		}
+		return nil
+	}
+}
+
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Critical  bool    `json:"critical"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// Run выполняет все проверки параллельно.
+func (c *Checks) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]check(nil), c.checks...)
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, ch := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := ch.fn(checkCtx)
+			results[i] = Result{
+				Status:    StatusOK,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+				Critical:  ch.critical,
+			}
+			if err != nil {
+				results[i].Status = StatusFail
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for i, ch := range checks {
+		res := results[i]
+		report.Checks[ch.name] = res
+		if res.Status == StatusOK {
+			continue
+		}
+		if ch.critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// Liveness — /healthz: процесс жив и обслуживает HTTP.
+func (c *Checks) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+// Readiness — /readyz: 503, если провалилась хотя бы одна обязательная проверка.
+func (c *Checks) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+
+	status := http.StatusOK
+	if report.Status == StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,48 @@
+// Package httpserver запускает HTTP-сервер с таймаутами и плавной остановкой.
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"common/config"
+)
+
+// Run обслуживает запросы на addr, пока не отменят ctx (обычно по SIGTERM),
+// затем перестает принимать соединения и ждет завершения текущих запросов
+// не дольше cfg.ShutdownTimeout. Возвращает nil при штатной остановке.
+func Run(ctx context.Context, addr string, handler http.Handler, cfg config.HTTP) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, draining connections", "timeout", cfg.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
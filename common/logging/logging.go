@@ -0,0 +1,60 @@
+// Package logging настраивает журнал на log/slog: JSON или текст, уровень из
+// конфигурации, поля запроса (request_id, route, user_id, trace_id) из контекста
+// и вырезание персональных данных и секретов.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"common/config"
+)
+
+// Setup создает логгер сервиса и делает его логгером по умолчанию для slog
+// и стандартного пакета log.
+func Setup(service string, cfg config.Logging) *slog.Logger {
+	logger := slog.New(NewHandler(os.Stdout, cfg)).With("service", service)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// NewHandler собирает обработчик: формат из cfg, поля запроса из контекста
+// и Redact для каждого атрибута.
+func NewHandler(w io.Writer, cfg config.Logging) slog.Handler {
+	opts := &slog.HandlerOptions{Level: cfg.SlogLevel(), ReplaceAttr: Redact}
+
+	var h slog.Handler
+	if cfg.Format == "text" {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return contextHandler{h}
+}
+
+// contextHandler дописывает к записи поля текущего запроса и трассировки
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if info := infoFrom(ctx); info != nil {
+		rec.AddAttrs(info.attrs()...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		rec.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
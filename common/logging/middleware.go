@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"common/apierror"
+	"common/route"
+)
+
+// Входящий X-Request-ID длиннее или с другими символами заменяется новым,
+// чтобы клиент не мог подложить в журнал произвольный текст.
+const maxRequestIDLength = 128
+
+type infoKey struct{}
+
+// requestInfo — изменяемые поля запроса. Маршрут ServeMux определяет уже после
+// middleware, а user id становится известен только в обработчике, поэтому
+// в контексте лежит указатель, а не готовый набор атрибутов.
+type requestInfo struct {
+	req *http.Request
+	id  string
+
+	mu    sync.Mutex
+	extra []slog.Attr
+}
+
+func (i *requestInfo) attrs() []slog.Attr {
+	attrs := []slog.Attr{slog.String("request_id", i.id)}
+	if pattern := route.Pattern(i.req); pattern != "" {
+		attrs = append(attrs, slog.String("route", pattern))
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append(attrs, i.extra...)
+}
+
+func infoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(infoKey{}).(*requestInfo)
+	return info
+}
+
+// AddAttrs добавляет поля (например, user_id) ко всем последующим записям
+// журнала этого запроса, включая итоговую запись о запросе. Повторный вызов
+// с тем же ключом заменяет значение.
+func AddAttrs(ctx context.Context, args ...any) {
+	info := infoFrom(ctx)
+	if info == nil {
+		return
+	}
+
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	for _, a := range slog.Group("", args...).Value.Group() {
+		i := slices.IndexFunc(info.extra, func(b slog.Attr) bool { return b.Key == a.Key })
+		if i >= 0 {
+			info.extra[i] = a
+		} else {
+			info.extra = append(info.extra, a)
+		}
+	}
+}
+
+// RequestID возвращает идентификатор текущего запроса или пустую строку
+func RequestID(ctx context.Context) string {
+	if info := infoFrom(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// Middleware принимает X-Request-ID от nginx или клиента (или создает новый),
+// возвращает его в ответе и пишет одну запись о каждом запросе. Должен стоять
+// снаружи auth.Middleware, чтобы записи запроса получали user_id; маршрут
+// приходит от route.Record перед ServeMux.
+// Строка запроса не пишется: в ней бывает поисковый текст.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(apierror.RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			r.Header.Set(apierror.RequestIDHeader, id)
+		}
+		w.Header().Set(apierror.RequestIDHeader, id)
+
+		info := &requestInfo{id: id}
+		r = route.Track(r)
+		r = r.WithContext(context.WithValue(r.Context(), infoKey{}, info))
+		info.req = r
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case rec.status >= 400:
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap нужен http.ResponseController (Flush, таймауты)
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys — атрибуты, значения которых не попадают в журнал ни на каком
+// уровне: содержимое заметок, адреса почты, пароли, токены и коды приглашений.
+// Ключ сравнивается без учета регистра, в том числе как суффикс после "_"
+// (new_password, user_email).
+var sensitiveKeys = []string{
+	"title", "content", "query",
+	"email",
+	"password", "secret", "token", "authorization", "cookie", "invite_code",
+}
+
+// Redact подставляется в slog.HandlerOptions.ReplaceAttr
+func Redact(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup || !isSensitive(a.Key) {
+		return a
+	}
+	return slog.String(a.Key, redacted)
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if key == s || strings.HasSuffix(key, "_"+s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+// Package metrics — общие метрики Prometheus: HTTP-запросы и пул pgx.
+// Метрики конкретного сервиса объявляются в его пакете internal/metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"common/route"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+// Handler отдает /metrics в формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware считает запросы и их длительность. Маршрут берется из шаблона
+// ServeMux (route.Pattern), а не из URL, чтобы id в пути не раздували число рядов.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		r = route.Track(r)
+		next.ServeHTTP(rec, r)
+
+		pattern := route.Pattern(r)
+		if pattern == "" {
+			pattern = "unmatched"
+		}
+		httpRequests.WithLabelValues(pattern, r.Method, strconv.Itoa(rec.status)).Inc()
+		httpDuration.WithLabelValues(pattern, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap нужен http.ResponseController (Flush для потоковых ответов и т.п.)
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
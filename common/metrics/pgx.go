@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector снимает статистику пула pgx в момент скрейпа.
+type PoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired     *prometheus.Desc
+	idle         *prometheus.Desc
+	total        *prometheus.Desc
+	max          *prometheus.Desc
+	acquireCount *prometheus.Desc
+	acquireWait  *prometheus.Desc
+	emptyAcquire *prometheus.Desc
+	canceled     *prometheus.Desc
+}
+
+// RegisterPool регистрирует метрики пула pool в стандартном реестре.
+func RegisterPool(pool *pgxpool.Pool) error {
+	return prometheus.Register(NewPoolCollector(pool))
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("pgxpool_"+name, help, nil, nil)
+	}
+	return &PoolCollector{
+		pool:         pool,
+		acquired:     desc("acquired_connections", "Connections currently checked out of the pool."),
+		idle:         desc("idle_connections", "Idle connections in the pool."),
+		total:        desc("total_connections", "Total connections in the pool."),
+		max:          desc("max_connections", "Maximum size of the pool."),
+		acquireCount: desc("acquire_total", "Successful connection acquisitions."),
+		acquireWait:  desc("acquire_wait_seconds_total", "Total time spent waiting for a connection."),
+		emptyAcquire: desc("empty_acquire_total", "Acquisitions that had to wait because the pool was empty."),
+		canceled:     desc("canceled_acquire_total", "Acquisitions canceled by context."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
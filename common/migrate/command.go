@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const usage = "usage: migrate status | up | down | to <version>"
+
+// RunCommand выполняет подкоманду `migrate` из аргументов командной строки
+// (без самого слова migrate) и печатает результат в out.
+func RunCommand(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "status":
+	case "up":
+		if err := m.Up(ctx); err != nil {
+			return err
+		}
+	case "down":
+		if err := m.Down(ctx); err != nil {
+			return err
+		}
+	case "to":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil || target < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := m.To(ctx, target); err != nil {
+			return err
+		}
+	default:
+		return errors.New(usage)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(out, "%04d  %-30s %s\n", s.Version, s.Name, applied)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// Package migrate — встраиваемые версионированные миграции схемы.
+//
+// Миграции лежат в embed.FS парами файлов NNNN_name.up.sql и NNNN_name.down.sql.
+// Версии применяются по возрастанию и откатываются по убыванию; каждая
+// версия выполняется в своей транзакции вместе с записью в таблицу версий.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Driver отвечает за конкретную СУБД: блокировку, таблицу версий и выполнение SQL.
+type Driver interface {
+	// Lock не дает нескольким репликам мигрировать одновременно
+	Lock(ctx context.Context) error
+	Unlock(ctx context.Context) error
+	EnsureVersionTable(ctx context.Context) error
+	AppliedVersions(ctx context.Context) (map[int]time.Time, error)
+	// Apply выполняет script и в той же транзакции записывает (up) или удаляет (down) версию
+	Apply(ctx context.Context, version int, script string, up bool) error
+}
+
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	driver     Driver
+	migrations []Migration
+}
+
+func New(driver Driver, migrations []Migration) *Migrator {
+	return &Migrator{driver: driver, migrations: migrations}
+}
+
+// Load читает миграции из каталога dir. Файл .down.sql необязателен,
+// но без него версию нельзя откатить.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		var direction string
+		base := strings.TrimSuffix(name, ".sql")
+		switch {
+		case strings.HasSuffix(base, ".up"):
+			direction, base = "up", strings.TrimSuffix(base, ".up")
+		case strings.HasSuffix(base, ".down"):
+			direction, base = "down", strings.TrimSuffix(base, ".down")
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", name)
+		}
+
+		prefix, title, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name prefix", name)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, title)
+		}
+
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no .up.sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up применяет все еще не примененные миграции.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down откатывает последнюю примененную миграцию.
+func (m *Migrator) Down(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+		if i == 0 {
+			return m.To(ctx, 0)
+		}
+		return m.To(ctx, statuses[i-1].Version)
+	}
+	return nil
+}
+
+// To приводит схему к версии target: применяет недостающие миграции до нее
+// включительно и откатывает примененные после нее. target 0 откатывает все.
+func (m *Migrator) To(ctx context.Context, target int) error {
+	if target != 0 && !m.known(target) {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	if err := m.driver.Lock(ctx); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	defer m.driver.Unlock(context.WithoutCancel(ctx))
+
+	if err := m.driver.EnsureVersionTable(ctx); err != nil {
+		return fmt.Errorf("error creating version table: %w", err)
+	}
+
+	// Версии перечитываются под блокировкой: другая реплика могла успеть все применить
+	applied, err := m.driver.AppliedVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("error reading applied versions: %w", err)
+	}
+
+	for _, mg := range m.migrations {
+		if mg.Version > target {
+			break
+		}
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+		if err := m.driver.Apply(ctx, mg.Version, mg.Up, true); err != nil {
+			return fmt.Errorf("error applying migration %d_%s: %w", mg.Version, mg.Name, err)
+		}
+		slog.InfoContext(ctx, "Applied migration", "version", mg.Version, "name", mg.Name)
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mg := m.migrations[i]
+		if mg.Version <= target {
+			break
+		}
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+		if mg.Down == "" {
+			return fmt.Errorf("migration %d_%s cannot be rolled back: no .down.sql", mg.Version, mg.Name)
+		}
+		if err := m.driver.Apply(ctx, mg.Version, mg.Down, false); err != nil {
+			return fmt.Errorf("error rolling back migration %d_%s: %w", mg.Version, mg.Name, err)
+		}
+		slog.InfoContext(ctx, "Rolled back migration", "version", mg.Version, "name", mg.Name)
+	}
+	return nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.driver.EnsureVersionTable(ctx); err != nil {
+		return nil, fmt.Errorf("error creating version table: %w", err)
+	}
+	applied, err := m.driver.AppliedVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading applied versions: %w", err)
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, mg := range m.migrations {
+		statuses[i] = Status{Version: mg.Version, Name: mg.Name}
+		if at, ok := applied[mg.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) known(version int) bool {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return true
+		}
+	}
+	return false
+}
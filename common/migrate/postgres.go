@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresDriver держит одно соединение из пула на время миграции:
+// сессионная advisory-блокировка pg_advisory_lock привязана к соединению.
+type PostgresDriver struct {
+	pool   *pgxpool.Pool
+	table  string
+	lockID int64
+	conn   *pgxpool.Conn
+}
+
+// Postgres создает драйвер с таблицей версий table. Ключ блокировки
+// выводится из имени таблицы, поэтому сервисы с разными таблицами не мешают друг другу.
+func Postgres(pool *pgxpool.Pool, table string) *PostgresDriver {
+	h := fnv.New64a()
+	h.Write([]byte(table))
+
+	return &PostgresDriver{
+		pool:   pool,
+		table:  pgx.Identifier{table}.Sanitize(),
+		lockID: int64(h.Sum64()),
+	}
+}
+
+func (d *PostgresDriver) Lock(ctx context.Context) error {
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", d.lockID); err != nil {
+		conn.Release()
+		return err
+	}
+	d.conn = conn
+	return nil
+}
+
+func (d *PostgresDriver) Unlock(ctx context.Context) error {
+	if d.conn == nil {
+		return nil
+	}
+	_, err := d.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", d.lockID)
+	d.conn.Release()
+	d.conn = nil
+	return err
+}
+
+func (d *PostgresDriver) EnsureVersionTable(ctx context.Context) error {
+	_, err := d.pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+d.table+` (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+func (d *PostgresDriver) AppliedVersions(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := d.pool.Query(ctx, "SELECT version, applied_at FROM "+d.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+func (d *PostgresDriver) Apply(ctx context.Context, version int, script string, up bool) error {
+	if d.conn == nil {
+		return errors.New("migration lock is not held")
+	}
+
+	tx, err := d.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	if up {
+		_, err = tx.Exec(ctx, "INSERT INTO "+d.table+" (version) VALUES ($1)", version)
+	} else {
+		_, err = tx.Exec(ctx, "DELETE FROM "+d.table+" WHERE version = $1", version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
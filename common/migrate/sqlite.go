@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SQLiteDriver работает через database/sql. Блокировка не нужна:
+// SQLite-файл обслуживает один процесс, а запись сериализует сама СУБД.
+type SQLiteDriver struct {
+	db    *sql.DB
+	table string
+}
+
+func SQLite(db *sql.DB, table string) *SQLiteDriver {
+	return &SQLiteDriver{db: db, table: `"` + table + `"`}
+}
+
+func (d *SQLiteDriver) Lock(ctx context.Context) error   { return nil }
+func (d *SQLiteDriver) Unlock(ctx context.Context) error { return nil }
+
+func (d *SQLiteDriver) EnsureVersionTable(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+d.table+` (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+func (d *SQLiteDriver) AppliedVersions(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT version, applied_at FROM "+d.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+func (d *SQLiteDriver) Apply(ctx context.Context, version int, script string, up bool) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO "+d.table+" (version, applied_at) VALUES (?, ?)", version, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+d.table+" WHERE version = ?", version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
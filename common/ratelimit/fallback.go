@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"common/config"
+)
+
+// После ошибки основного хранилища запросы идут в запасное в течение retryAfter,
+// чтобы недоступный Redis не добавлял таймаут к каждому запросу.
+const retryAfter = 5 * time.Second
+
+type fallback struct {
+	primary, secondary Store
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+// WithFallback возвращает хранилище, которое при ошибке primary (обычно Redis)
+// переключается на secondary (обычно Memory), а не пропускает и не отклоняет запросы.
+func WithFallback(primary, secondary Store) Store {
+	return &fallback{primary: primary, secondary: secondary}
+}
+
+func (f *fallback) Take(ctx context.Context, key string, rate config.Rate, now time.Time) (Result, error) {
+	f.mu.Lock()
+	down := now.Before(f.downUntil)
+	f.mu.Unlock()
+	if down {
+		return f.secondary.Take(ctx, key, rate, now)
+	}
+
+	res, err := f.primary.Take(ctx, key, rate, now)
+	if err == nil {
+		return res, nil
+	}
+
+	f.mu.Lock()
+	f.downUntil = now.Add(retryAfter)
+	f.mu.Unlock()
+	slog.WarnContext(ctx, "Rate limit store unavailable, using in-memory counters", "error", err)
+	return f.secondary.Take(ctx, key, rate, now)
+}
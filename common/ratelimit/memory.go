@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"common/config"
+)
+
+// Устаревшие счетчики вычищаются не чаще, чем раз в sweepInterval
+const sweepInterval = time.Minute
+
+type counter struct {
+	index      int64
+	prev, curr int64
+	window     time.Duration
+}
+
+// Memory хранит счетчики в памяти процесса: лимит действует на каждую реплику
+// отдельно. Используется в однонодовом режиме и как запасной вариант для Redis.
+type Memory struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{counters: make(map[string]*counter)}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, rate config.Rate, now time.Time) (Result, error) {
+	index, elapsed := window(rate, now)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	c, ok := m.counters[key]
+	if !ok {
+		c = &counter{index: index, window: rate.Window}
+		m.counters[key] = c
+	}
+	switch {
+	case c.index == index:
+	case c.index == index-1:
+		c.prev, c.curr = c.curr, 0
+	default:
+		c.prev, c.curr = 0, 0
+	}
+	c.index = index
+
+	allowed, used := slide(rate, c.prev, c.curr, elapsed)
+	if allowed {
+		c.curr++
+	}
+	return result(rate, allowed, used, elapsed), nil
+}
+
+// sweep удаляет счетчики, не обновлявшиеся дольше двух окон
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	ms := now.UnixMilli()
+	for key, c := range m.counters {
+		if ms/c.window.Milliseconds()-c.index > 1 {
+			delete(m.counters, key)
+		}
+	}
+}
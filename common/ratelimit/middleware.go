@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"common/apierror"
+	"common/config"
+)
+
+// KeyFunc определяет, чей это запрос, например "user:42". Пустая строка —
+// клиент не аутентифицирован, и лимит считается по IP.
+type KeyFunc func(r *http.Request) string
+
+// Limiter применяет лимиты к маршрутам ServeMux
+type Limiter struct {
+	store      Store
+	key        KeyFunc
+	trustProxy bool
+	fallback   config.Rate
+	routes     map[string]config.Rate
+}
+
+// New собирает Limiter. routes — встроенные лимиты сервиса по шаблонам маршрутов;
+// правила из RATE_LIMIT_ROUTES их дополняют и переопределяют. При выключенном
+// RATE_LIMIT_ENABLED возвращается nil, и Middleware ничего не ограничивает.
+func New(store Store, cfg config.RateLimit, routes map[string]config.Rate, key KeyFunc) (*Limiter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	fallback, err := config.ParseRate(cfg.Default)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := config.ParseRateRoutes(cfg.Routes)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]config.Rate, len(routes)+len(overrides))
+	for pattern, rate := range routes {
+		merged[pattern] = rate
+	}
+	for pattern, rate := range overrides {
+		merged[pattern] = rate
+	}
+	return &Limiter{store: store, key: key, trustProxy: cfg.TrustProxy, fallback: fallback, routes: merged}, nil
+}
+
+func (l *Limiter) rate(pattern string) config.Rate {
+	if rate, ok := l.routes[pattern]; ok {
+		return rate
+	}
+	return l.fallback
+}
+
+// Middleware проверяет лимит маршрута до вызова обработчика. Маршрут
+// определяется через mux.Handler, поэтому middleware оборачивает сам mux,
+// а лимиты задаются его шаблонами. Ответы получают заголовки RateLimit-*,
+// превышение — 429 с Retry-After.
+func (l *Limiter) Middleware(mux *http.ServeMux) http.Handler {
+	if l == nil {
+		return mux
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		rate := l.rate(pattern)
+		if pattern == "" || rate.Unlimited() {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		client := l.key(r)
+		if client == "" {
+			client = "ip:" + ClientIP(r, l.trustProxy)
+		}
+
+		res, err := l.store.Take(r.Context(), pattern+"|"+client, rate, time.Now())
+		if err != nil {
+			// Без счетчиков не наказываем клиентов: пропускаем запрос
+			slog.ErrorContext(r.Context(), "Error checking rate limit", "error", err)
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		reset := int(math.Ceil(res.Reset.Seconds()))
+		h := w.Header()
+		h.Set("RateLimit-Policy", strconv.Itoa(rate.Requests)+";w="+strconv.Itoa(int(rate.Window.Seconds())))
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(reset))
+
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(reset))
+			apierror.Write(w, r, apierror.TooManyRequests("Too many requests, retry later"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// ClientIP возвращает адрес клиента. X-Real-IP учитывается только с trustProxy:
+// nginx перезаписывает его, а напрямую клиент мог бы подставить любой адрес.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,57 @@
+// Package ratelimit ограничивает частоту запросов скользящим окном: счетчики
+// текущего и предыдущего окна, причем предыдущее учитывается пропорционально
+// еще не истекшей части. Счетчики хранятся в Redis, чтобы лимит был общим для
+// всех реплик, а при недоступности Redis — в памяти процесса.
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"common/config"
+)
+
+// Store считает запросы по ключу. Take учитывает запрос, если он укладывается
+// в лимит, и в любом случае сообщает текущее состояние окна.
+type Store interface {
+	Take(ctx context.Context, key string, rate config.Rate, now time.Time) (Result, error)
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Сколько осталось до конца текущего окна
+	Reset time.Duration
+}
+
+// window возвращает номер окна и сколько от него прошло
+func window(rate config.Rate, now time.Time) (index int64, elapsed time.Duration) {
+	w := rate.Window.Milliseconds()
+	ms := now.UnixMilli()
+	return ms / w, time.Duration(ms%w) * time.Millisecond
+}
+
+// slide оценивает число запросов за последнее окно и решает, пропустить ли еще один.
+// Ту же формулу выполняет Lua-скрипт Redis-хранилища.
+func slide(rate config.Rate, prev, curr int64, elapsed time.Duration) (allowed bool, used int64) {
+	weight := float64(rate.Window-elapsed) / float64(rate.Window)
+	estimated := int64(float64(prev)*weight) + curr
+	if estimated >= int64(rate.Requests) {
+		return false, estimated
+	}
+	return true, estimated + 1
+}
+
+func result(rate config.Rate, allowed bool, used int64, elapsed time.Duration) Result {
+	remaining := int64(rate.Requests) - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{
+		Allowed:   allowed,
+		Limit:     rate.Requests,
+		Remaining: int(remaining),
+		Reset:     rate.Window - elapsed,
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"common/config"
+)
+
+// takeScript — атомарная версия slide. KEYS: счетчики текущего и предыдущего окна;
+// ARGV: лимит, длина окна и прошедшая часть окна в миллисекундах.
+// Возвращает {пропущен (0/1), оценка числа запросов с учетом этого}.
+var takeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local curr = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+local estimated = math.floor(prev * (window - elapsed) / window) + curr
+if estimated >= limit then
+  return {0, estimated}
+end
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return {1, estimated + 1}
+`)
+
+// Redis хранит счетчики в Redis, общем для всех реплик сервиса
+type Redis struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedis создает хранилище; prefix отделяет ключи сервиса, например "auth".
+func NewRedis(client redis.Scripter, prefix string) *Redis {
+	return &Redis{client: client, prefix: "ratelimit:" + prefix + ":"}
+}
+
+func (s *Redis) Take(ctx context.Context, key string, rate config.Rate, now time.Time) (Result, error) {
+	index, elapsed := window(rate, now)
+	// Фигурные скобки держат оба счетчика ключа в одном слоте Redis Cluster
+	base := s.prefix + "{" + key + "}:"
+
+	res, err := takeScript.Run(ctx, s.client,
+		[]string{base + strconv.FormatInt(index, 10), base + strconv.FormatInt(index-1, 10)},
+		rate.Requests, rate.Window.Milliseconds(), elapsed.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("error running rate limit script: %w", err)
+	}
+	if len(res) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", res)
+	}
+	return result(rate, res[0] == 1, res[1], elapsed), nil
+}
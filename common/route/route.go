@@ -0,0 +1,48 @@
+// Package route передает шаблон маршрута ServeMux внешним middleware.
+// ServeMux записывает шаблон в r.Pattern того запроса, который получил сам,
+// а middleware, меняющие контекст (r.WithContext), передают дальше копию,
+// и снаружи r.Pattern остается пустым. Поэтому в контексте лежит ссылка на
+// запрос, дошедший до ServeMux.
+package route
+
+import (
+	"context"
+	"net/http"
+)
+
+type key struct{}
+
+type holder struct {
+	req *http.Request
+}
+
+// Track кладет в контекст место под маршрут, если его там еще нет. Вызывается
+// middleware, которому нужен маршрут, до передачи запроса дальше.
+func Track(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(key{}).(*holder); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), key{}, &holder{}))
+}
+
+// Record запоминает запрос, который получит ServeMux, поэтому между ними не
+// должно быть middleware, меняющих запрос. При вложенных ServeMux побеждает
+// внутренний: его шаблон точнее.
+func Record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := r.Context().Value(key{}).(*holder); ok {
+			h.req = r
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Pattern возвращает шаблон маршрута или пустую строку, если ServeMux его еще
+// не выбрал или ни один маршрут не подошел. Запомненный запрос важнее
+// r.Pattern: r мог пройти через внешний ServeMux с менее точным шаблоном.
+func Pattern(r *http.Request) string {
+	if h, ok := r.Context().Value(key{}).(*holder); ok && h.req != nil {
+		return h.req.Pattern
+	}
+	return r.Pattern
+}
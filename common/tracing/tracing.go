@@ -0,0 +1,84 @@
+// Package tracing настраивает OpenTelemetry: W3C traceparent, спаны HTTP-обработчиков
+// и экспорт по OTLP или в stdout.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"common/config"
+	"common/route"
+)
+
+// Setup регистрирует глобальные TracerProvider и пропагатор. Возвращаемая функция
+// дописывает накопленные спаны и должна вызываться при остановке сервиса.
+// С экспортером none спаны не пишутся, но traceparent по-прежнему пробрасывается.
+func Setup(ctx context.Context, serviceName string, cfg config.Tracing) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Middleware открывает серверный спан на каждый запрос, продолжая трассировку
+// из входящего traceparent. Имя спана — метод и шаблон маршрута ServeMux.
+func Middleware(serviceName string, next http.Handler) http.Handler {
+	return otelhttp.NewHandler(withRoute(next), serviceName)
+}
+
+// withRoute называет спан по маршруту и добавляет атрибут http.route, когда
+// обработчик завершился. otelhttp сам переименовывает спан только по r.Pattern
+// своего запроса, а он пуст, если запрос дальше копировали.
+func withRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = route.Track(r)
+		next.ServeHTTP(w, r)
+
+		if pattern := route.Pattern(r); pattern != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + pattern)
+			span.SetAttributes(attribute.String("http.route", pattern))
+		}
+	})
+}
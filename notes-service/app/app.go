@@ -0,0 +1,74 @@
+// Package app позволяет встроить notes-service в другой процесс
+// (см. модуль single-node), не открывая внутренние пакеты.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"common/audit"
+	"common/auth"
+	"common/health"
+	"common/ratelimit"
+	"notes-service/internal/collab"
+	"notes-service/internal/config"
+	"notes-service/internal/events"
+	"notes-service/internal/handlers"
+	"notes-service/internal/metrics"
+	"notes-service/internal/notesync"
+	"notes-service/internal/outbox"
+	"notes-service/internal/storage"
+	"notes-service/internal/webhooks"
+)
+
+// Config — настройки notes-service; встраивающий процесс загружает их сам
+// и проверяет через Validate.
+type Config = config.Config
+
+// NewSQLiteHandler собирает обработчики notes-service поверх уже открытой
+// SQLite-базы, применяя миграции заметок и журнала аудита. Счетчики лимитов
+// хранятся в памяти. Доставка вебхуков, публикация событий outbox и удаление
+// старых следов удаленных заметок работают в фоне, пока не отменен ctx; тогда же закрываются потоки событий клиентов
+// и сеансы совместного редактирования. wait дожидается, пока сеансы сохранят
+// текст в заметки: базу можно закрывать только после него.
+func NewSQLiteHandler(ctx context.Context, db *sql.DB, cfg *Config, checks *health.Checks) (handler http.Handler, wait func(), err error) {
+	notes, err := storage.NewSQLiteRepository(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := metrics.RegisterNotes(notes.CountNotesByUser); err != nil {
+		return nil, nil, err
+	}
+	auditStore, err := audit.NewSQLiteStore(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	limiter, err := handlers.NewLimiter(ratelimit.NewMemory(), cfg.RateLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	recorder := audit.NewRecorder(auditStore, cfg.RateLimit.TrustProxy)
+	hooks := storage.NewSQLiteWebhookStore(db)
+	go webhooks.NewDispatcher(hooks, cfg.Webhooks).Run(ctx)
+	// Вебхуки и потоки событий получают события прямо от relay этого процесса
+	broker := events.NewBroker(notes)
+	context.AfterFunc(ctx, broker.Close)
+	go outbox.NewRelay(notes, outbox.Publishers{webhooks.NewPublisher(hooks), broker}, cfg.Outbox).Run(ctx)
+	go notesync.NewPruner(notes, cfg.Sync).Run(ctx)
+
+	quota := storage.NewAuditRepository(storage.NewMergeRepository(storage.NewQuotaRepository(notes, cfg.Limits)), recorder)
+	hub := collab.NewHub(collab.NewMemoryStore(cfg.Collab.HistoryLimit), quota, cfg.Collab, cfg.Limits)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hub.Run(ctx)
+	}()
+
+	server := handlers.NewServer(quota, hooks, broker, hub, checks, auth.New(cfg.JWTSecret), limiter, recorder, cfg)
+	return server.Routes(), func() { <-done }, nil
+}
+
+func OpenSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	return storage.OpenSQLite(ctx, path)
+}
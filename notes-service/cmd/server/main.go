@@ -1,22 +1,284 @@
-package main
-
-import (
-	"log"
-	"net/http"
-
-	"notes-service/internal/cache"
-	"notes-service/internal/handlers"
-)
-
-func main() {
-    cache.InitRedis()
-    http.HandleFunc("/api/notes", handlers.CreateNoteHandler)          
-    http.HandleFunc("/api/notes/list", handlers.GetNotesHandler)       
-    http.HandleFunc("/api/notes/", handlers.NoteDetailHandler)         
-    http.HandleFunc("/health", handlers.HealthHandler)
-    http.HandleFunc("/api/notes/update", handlers.UpdateNoteHandler)
-    http.HandleFunc("/api/notes/delete", handlers.DeleteNoteHandler)
-    
-    log.Println("Notes service starting on port 8081...")
-    log.Fatal(http.ListenAndServe(":8081", nil))
-}
\ No newline at end of file
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"notes-service/internal/cache"
+	"notes-service/internal/collab"
+	"notes-service/internal/config"
+	"notes-service/internal/events"
+	"notes-service/internal/handlers"
+	"notes-service/internal/metrics"
+	"notes-service/internal/notesync"
+	"notes-service/internal/outbox"
+	"notes-service/internal/storage"
+	"notes-service/internal/webhooks"
+	"common/audit"
+	"common/auth"
+	commonconfig "common/config"
+	"common/health"
+	"common/httpserver"
+	"common/logging"
+	"common/tracing"
+	commonmetrics "common/metrics"
+	"common/migrate"
+	"common/ratelimit"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Каждая проверка /readyz должна уложиться в timeoutSeconds пробы k8s
+const healthCheckTimeout = 2 * time.Second
+
+func main() {
+    configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to YAML config file")
+    printConfig := flag.Bool("print-config", false, "print effective configuration with secrets redacted and exit")
+    flag.Parse()
+
+    cfg, err := config.Load(*configPath)
+    if err != nil {
+        log.Fatal(err)
+    }
+    if *printConfig {
+        if err := commonconfig.Dump(os.Stdout, cfg); err != nil {
+            log.Fatal(err)
+        }
+        return
+    }
+
+    logging.Setup("notes-service", cfg.Logging)
+
+    // notes-service migrate [audit] status|up|down|to N
+    if flag.Arg(0) == "migrate" {
+        runMigrations(context.Background(), cfg, flag.Args()[1:])
+        return
+    }
+
+    // SIGTERM приходит от k8s при rolling update: дожидаемся текущих запросов,
+    // затем закрываем пул Postgres и клиент Redis
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+
+    shutdownTracing, err := tracing.Setup(ctx, "notes-service", cfg.Tracing)
+    if err != nil {
+        fatal("Error setting up tracing", err)
+    }
+    // Выполняется последним: спаны дописываются после остановки сервера и закрытия базы
+    defer shutdownTracing(context.Background())
+
+    checks := health.New(healthCheckTimeout)
+    checks.Add("shutdown", health.ShuttingDown(ctx))
+    authn := auth.New(cfg.JWTSecret)
+    checks.Add("jwt_secret", authn.Check)
+
+    stores := openStores(ctx, cfg, checks)
+    defer stores.close()
+    recorder := audit.NewRecorder(stores.audit, cfg.RateLimit.TrustProxy)
+    // Правки с устаревшей base_version сливаются с серверными до проверки квоты;
+    // журнал аудита видит каждое изменение, откуда бы оно ни пришло
+    notes := storage.NewAuditRepository(storage.NewMergeRepository(storage.NewQuotaRepository(stores.notes, cfg.Limits)), recorder)
+    if err := metrics.RegisterNotes(notes.CountNotesByUser); err != nil {
+        slog.Warn("Error registering note metrics", "error", err)
+    }
+
+    // Счетчики лимитов общие для реплик через Redis; без него каждая реплика считает сама
+    limitStore := ratelimit.Store(ratelimit.NewMemory())
+    if cfg.Storage == "postgres" {
+        limitStore = ratelimit.WithFallback(ratelimit.NewRedis(cache.Client(), "notes"), limitStore)
+    }
+    limiter, err := handlers.NewLimiter(limitStore, cfg.RateLimit)
+    if err != nil {
+        fatal("Error configuring rate limits", err)
+    }
+
+    // Открытые потоки событий закрываются при остановке, иначе они задержали бы ее
+    broker := events.NewBroker(stores.outbox)
+    context.AfterFunc(ctx, broker.Close)
+    // Документы совместного редактирования общие для реплик через Redis. При остановке
+    // клиенты отключаются, а несохраненный текст дописывается в заметки до закрытия базы.
+    collabStore := collab.Store(collab.NewMemoryStore(cfg.Collab.HistoryLimit))
+    if cfg.Storage == "postgres" {
+        collabStore = collab.NewRedisStore(cache.Client(), cfg.Collab.RedisPrefix, cfg.Collab.HistoryLimit, cfg.Collab.IdleTTL)
+    }
+    hub := collab.NewHub(collabStore, notes, cfg.Collab, cfg.Limits)
+    collabDone := make(chan struct{})
+    go func() {
+        defer close(collabDone)
+        hub.Run(ctx)
+    }()
+    defer func() { <-collabDone }()
+    server := handlers.NewServer(notes, stores.webhooks, broker, hub, checks, authn, limiter, recorder, cfg)
+
+    // Доставка вебхуков останавливается вместе с сервером; недоставленное
+    // остается в очереди в базе
+    go webhooks.NewDispatcher(stores.webhooks, cfg.Webhooks).Run(ctx)
+
+    // События об изменении заметок публикуются, пока работает сервис; неопубликованные
+    // дождутся следующего запуска в outbox. Из них же ставятся доставки вебхуков.
+    // С Redis события доходят до клиентов всех реплик через pub/sub, без него —
+    // только до клиентов этого процесса.
+    publisher := outbox.Publisher(broker)
+    if cfg.Storage == "postgres" {
+        publisher = outbox.NewRedisPublisher(cache.Client(), cfg.Outbox.Stream, cfg.Outbox.StreamMaxLen, cfg.Outbox.Channel)
+        go broker.Listen(ctx, cache.Client(), cfg.Outbox.Channel)
+    }
+    go outbox.NewRelay(stores.outbox, outbox.Publishers{webhooks.NewPublisher(stores.webhooks), publisher}, cfg.Outbox).Run(ctx)
+    // Следы удаленных заметок нужны клиентам синхронизации, пока не истек SYNC_TOMBSTONE_RETENTION
+    go notesync.NewPruner(stores.notes, cfg.Sync).Run(ctx)
+
+    slog.Info("Notes service starting", "port", cfg.Port)
+    handler := tracing.Middleware("notes-service", commonmetrics.Middleware(logging.Middleware(server.Routes())))
+    if err := httpserver.Run(ctx, fmt.Sprintf(":%d", cfg.Port), handler, cfg.HTTP); err != nil {
+        slog.Error("Notes service error", "error", err)
+        return
+    }
+    slog.Info("Notes service stopped")
+}
+
+// fatal пишет ошибку в журнал и завершает процесс
+func fatal(msg string, err error) {
+    slog.Error(msg, "error", err)
+    os.Exit(1)
+}
+
+// stores — хранилища notes-service поверх одной базы
+type stores struct {
+    notes    storage.NoteRepository
+    webhooks storage.WebhookStore
+    audit    audit.Store
+    // outbox — события об изменении заметок
+    outbox storage.OutboxStore
+    // close закрывает соединения с базой и кэшем
+    close func()
+}
+
+// openStores выбирает хранилище по NOTES_STORAGE: postgres (по умолчанию), sqlite или memory.
+// Redis-кэш и поток событий нужны только вместе с Postgres; вебхуки, outbox и журнал аудита
+// живут в той же базе, что и заметки. Проверки базы и кэша добавляются в checks.
+func openStores(ctx context.Context, cfg *config.Config, checks *health.Checks) stores {
+    switch cfg.Storage {
+    case "postgres":
+        cache.InitRedis(cfg.Redis)
+
+        pool, err := storage.NewPostgresPool(ctx, cfg.Database.DSN())
+        if err != nil {
+            fatal("Error connecting to database", err)
+        }
+
+        // Реплики мигрируют под advisory-блокировкой; MIGRATE_ON_START=false
+        // оставляет миграции отдельному запуску `migrate up`
+        if cfg.MigrateOnStart {
+            for _, load := range []func(*pgxpool.Pool) (*migrate.Migrator, error){storage.PostgresMigrator, audit.PostgresMigrator} {
+                migrator, err := load(pool)
+                if err != nil {
+                    fatal("Error loading migrations", err)
+                }
+                if err := migrator.Up(ctx); err != nil {
+                    fatal("Error migrating database", err)
+                }
+            }
+        }
+
+        checks.Add("postgres", pool.Ping)
+        if err := commonmetrics.RegisterPool(pool); err != nil {
+            slog.Warn("Error registering pool metrics", "error", err)
+        }
+        // Без Redis заметки читаются напрямую из Postgres, поэтому он не обязателен
+        checks.AddOptional("redis", cache.Ping)
+
+        closeAll := func() {
+            pool.Close()
+            cache.CloseRedis()
+        }
+        notes := storage.NewPostgresRepository(pool)
+        return stores{
+            notes:    storage.NewCachedRepository(notes, cfg.CacheTTL),
+            webhooks: storage.NewPostgresWebhookStore(pool),
+            audit:    audit.NewPostgresStore(pool),
+            outbox:   notes,
+            close:    closeAll,
+        }
+    case "sqlite":
+        db, err := storage.OpenSQLite(ctx, cfg.SQLitePath)
+        if err != nil {
+            fatal("Error opening sqlite database", err)
+        }
+        notes, err := storage.NewSQLiteRepository(ctx, db)
+        if err != nil {
+            fatal("Error preparing sqlite database", err)
+        }
+        auditStore, err := audit.NewSQLiteStore(ctx, db)
+        if err != nil {
+            fatal("Error preparing sqlite database", err)
+        }
+        checks.Add("sqlite", db.PingContext)
+        slog.Info("Using sqlite note storage", "path", cfg.SQLitePath)
+        return stores{
+            notes:    notes,
+            webhooks: storage.NewSQLiteWebhookStore(db),
+            audit:    auditStore,
+            outbox:   notes,
+            close:    func() { db.Close() },
+        }
+    default:
+        slog.Warn("Using in-memory note storage, data will be lost on restart")
+        notes := storage.NewMemoryRepository()
+        return stores{
+            notes:    notes,
+            webhooks: storage.NewMemoryWebhookStore(),
+            audit:    audit.NewMemoryStore(),
+            outbox:   notes,
+            close:    func() {},
+        }
+    }
+}
+
+// runMigrations выполняет подкоманду migrate для хранилища из NOTES_STORAGE;
+// `migrate audit ...` управляет схемой общего журнала аудита.
+func runMigrations(ctx context.Context, cfg *config.Config, args []string) {
+    postgresMigrator, sqliteMigrator := storage.PostgresMigrator, storage.SQLiteMigrator
+    if len(args) > 0 && args[0] == "audit" {
+        postgresMigrator, sqliteMigrator = audit.PostgresMigrator, audit.SQLiteMigrator
+        args = args[1:]
+    }
+
+    var migrator *migrate.Migrator
+    switch cfg.Storage {
+    case "postgres":
+        pool, err := storage.NewPostgresPool(ctx, cfg.Database.DSN())
+        if err != nil {
+            fatal("Error connecting to database", err)
+        }
+        defer pool.Close()
+
+        migrator, err = postgresMigrator(pool)
+        if err != nil {
+            fatal("Error loading migrations", err)
+        }
+    case "sqlite":
+        db, err := storage.OpenSQLite(ctx, cfg.SQLitePath)
+        if err != nil {
+            fatal("Error opening sqlite database", err)
+        }
+        defer db.Close()
+
+        migrator, err = sqliteMigrator(db)
+        if err != nil {
+            fatal("Error loading migrations", err)
+        }
+    default:
+        fatal("Error running migrations", fmt.Errorf("NOTES_STORAGE %q has no migrations", cfg.Storage))
+    }
+
+    if err := migrate.RunCommand(ctx, migrator, args, os.Stdout); err != nil {
+        fatal("Migration failed", err)
+    }
+}
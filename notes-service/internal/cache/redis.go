@@ -1,82 +1,121 @@
-package cache
-
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"log"
-	"time"
-
-	"notes-service/internal/models"
-	"github.com/redis/go-redis/v9"
-)
-
-var (
-	redisClient *redis.Client
-	ctx         = context.Background()
-)
-
-func InitRedis() {
-	redisClient = redis.NewClient(&redis.Options{
-		Addr:     "redis:6379",
-		Password: "",
-		DB:       0,
-	})
-
-	
-	_, err := redisClient.Ping(ctx).Result()
-	if err != nil {
-		log.Printf("❌ Failed to connect to Redis: %v", err)
-		return
-	}
-	log.Println("✅ Notes service connected to Redis")
-}
-
-
-func CacheUserNotes(userID int32, notes []models.Note, expiration time.Duration) error {
-	if redisClient == nil {
-		return nil 
-	}
-
-	key := fmt.Sprintf("user:%d:notes", userID)
-	jsonData, err := json.Marshal(notes)
-	if err != nil {
-		return err
-	}
-
-	err = redisClient.Set(ctx, key, jsonData, expiration).Err()
-	if err != nil {
-		log.Printf("Warning: failed to cache notes: %v", err)
-	}
-	return err
-}
-
-
-func GetCachedUserNotes(userID int32) ([]models.Note, error) {
-	if redisClient == nil {
-		return nil, fmt.Errorf("redis not available")
-	}
-
-	key := fmt.Sprintf("user:%d:notes", userID)
-	jsonData, err := redisClient.Get(ctx, key).Result()
-	if err != nil {
-		return nil, err
-	}
-
-	var notes []models.Note
-	err = json.Unmarshal([]byte(jsonData), &notes)
-	return notes, err
-}
-
-func InvalidateUserCache(userID int32) error {
-	if redisClient == nil {
-		return nil
-	}
-
-	key := fmt.Sprintf("user:%d:notes", userID)
-	err := redisClient.Del(ctx, key).Err()
-	if err != nil {
-		log.Printf("Warning: failed to invalidate cache: %v", err)
-	}
-	return err
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"common/config"
+	"notes-service/internal/metrics"
+	"notes-service/internal/models"
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+var redisClient *redis.Client
+
+func InitRedis(cfg config.Redis) {
+	redisClient = redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	// Команды Redis попадают в трассировку запроса как дочерние спаны
+	if err := redisotel.InstrumentTracing(redisClient); err != nil {
+		slog.Warn("Failed to instrument Redis tracing", "error", err)
+	}
+
+	_, err := redisClient.Ping(context.Background()).Result()
+	if err != nil {
+		slog.Warn("Failed to connect to Redis", "addr", cfg.Addr, "error", err)
+		return
+	}
+	slog.Info("Connected to Redis", "addr", cfg.Addr)
+}
+
+// Client возвращает клиент Redis для других подсистем (ограничение частоты запросов);
+// nil, если InitRedis не вызывался.
+func Client() *redis.Client {
+	return redisClient
+}
+
+// Ping проверяет соединение с Redis для /readyz
+func Ping(ctx context.Context) error {
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+	return redisClient.Ping(ctx).Err()
+}
+
+// CloseRedis закрывает клиент при остановке сервиса
+func CloseRedis() {
+	if redisClient == nil {
+		return
+	}
+	if err := redisClient.Close(); err != nil {
+		slog.Warn("Failed to close Redis client", "error", err)
+	}
+	redisClient = nil
+}
+
+// UserNotesKey — ключ кэша списка заметок пользователя
+func UserNotesKey(userID int32) string {
+	return fmt.Sprintf("user:%d:notes", userID)
+}
+
+func CacheUserNotes(ctx context.Context, userID int32, notes []models.Note, expiration time.Duration) error {
+	if redisClient == nil {
+		return nil 
+	}
+
+	key := UserNotesKey(userID)
+	jsonData, err := json.Marshal(notes)
+	if err != nil {
+		return err
+	}
+
+	err = redisClient.Set(ctx, key, jsonData, expiration).Err()
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to cache notes", "error", err)
+	}
+	return err
+}
+
+
+func GetCachedUserNotes(ctx context.Context, userID int32) ([]models.Note, error) {
+	if redisClient == nil {
+		return nil, fmt.Errorf("redis not available")
+	}
+
+	key := UserNotesKey(userID)
+	jsonData, err := redisClient.Get(ctx, key).Result()
+	if err == redis.Nil {
+		metrics.CacheMiss()
+		return nil, err
+	}
+	if err != nil {
+		metrics.CacheError()
+		return nil, err
+	}
+	metrics.CacheHit()
+
+	var notes []models.Note
+	err = json.Unmarshal([]byte(jsonData), &notes)
+	return notes, err
+}
+
+func InvalidateUserCache(ctx context.Context, userID int32) error {
+	if redisClient == nil {
+		return nil
+	}
+
+	key := UserNotesKey(userID)
+	err := redisClient.Del(ctx, key).Err()
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to invalidate cache", "error", err)
+	}
+	return err
 }
\ No newline at end of file
@@ -0,0 +1,224 @@
+package collab
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"common/auth"
+	"notes-service/internal/models"
+)
+
+const (
+	// Сколько сообщений может ждать отправки клиенту; медленный клиент отключается
+	sendBuffer = 256
+	writeWait  = 10 * time.Second
+	// Клиент без ответа на ping дольше pongWait считается отключенным
+	pongWait   = time.Minute
+	pingPeriod = pongWait * 9 / 10
+)
+
+// Коды закрытия WebSocket; 4000–4999 отведены приложениям
+const (
+	closeGoingAway   = websocket.CloseGoingAway
+	closeTooSlow     = websocket.CloseTryAgainLater
+	closeNoteDeleted = 4404
+)
+
+// Сообщения клиента
+type clientMessage struct {
+	// op — правка над ревизией Rev, cursor — выделение в ревизии Rev
+	Type   string `json:"type"`
+	Rev    int    `json:"rev"`
+	Op     Op     `json:"op"`
+	Anchor int    `json:"anchor"`
+	Head   int    `json:"head"`
+}
+
+// Сообщения сервера
+type initMessage struct {
+	Type     string `json:"type"`
+	ClientID string `json:"client_id"`
+	Rev      int    `json:"rev"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Peers    []Peer `json:"peers"`
+}
+
+type opMessage struct {
+	Type     string `json:"type"`
+	Rev      int    `json:"rev"`
+	Op       Op     `json:"op"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+type ackMessage struct {
+	Type string `json:"type"`
+	Rev  int    `json:"rev"`
+}
+
+type presenceMessage struct {
+	Type string `json:"type"`
+	Peer Peer   `json:"peer"`
+}
+
+type leaveMessage struct {
+	Type     string `json:"type"`
+	ClientID string `json:"client_id"`
+}
+
+type errorMessage struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func encode(msg any) []byte {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		// Все сообщения — простые структуры, ошибка здесь — ошибка в коде
+		panic(err)
+	}
+	return data
+}
+
+// client — одно WebSocket-соединение. Писать в соединение может только writeLoop,
+// остальные ставят сообщения в очередь через enqueue.
+type client struct {
+	conn *websocket.Conn
+	send chan []byte
+	// peer меняется под document.mu
+	peer Peer
+
+	once sync.Once
+	done chan struct{}
+}
+
+// enqueue ставит сообщение в очередь; клиента, не успевающего читать, отключает
+func (c *client) enqueue(msg []byte) {
+	select {
+	case <-c.done:
+	case c.send <- msg:
+	default:
+		c.close(closeTooSlow, "client is too slow")
+	}
+}
+
+// close отправляет кадр закрытия и закрывает соединение; readLoop после этого
+// получает ошибку и клиент покидает документ
+func (c *client) close(code int, reason string) {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+		c.conn.Close()
+	})
+}
+
+func (c *client) writeLoop() {
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
+
+// Serve ведет сеанс редактирования заметки по уже установленному соединению,
+// пока клиент не отключится. Доступ к заметке проверяет вызывающий.
+// maxMessage ограничивает размер одного сообщения клиента; 0 — без ограничения.
+func (h *Hub) Serve(ctx context.Context, conn *websocket.Conn, note *models.Note, principal *auth.Principal, maxMessage int64) {
+	c := &client{
+		conn: conn,
+		send: make(chan []byte, sendBuffer),
+		peer: Peer{ClientID: rand.Text(), UserID: principal.UserID, Username: principal.Username},
+		done: make(chan struct{}),
+	}
+	defer c.close(websocket.CloseNormalClosure, "")
+
+	d, err := h.join(ctx, note, c)
+	if err != nil {
+		if !errors.Is(err, errClosed) {
+			slog.ErrorContext(ctx, "Error opening collab document", "note_id", note.ID, "error", err)
+		}
+		c.close(websocket.CloseInternalServerErr, "document is unavailable")
+		return
+	}
+	defer h.leave(ctx, d, c)
+	go c.writeLoop()
+
+	if maxMessage > 0 {
+		conn.SetReadLimit(maxMessage)
+	}
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				slog.DebugContext(ctx, "Collab connection closed", "note_id", note.ID, "error", err)
+			}
+			return
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.enqueue(encode(errorMessage{Type: "error", Code: "invalid_message", Message: "Invalid message"}))
+			continue
+		}
+		switch msg.Type {
+		case "op":
+			if err := h.submit(ctx, d, c, msg.Rev, msg.Op); err != nil {
+				h.reject(ctx, d, c, err)
+			}
+		case "cursor":
+			h.moveCursor(ctx, d, c, msg.Rev, msg.Anchor, msg.Head)
+		default:
+			c.enqueue(encode(errorMessage{Type: "error", Code: "invalid_message", Message: "Unknown message type"}))
+		}
+	}
+}
+
+// reject сообщает клиенту, почему правка не принята, и отправляет документ
+// заново: неподтвержденная правка уже применена у клиента и должна быть отброшена
+func (h *Hub) reject(ctx context.Context, d *document, c *client, err error) {
+	msg := errorMessage{Type: "error", Code: "internal_error", Message: "Error applying operation"}
+	switch {
+	case errors.Is(err, ErrInvalidOp):
+		msg.Code, msg.Message = "invalid_operation", "Operation does not match the document"
+	case errors.Is(err, ErrHistoryTrimmed):
+		msg.Code, msg.Message = "stale_revision", "Revision is too old"
+	case errors.Is(err, errNoteTooLarge):
+		msg.Code, msg.Message = "note_too_large", "Note exceeds the maximum size"
+	case errors.Is(err, errBusy):
+		msg.Code, msg.Message = "busy", "Too many concurrent edits, try again"
+	default:
+		slog.ErrorContext(ctx, "Error applying collab operation", "note_id", d.id, "error", err)
+	}
+	c.enqueue(encode(msg))
+
+	d.mu.Lock()
+	c.enqueue(h.initMessage(d, c))
+	d.mu.Unlock()
+}
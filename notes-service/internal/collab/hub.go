@@ -0,0 +1,680 @@
+package collab
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"common/audit"
+	"notes-service/internal/config"
+	"notes-service/internal/merge"
+	"notes-service/internal/metrics"
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+const (
+	// Сколько раз правка догоняет другие реплики, прежде чем клиент получит ошибку
+	commitAttempts = 10
+	// Курсор участника с другой реплики пропадает, если о нем так долго ничего не слышно
+	presenceTTL = time.Minute
+	// Сколько может длиться сохранение текста в заметку
+	saveTimeout = 10 * time.Second
+)
+
+var (
+	errClosed       = errors.New("collab hub is closed")
+	errNoteTooLarge = errors.New("note exceeds maximum size")
+	errBusy         = errors.New("too many concurrent edits")
+)
+
+// Peer — участник сеанса и его выделение: Anchor и Head — позиции в тексте
+// в символах Unicode, при простом курсоре равны
+type Peer struct {
+	ClientID string `json:"client_id"`
+	UserID   int32  `json:"user_id"`
+	Username string `json:"username"`
+	Anchor   int    `json:"anchor"`
+	Head     int    `json:"head"`
+
+	// Участник другой реплики и когда о нем было последнее сообщение
+	remote bool
+	seen   time.Time
+}
+
+// document — заметка, которую редактируют клиенты этой реплики
+type document struct {
+	id    int32
+	owner int32
+	// refs — подключенные и подключающиеся клиенты, под Hub.mu
+	refs int
+
+	mu      sync.Mutex
+	loaded  bool
+	title   string
+	text    string
+	rev     int
+	history []Change
+	clients map[*client]struct{}
+	peers   map[string]*Peer
+	// savedRev — последняя ревизия, записанная в заметку
+	savedRev int
+	// editor — участник, чья правка принята последней: на него журнал аудита
+	// записывает сохранение
+	editor    int32
+	saveTimer *time.Timer
+	// saveMu не дает двум сохранениям одного документа идти одновременно
+	saveMu sync.Mutex
+}
+
+// Hub — сеансы совместного редактирования на этой реплике. Порядок правок
+// задает Store, поэтому одну заметку можно редактировать через разные реплики.
+type Hub struct {
+	store        Store
+	notes        storage.NoteRepository
+	cfg          config.Collab
+	maxNoteBytes int64
+
+	mu     sync.Mutex
+	docs   map[int32]*document
+	closed bool
+}
+
+func NewHub(store Store, notes storage.NoteRepository, cfg config.Collab, limits config.Limits) *Hub {
+	return &Hub{
+		store:        store,
+		notes:        notes,
+		cfg:          cfg,
+		maxNoteBytes: limits.MaxNoteBytes,
+		docs:         make(map[int32]*document),
+	}
+}
+
+// Run принимает сообщения других реплик и обновляет курсоры, пока не отменен ctx,
+// затем отключает клиентов и сохраняет несохраненный текст. Хранилище заметок
+// нужно закрывать только после возврата Run.
+func (h *Hub) Run(ctx context.Context) {
+	listening := make(chan struct{})
+	go func() {
+		defer close(listening)
+		h.store.Listen(ctx, h.receive)
+	}()
+
+	ticker := time.NewTicker(presenceTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			<-listening
+			h.close()
+			return
+		case <-ticker.C:
+			h.refreshPresence(ctx)
+		}
+	}
+}
+
+// join подключает клиента к документу заметки, загружая документ при первом
+// подключении, и отправляет ему текущее состояние
+func (h *Hub) join(ctx context.Context, note *models.Note, c *client) (*document, error) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, errClosed
+	}
+	d := h.docs[note.ID]
+	if d == nil {
+		d = &document{
+			id:      note.ID,
+			owner:   note.UserID,
+			clients: make(map[*client]struct{}),
+			peers:   make(map[string]*Peer),
+		}
+		h.docs[note.ID] = d
+	}
+	d.refs++
+	h.mu.Unlock()
+
+	d.mu.Lock()
+	if !d.loaded {
+		if err := h.load(ctx, d, note); err != nil {
+			d.mu.Unlock()
+			h.release(d)
+			return nil, err
+		}
+	}
+
+	d.clients[c] = struct{}{}
+	d.peers[c.peer.ClientID] = &c.peer
+	c.enqueue(h.initMessage(d, c))
+	h.broadcastPeer(ctx, d, c)
+	d.mu.Unlock()
+	metrics.CollabConnected()
+	return d, nil
+}
+
+// load читает документ из Store. Если заметку изменили мимо совместного
+// редактирования, документ догоняется до нее обычной правкой, которую
+// получат и клиенты других реплик.
+func (h *Hub) load(ctx context.Context, d *document, note *models.Note) error {
+	if err := h.store.Watch(ctx, d.id); err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		snap, err := h.store.Load(ctx, d.id, note.Content, note.Version)
+		if err != nil {
+			return err
+		}
+		d.title, d.text, d.rev, d.history = note.Title, snap.Text, snap.Rev, nil
+		if snap.Synced == note.Content || attempt == commitAttempts {
+			break
+		}
+
+		change := Change{Rev: snap.Rev + 1, Op: Diff(snap.Text, note.Content)}
+		ok, err := h.store.Commit(ctx, d.id, change, note.Content)
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.text, d.rev = note.Content, change.Rev
+			d.history = append(d.history, change)
+			if err := h.store.MarkSynced(ctx, d.id, note.Content, note.Version); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	d.savedRev = d.rev
+	d.loaded = true
+	return nil
+}
+
+// leave отключает клиента; после последнего клиента текст сохраняется в заметку
+func (h *Hub) leave(ctx context.Context, d *document, c *client) {
+	d.mu.Lock()
+	if _, ok := d.clients[c]; ok {
+		delete(d.clients, c)
+		delete(d.peers, c.peer.ClientID)
+		metrics.CollabDisconnected()
+		msg := encode(leaveMessage{Type: "leave", ClientID: c.peer.ClientID})
+		for other := range d.clients {
+			other.enqueue(msg)
+		}
+		if err := h.store.Publish(ctx, d.id, Envelope{Left: c.peer.ClientID}); err != nil {
+			slog.WarnContext(ctx, "Error publishing collab presence", "note_id", d.id, "error", err)
+		}
+	}
+	d.mu.Unlock()
+	h.release(d)
+}
+
+// release снимает ссылку на документ и убирает его, если ссылок не осталось
+func (h *Hub) release(d *document) {
+	h.mu.Lock()
+	d.refs--
+	last := d.refs == 0
+	if last {
+		delete(h.docs, d.id)
+	}
+	h.mu.Unlock()
+	if !last {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	defer cancel()
+	d.mu.Lock()
+	if d.saveTimer != nil {
+		d.saveTimer.Stop()
+		d.saveTimer = nil
+	}
+	loaded := d.loaded
+	d.mu.Unlock()
+	if loaded {
+		h.save(ctx, d)
+	}
+	if err := h.store.Unwatch(ctx, d.id); err != nil {
+		slog.WarnContext(ctx, "Error unsubscribing from collab messages", "note_id", d.id, "error", err)
+	}
+	if err := h.store.Release(ctx, d.id); err != nil {
+		slog.WarnContext(ctx, "Error releasing collab document", "note_id", d.id, "error", err)
+	}
+}
+
+// submit принимает правку клиента, сделанную над ревизией base: преобразует ее
+// относительно правок, принятых после base, и записывает следующей ревизией
+func (h *Hub) submit(ctx context.Context, d *document, c *client, base int, op Op) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if base < 0 || base > d.rev {
+		return ErrInvalidOp
+	}
+
+	rev, err := h.commit(ctx, d, c, base, op)
+	if err != nil {
+		return err
+	}
+	d.editor = c.peer.UserID
+	c.enqueue(encode(ackMessage{Type: "ack", Rev: rev}))
+	h.scheduleSave(d)
+	return nil
+}
+
+// commit преобразует op, сделанную над ревизией base, относительно правок,
+// принятых после base, записывает ее следующей ревизией и возвращает эту
+// ревизию; author == nil — правку вносит сам сервер, и ее получают все клиенты
+func (h *Hub) commit(ctx context.Context, d *document, author *client, base int, op Op) (int, error) {
+	clientID := ""
+	if author != nil {
+		clientID = author.peer.ClientID
+	}
+	for attempt := 0; attempt < commitAttempts; attempt++ {
+		concurrent, err := h.since(ctx, d, base)
+		if err != nil {
+			return 0, err
+		}
+		transformed := op
+		for _, change := range concurrent {
+			if transformed, _, err = Transform(transformed, change.Op); err != nil {
+				return 0, err
+			}
+		}
+		text, err := transformed.Apply(d.text)
+		if err != nil {
+			return 0, err
+		}
+		if h.maxNoteBytes > 0 && models.NoteSize(d.title, text) > h.maxNoteBytes {
+			return 0, errNoteTooLarge
+		}
+
+		change := Change{Rev: d.rev + 1, Op: transformed, ClientID: clientID}
+		ok, err := h.store.Commit(ctx, d.id, change, text)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			h.apply(d, change, text, author)
+			return change.Rev, nil
+		}
+		// Другая реплика успела раньше: догоняем и преобразуем правку еще раз
+		if err := h.catchUp(ctx, d); err != nil {
+			return 0, err
+		}
+	}
+	return 0, errBusy
+}
+
+// since возвращает изменения после ревизии base до текущей ревизии документа
+func (h *Hub) since(ctx context.Context, d *document, base int) ([]Change, error) {
+	if base == d.rev {
+		return nil, nil
+	}
+	if changes, err := changesAfter(d.history, base, d.rev); err == nil {
+		return changes, nil
+	}
+	changes, err := h.store.Changes(ctx, d.id, base)
+	if err != nil {
+		return nil, err
+	}
+	for i, change := range changes {
+		if change.Rev > d.rev {
+			return changes[:i], nil
+		}
+	}
+	return changes, nil
+}
+
+// apply применяет принятое изменение к документу и рассылает его клиентам,
+// кроме автора; author == nil — изменение пришло с другой реплики
+func (h *Hub) apply(d *document, change Change, text string, author *client) {
+	d.text, d.rev = text, change.Rev
+	d.history = append(d.history, change)
+	if extra := len(d.history) - h.cfg.HistoryLimit; extra > 0 {
+		d.history = append([]Change(nil), d.history[extra:]...)
+	}
+	for _, peer := range d.peers {
+		peer.Anchor = TransformIndex(peer.Anchor, change.Op)
+		peer.Head = TransformIndex(peer.Head, change.Op)
+	}
+
+	msg := encode(opMessage{Type: "op", Rev: change.Rev, Op: change.Op, ClientID: change.ClientID})
+	for c := range d.clients {
+		if c != author {
+			c.enqueue(msg)
+		}
+	}
+}
+
+// catchUp применяет изменения, принятые другими репликами. Если их уже не
+// восстановить, документ перечитывается и клиенты получают его заново.
+func (h *Hub) catchUp(ctx context.Context, d *document) error {
+	changes, err := h.store.Changes(ctx, d.id, d.rev)
+	if err == nil {
+		for _, change := range changes {
+			text, applyErr := change.Op.Apply(d.text)
+			if applyErr != nil || change.Rev != d.rev+1 {
+				err = ErrHistoryTrimmed
+				break
+			}
+			h.apply(d, change, text, nil)
+		}
+	}
+	if !errors.Is(err, ErrHistoryTrimmed) {
+		return err
+	}
+
+	slog.WarnContext(ctx, "Collab document is out of sync, reloading", "note_id", d.id, "rev", d.rev)
+	// Версия заметки здесь неизвестна: сохранение сольет текст с заметкой по Synced
+	snap, err := h.store.Load(ctx, d.id, d.text, 0)
+	if err != nil {
+		return err
+	}
+	d.text, d.rev, d.history = snap.Text, snap.Rev, nil
+	d.savedRev = min(d.savedRev, d.rev)
+	for c := range d.clients {
+		c.enqueue(h.initMessage(d, c))
+	}
+	return nil
+}
+
+// moveCursor обновляет выделение клиента, заданное в ревизии rev
+func (h *Hub) moveCursor(ctx context.Context, d *document, c *client, rev, anchor, head int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if rev > d.rev {
+		return
+	}
+	// Курсор, отставший больше чем на хранимую историю, не стоит запроса в Store
+	changes, err := changesAfter(d.history, rev, d.rev)
+	if err != nil {
+		return
+	}
+	for _, change := range changes {
+		anchor = TransformIndex(anchor, change.Op)
+		head = TransformIndex(head, change.Op)
+	}
+	length := utf8.RuneCountInString(d.text)
+	c.peer.Anchor = max(0, min(anchor, length))
+	c.peer.Head = max(0, min(head, length))
+	h.broadcastPeer(ctx, d, c)
+}
+
+// broadcastPeer рассылает курсор клиента остальным клиентам и репликам
+func (h *Hub) broadcastPeer(ctx context.Context, d *document, c *client) {
+	peer := c.peer
+	msg := encode(presenceMessage{Type: "presence", Peer: peer})
+	for other := range d.clients {
+		if other != c {
+			other.enqueue(msg)
+		}
+	}
+	if err := h.store.Publish(ctx, d.id, Envelope{Peer: &peer}); err != nil {
+		slog.WarnContext(ctx, "Error publishing collab presence", "note_id", d.id, "error", err)
+	}
+}
+
+// receive обрабатывает сообщение другой реплики
+func (h *Hub) receive(noteID int32, env *Envelope) {
+	h.mu.Lock()
+	d := h.docs[noteID]
+	h.mu.Unlock()
+	if d == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	defer cancel()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.loaded {
+		return
+	}
+
+	switch {
+	case env == nil:
+		if err := h.catchUp(ctx, d); err != nil {
+			slog.ErrorContext(ctx, "Error catching up collab document", "note_id", noteID, "error", err)
+		}
+	case env.Change != nil:
+		change := *env.Change
+		if change.Rev <= d.rev {
+			return
+		}
+		if change.Rev == d.rev+1 {
+			if text, err := change.Op.Apply(d.text); err == nil {
+				h.apply(d, change, text, nil)
+				return
+			}
+		}
+		if err := h.catchUp(ctx, d); err != nil {
+			slog.ErrorContext(ctx, "Error catching up collab document", "note_id", noteID, "error", err)
+		}
+	case env.Peer != nil:
+		peer := *env.Peer
+		if local, ok := d.peers[peer.ClientID]; ok && !local.remote {
+			return
+		}
+		peer.remote, peer.seen = true, time.Now()
+		d.peers[peer.ClientID] = &peer
+		msg := encode(presenceMessage{Type: "presence", Peer: peer})
+		for c := range d.clients {
+			c.enqueue(msg)
+		}
+	case env.Left != "":
+		if peer, ok := d.peers[env.Left]; ok && peer.remote {
+			delete(d.peers, env.Left)
+			msg := encode(leaveMessage{Type: "leave", ClientID: env.Left})
+			for c := range d.clients {
+				c.enqueue(msg)
+			}
+		}
+	}
+}
+
+// refreshPresence напоминает другим репликам о курсорах своих клиентов
+// и забывает участников, о которых реплики давно не сообщали
+func (h *Hub) refreshPresence(ctx context.Context) {
+	h.mu.Lock()
+	docs := make([]*document, 0, len(h.docs))
+	for _, d := range h.docs {
+		docs = append(docs, d)
+	}
+	h.mu.Unlock()
+
+	for _, d := range docs {
+		d.mu.Lock()
+		for c := range d.clients {
+			peer := c.peer
+			if err := h.store.Publish(ctx, d.id, Envelope{Peer: &peer}); err != nil {
+				slog.WarnContext(ctx, "Error publishing collab presence", "note_id", d.id, "error", err)
+				break
+			}
+		}
+		for id, peer := range d.peers {
+			if peer.remote && time.Since(peer.seen) > presenceTTL {
+				delete(d.peers, id)
+				msg := encode(leaveMessage{Type: "leave", ClientID: id})
+				for c := range d.clients {
+					c.enqueue(msg)
+				}
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// scheduleSave откладывает сохранение в заметку на SnapshotDelay после правки,
+// чтобы не писать в базу на каждое нажатие клавиши
+func (h *Hub) scheduleSave(d *document) {
+	if d.saveTimer != nil {
+		return
+	}
+	d.saveTimer = time.AfterFunc(h.cfg.SnapshotDelay, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+		defer cancel()
+		d.mu.Lock()
+		d.saveTimer = nil
+		d.mu.Unlock()
+		h.save(ctx, d)
+	})
+}
+
+// save записывает текст документа в заметку через репозиторий, поэтому
+// действуют квоты и outbox получает note.updated. Заголовок остается тем,
+// что сейчас в заметке; правки текста через REST во время сеанса сливаются
+// с документом.
+func (h *Hub) save(ctx context.Context, d *document) {
+	d.saveMu.Lock()
+	defer d.saveMu.Unlock()
+
+	d.mu.Lock()
+	text, rev := d.text, d.rev
+	saved := rev <= d.savedRev
+	ctx = audit.WithDetail(audit.WithActor(ctx, d.editor), "collab")
+	d.mu.Unlock()
+	if saved {
+		return
+	}
+
+	err := h.saveText(ctx, d, text, rev)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var quota *storage.QuotaError
+	switch {
+	case err == nil:
+		d.savedRev = max(d.savedRev, rev)
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrForbidden):
+		// Заметку удалили: редактировать больше нечего
+		for c := range d.clients {
+			c.enqueue(encode(errorMessage{Type: "error", Code: "note_deleted", Message: "Note was deleted"}))
+			c.close(closeNoteDeleted, "note deleted")
+		}
+	case errors.As(err, &quota), errors.Is(err, storage.ErrNoteTooLarge):
+		// Текст остается в документе; следующая правка попробует сохранить снова
+		code, message := "quota_exceeded", "Storage quota exceeded, changes are not saved"
+		if quota == nil {
+			code, message = "note_too_large", "Note exceeds the maximum size, changes are not saved"
+		}
+		for c := range d.clients {
+			c.enqueue(encode(errorMessage{Type: "error", Code: code, Message: message}))
+		}
+	default:
+		slog.ErrorContext(ctx, "Error saving collab document", "note_id", d.id, "error", err)
+		if len(d.clients) > 0 {
+			h.scheduleSave(d)
+		}
+	}
+}
+
+// saveText записывает text ревизии rev в заметку поверх версии, с которой
+// документ последний раз совпадал с заметкой. Если заметка с тех пор
+// изменилась мимо сеанса, правки сливаются, а результат возвращается
+// в документ правкой сервера.
+func (h *Hub) saveText(ctx context.Context, d *document, text string, rev int) error {
+	current, err := h.notes.GetNoteByID(ctx, d.id, d.owner)
+	if err != nil {
+		return err
+	}
+	snap, err := h.store.Load(ctx, d.id, current.Content, current.Version)
+	if err != nil {
+		return err
+	}
+	if current.Content == text {
+		return h.store.MarkSynced(ctx, d.id, text, current.Version)
+	}
+
+	// Synced отмечается до записи: реплика, открывающая документ в этот момент,
+	// не должна принять новый текст заметки за правку мимо сеанса
+	if err := h.store.MarkSynced(ctx, d.id, text, snap.SyncedVersion); err != nil {
+		return err
+	}
+	note, err := h.update(ctx, d, current, snap, text)
+	if err != nil {
+		if syncErr := h.store.MarkSynced(ctx, d.id, snap.Synced, snap.SyncedVersion); syncErr != nil {
+			slog.WarnContext(ctx, "Error restoring collab synced text", "note_id", d.id, "error", syncErr)
+		}
+		return err
+	}
+	if err := h.store.MarkSynced(ctx, d.id, note.Content, note.Version); err != nil {
+		slog.WarnContext(ctx, "Error marking collab document synced", "note_id", d.id, "error", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.title = note.Title
+	if note.Content != text {
+		if _, err := h.commit(ctx, d, nil, rev, Diff(text, note.Content)); err != nil {
+			slog.WarnContext(ctx, "Error merging note edits into collab document", "note_id", d.id, "error", err)
+		}
+	}
+	return nil
+}
+
+// update сливает text с правками заметки, сделанными после SyncedVersion.
+// Конфликтующие строки сохраняются с маркерами обеих сторон: участники
+// разрешат их в документе сами.
+func (h *Hub) update(ctx context.Context, d *document, current *models.Note, snap Snapshot, text string) (*models.Note, error) {
+	if snap.SyncedVersion > 0 {
+		note, err := h.notes.UpdateNote(ctx, d.id, d.owner, current.Title, text, snap.SyncedVersion)
+		var conflict *storage.MergeConflictError
+		if errors.As(err, &conflict) {
+			merged := conflict.Merged
+			return h.notes.UpdateNote(ctx, d.id, d.owner, merged.Title, merged.Content, merged.BaseVersion)
+		}
+		if !errors.Is(err, storage.ErrVersionConflict) {
+			return note, err
+		}
+	}
+	// Версия неизвестна или уже забыта: базой служит текст, сохраненный в Store
+	merged, _ := merge.Merge(snap.Synced, current.Content, text)
+	return h.notes.UpdateNote(ctx, d.id, d.owner, current.Title, merged, current.Version)
+}
+
+// close отключает всех клиентов и сохраняет документы при остановке сервиса
+func (h *Hub) close() {
+	h.mu.Lock()
+	h.closed = true
+	docs := make([]*document, 0, len(h.docs))
+	for _, d := range h.docs {
+		docs = append(docs, d)
+	}
+	h.mu.Unlock()
+
+	for _, d := range docs {
+		ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+		d.mu.Lock()
+		for c := range d.clients {
+			c.close(closeGoingAway, "server is shutting down")
+		}
+		if d.saveTimer != nil {
+			d.saveTimer.Stop()
+			d.saveTimer = nil
+		}
+		loaded := d.loaded
+		d.mu.Unlock()
+		if loaded {
+			h.save(ctx, d)
+		}
+		cancel()
+	}
+}
+
+func (h *Hub) initMessage(d *document, c *client) []byte {
+	peers := make([]Peer, 0, len(d.peers))
+	for id, peer := range d.peers {
+		if id != c.peer.ClientID {
+			peers = append(peers, *peer)
+		}
+	}
+	return encode(initMessage{
+		Type:     "init",
+		ClientID: c.peer.ClientID,
+		Rev:      d.rev,
+		Title:    d.title,
+		Content:  d.text,
+		Peers:    peers,
+	})
+}
@@ -0,0 +1,310 @@
+// Package collab — совместное редактирование текста заметки. Клиенты
+// присылают операции над текстом (operational transformation), сервер
+// упорядочивает их, преобразует относительно параллельных правок
+// и рассылает остальным; готовый текст периодически сохраняется в заметку.
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var ErrInvalidOp = errors.New("invalid operation")
+
+// maxOpLen — наибольшая длина текста до и после операции в символах. Текст
+// заметки заведомо короче, а суммы длин шагов с таким пределом не переполняют int.
+const maxOpLen = 1 << 30
+
+// component — шаг операции: retain > 0 пропускает символы, retain < 0
+// удаляет -retain символов, insert вставляет строку
+type component struct {
+	retain int
+	insert string
+}
+
+// Op — операция над текстом в формате ot.js: в JSON это массив, где
+// положительное число — пропустить символы, отрицательное — удалить,
+// строка — вставить. Длины считаются в символах Unicode (code points),
+// а не в байтах и не в UTF-16.
+type Op struct {
+	ops []component
+	// Длина текста до и после операции
+	baseLen, targetLen int
+}
+
+func (o *Op) Retain(n int) *Op {
+	if n <= 0 {
+		return o
+	}
+	o.baseLen += n
+	o.targetLen += n
+	if last := o.last(); last != nil && last.insert == "" && last.retain > 0 {
+		last.retain += n
+		return o
+	}
+	o.ops = append(o.ops, component{retain: n})
+	return o
+}
+
+func (o *Op) Insert(s string) *Op {
+	if s == "" {
+		return o
+	}
+	o.targetLen += utf8.RuneCountInString(s)
+	last := o.last()
+	switch {
+	case last != nil && last.insert != "":
+		last.insert += s
+	case last != nil && last.retain < 0:
+		// Вставка всегда идет перед удалением в той же позиции: так у одного
+		// изменения одна запись
+		if prev := o.at(len(o.ops) - 2); prev != nil && prev.insert != "" {
+			prev.insert += s
+		} else {
+			o.ops = append(o.ops, component{})
+			copy(o.ops[len(o.ops)-1:], o.ops[len(o.ops)-2:])
+			o.ops[len(o.ops)-2] = component{insert: s}
+		}
+	default:
+		o.ops = append(o.ops, component{insert: s})
+	}
+	return o
+}
+
+func (o *Op) Delete(n int) *Op {
+	if n <= 0 {
+		return o
+	}
+	o.baseLen += n
+	if last := o.last(); last != nil && last.insert == "" && last.retain < 0 {
+		last.retain -= n
+		return o
+	}
+	o.ops = append(o.ops, component{retain: -n})
+	return o
+}
+
+func (o *Op) last() *component {
+	return o.at(len(o.ops) - 1)
+}
+
+func (o *Op) at(i int) *component {
+	if i < 0 || i >= len(o.ops) {
+		return nil
+	}
+	return &o.ops[i]
+}
+
+// BaseLen — длина текста, к которому применима операция
+func (o Op) BaseLen() int { return o.baseLen }
+
+// TargetLen — длина текста после операции
+func (o Op) TargetLen() int { return o.targetLen }
+
+// IsNoop — операция ничего не меняет
+func (o Op) IsNoop() bool {
+	return len(o.ops) == 0 || len(o.ops) == 1 && o.ops[0].insert == "" && o.ops[0].retain > 0
+}
+
+// Apply применяет операцию к тексту
+func (o Op) Apply(text string) (string, error) {
+	runes := []rune(text)
+	if len(runes) != o.baseLen {
+		return "", fmt.Errorf("%w: expects text of %d characters, got %d", ErrInvalidOp, o.baseLen, len(runes))
+	}
+
+	result := make([]rune, 0, o.targetLen)
+	pos := 0
+	for _, c := range o.ops {
+		// Шаги сверяются с текстом сами: ошибка в подсчете длин не должна
+		// обернуться выходом за границы
+		if c.insert == "" && abs(c.retain) > len(runes)-pos {
+			return "", fmt.Errorf("%w: operation goes past the end of the text", ErrInvalidOp)
+		}
+		switch {
+		case c.insert != "":
+			result = append(result, []rune(c.insert)...)
+		case c.retain > 0:
+			result = append(result, runes[pos:pos+c.retain]...)
+			pos += c.retain
+		default:
+			pos -= c.retain
+		}
+	}
+	if pos != len(runes) {
+		return "", fmt.Errorf("%w: operation does not cover the whole text", ErrInvalidOp)
+	}
+	return string(result), nil
+}
+
+// Transform преобразует параллельные операции a и b над одним текстом в a' и b'
+// так, что b' после a и a' после b дают один и тот же текст. При вставке
+// в одну позицию текст a оказывается раньше.
+func Transform(a, b Op) (Op, Op, error) {
+	if a.baseLen != b.baseLen {
+		return Op{}, Op{}, fmt.Errorf("%w: concurrent operations expect texts of %d and %d characters", ErrInvalidOp, a.baseLen, b.baseLen)
+	}
+
+	var aPrime, bPrime Op
+	ops1, ops2 := a.ops, b.ops
+	var op1, op2 *component
+	next := func(ops *[]component) *component {
+		if len(*ops) == 0 {
+			return nil
+		}
+		c := (*ops)[0]
+		*ops = (*ops)[1:]
+		return &c
+	}
+	op1, op2 = next(&ops1), next(&ops2)
+
+	for op1 != nil || op2 != nil {
+		if op1 != nil && op1.insert != "" {
+			aPrime.Insert(op1.insert)
+			bPrime.Retain(utf8.RuneCountInString(op1.insert))
+			op1 = next(&ops1)
+			continue
+		}
+		if op2 != nil && op2.insert != "" {
+			aPrime.Retain(utf8.RuneCountInString(op2.insert))
+			bPrime.Insert(op2.insert)
+			op2 = next(&ops2)
+			continue
+		}
+		// Длины совпадают, поэтому оставшиеся шаги обеих операций кончаются одновременно
+		if op1 == nil || op2 == nil {
+			return Op{}, Op{}, fmt.Errorf("%w: operations cover different lengths", ErrInvalidOp)
+		}
+
+		n1, n2 := abs(op1.retain), abs(op2.retain)
+		n := min(n1, n2)
+		switch {
+		case op1.retain > 0 && op2.retain > 0:
+			aPrime.Retain(n)
+			bPrime.Retain(n)
+		case op1.retain < 0 && op2.retain > 0:
+			aPrime.Delete(n)
+		case op1.retain > 0 && op2.retain < 0:
+			bPrime.Delete(n)
+		}
+		// Оба удалили одни и те же символы — в преобразованных операциях их нет
+
+		if n1 == n {
+			op1 = next(&ops1)
+		} else {
+			op1.retain -= sign(op1.retain) * n
+		}
+		if n2 == n {
+			op2 = next(&ops2)
+		} else {
+			op2.retain -= sign(op2.retain) * n
+		}
+	}
+	return aPrime, bPrime, nil
+}
+
+// TransformIndex сдвигает позицию курсора в тексте на результат операции
+func TransformIndex(index int, o Op) int {
+	newIndex := index
+	for _, c := range o.ops {
+		switch {
+		case c.insert != "":
+			newIndex += utf8.RuneCountInString(c.insert)
+		case c.retain > 0:
+			index -= c.retain
+		default:
+			newIndex -= min(index, -c.retain)
+			index += c.retain
+		}
+		if index < 0 {
+			break
+		}
+	}
+	return newIndex
+}
+
+// Diff строит операцию, превращающую old в new: общие начало и конец
+// сохраняются, середина заменяется целиком
+func Diff(old, new string) Op {
+	a, b := []rune(old), []rune(new)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var op Op
+	op.Retain(prefix)
+	op.Insert(string(b[prefix : len(b)-suffix]))
+	op.Delete(len(a) - prefix - suffix)
+	op.Retain(suffix)
+	return op
+}
+
+func (o Op) MarshalJSON() ([]byte, error) {
+	items := make([]any, len(o.ops))
+	for i, c := range o.ops {
+		if c.insert != "" {
+			items[i] = c.insert
+		} else {
+			items[i] = c.retain
+		}
+	}
+	return json.Marshal(items)
+}
+
+func (o *Op) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOp, err)
+	}
+
+	var op Op
+	for _, item := range items {
+		var s string
+		if err := json.Unmarshal(item, &s); err == nil {
+			if s == "" {
+				return fmt.Errorf("%w: empty insert", ErrInvalidOp)
+			}
+			if utf8.RuneCountInString(s) > maxOpLen-op.targetLen {
+				return fmt.Errorf("%w: operation is too long", ErrInvalidOp)
+			}
+			op.Insert(s)
+			continue
+		}
+		var n int
+		if err := json.Unmarshal(item, &n); err != nil || n == 0 {
+			return fmt.Errorf("%w: component must be a non-empty string or a non-zero integer", ErrInvalidOp)
+		}
+		// Длины сверяются до сложения и до смены знака: -math.MinInt тоже отрицательно
+		if n > maxOpLen-op.baseLen || n < -(maxOpLen-op.baseLen) || n > 0 && n > maxOpLen-op.targetLen {
+			return fmt.Errorf("%w: operation is too long", ErrInvalidOp)
+		}
+		if n > 0 {
+			op.Retain(n)
+		} else {
+			op.Delete(-n)
+		}
+	}
+	*o = op
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,180 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func mustOp(t *testing.T, s string) Op {
+	t.Helper()
+	var op Op
+	if err := json.Unmarshal([]byte(s), &op); err != nil {
+		t.Fatalf("unmarshal %s: %v", s, err)
+	}
+	return op
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name, text, op, want string
+	}{
+		{"insert at start", "hello", `["> ",5]`, "> hello"},
+		{"insert at end", "hello", `[5,"!"]`, "hello!"},
+		{"delete", "hello", `[1,-3,1]`, "ho"},
+		{"replace", "hello", `["j",-1,4]`, "jello"},
+		{"delete everything", "hello", `[-5]`, ""},
+		{"insert into empty", "", `["hi"]`, "hi"},
+		{"code points, not bytes", "привет", `[2,"ё",-4]`, "прё"},
+		{"astral characters count once", "a😀b", `[2,-1,"c"]`, "a😀c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mustOp(t, tt.op).Apply(tt.text)
+			if err != nil || got != tt.want {
+				t.Errorf("Apply(%q) = %q, %v; want %q", tt.text, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		op   Op
+	}{
+		{"text too short", "hell", mustOp(t, `[5,"!"]`)},
+		{"text too long", "hello!", mustOp(t, `[5,"!"]`)},
+		// Длины, не совпадающие с шагами, не должны выводить за границы текста
+		{"retain past the end", "hello", Op{ops: []component{{retain: 7}}, baseLen: 5, targetLen: 5}},
+		{"delete past the end", "hello", Op{ops: []component{{retain: 3}, {retain: -3}}, baseLen: 5, targetLen: 3}},
+		{"short of the end", "hello", Op{ops: []component{{retain: 3}}, baseLen: 5, targetLen: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.op.Apply(tt.text); !errors.Is(err, ErrInvalidOp) {
+				t.Errorf("Apply(%q) = %q, %v; want ErrInvalidOp", tt.text, got, err)
+			}
+		})
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name, text, a, b, want string
+	}{
+		{"inserts in different places", "hello", `["<",5]`, `[5,">"]`, "<hello>"},
+		{"inserts at the same place, a first", "hello", `[5,"A"]`, `[5,"B"]`, "helloAB"},
+		{"insert inside deleted range", "hello", `[2,"X",3]`, `[1,-3,1]`, "hXo"},
+		{"overlapping deletes", "hello", `[1,-3,1]`, `[2,-3]`, "h"},
+		{"same delete", "hello", `[-2,3]`, `[-2,3]`, "llo"},
+		{"delete and retain", "hello", `[-5]`, `[5]`, ""},
+		{"noop against edit", "hello", `[5]`, `["¡",-1,4]`, "¡ello"},
+		{"both empty base", "", `["a"]`, `["b"]`, "ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := mustOp(t, tt.a), mustOp(t, tt.b)
+			aPrime, bPrime, err := Transform(a, b)
+			if err != nil {
+				t.Fatalf("Transform: %v", err)
+			}
+
+			// TP1: a, затем b' дает то же, что b, затем a'
+			afterA, err := a.Apply(tt.text)
+			if err != nil {
+				t.Fatalf("a.Apply: %v", err)
+			}
+			viaA, err := bPrime.Apply(afterA)
+			if err != nil {
+				t.Fatalf("b'.Apply: %v", err)
+			}
+			afterB, err := b.Apply(tt.text)
+			if err != nil {
+				t.Fatalf("b.Apply: %v", err)
+			}
+			viaB, err := aPrime.Apply(afterB)
+			if err != nil {
+				t.Fatalf("a'.Apply: %v", err)
+			}
+			if viaA != viaB {
+				t.Fatalf("TP1 violated: a·b' = %q, b·a' = %q", viaA, viaB)
+			}
+			if viaA != tt.want {
+				t.Errorf("converged text = %q, want %q", viaA, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformMismatchedLengths(t *testing.T) {
+	if _, _, err := Transform(mustOp(t, `[5]`), mustOp(t, `[4]`)); !errors.Is(err, ErrInvalidOp) {
+		t.Errorf("Transform of different base lengths: err = %v, want ErrInvalidOp", err)
+	}
+}
+
+func TestTransformIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		index int
+		op    string
+		want  int
+	}{
+		{"insert before", 3, `["ab",5]`, 5},
+		{"insert at the cursor pushes it", 3, `[3,"ab",2]`, 5},
+		{"insert after", 3, `[4,"ab",1]`, 3},
+		{"delete before", 3, `[-2,3]`, 1},
+		{"delete around", 3, `[1,-3,1]`, 1},
+		{"delete after", 1, `[2,-3]`, 1},
+		{"delete to the end", 5, `[2,-3]`, 2},
+		{"start stays", 0, `[-2,3]`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TransformIndex(tt.index, mustOp(t, tt.op)); got != tt.want {
+				t.Errorf("TransformIndex(%d, %s) = %d, want %d", tt.index, tt.op, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpJSON(t *testing.T) {
+	op := mustOp(t, `[2,"ab",-1,3]`)
+	data, err := json.Marshal(op)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(data) != `[2,"ab",-1,3]` {
+		t.Errorf("Marshal = %s, want [2,\"ab\",-1,3]", data)
+	}
+	if op.BaseLen() != 6 || op.TargetLen() != 7 {
+		t.Errorf("lengths = %d→%d, want 6→7", op.BaseLen(), op.TargetLen())
+	}
+}
+
+func TestOpJSONInvalid(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"not an array", `{"retain":1}`},
+		{"empty insert", `[1,""]`},
+		{"zero", `[0]`},
+		{"fraction", `[1.5]`},
+		{"nested", `[[1]]`},
+		{"null component", `[null]`},
+		{"overflowing retains", `[9223372036854775807,"x",9223372036854775807,7]`},
+		{"min int delete", `[-9223372036854775808]`},
+		{"retains past the limit", `[1073741824,1]`},
+		{"deletes past the limit", `[-1073741824,-1]`},
+		{"number beyond int", `[92233720368547758070]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var op Op
+			if err := json.Unmarshal([]byte(tt.data), &op); !errors.Is(err, ErrInvalidOp) {
+				t.Errorf("Unmarshal(%s): err = %v, want ErrInvalidOp", tt.data, err)
+			}
+		})
+	}
+}
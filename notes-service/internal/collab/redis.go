@@ -0,0 +1,245 @@
+package collab
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Пауза перед повтором, пока Redis недоступен
+const listenRetry = time.Second
+
+// Документ создается, только если его еще нет; TTL продлевается при каждом открытии
+var loadScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	redis.call('HSET', KEYS[1], 'text', ARGV[1], 'rev', 0, 'synced', ARGV[1], 'synced_version', ARGV[3])
+	redis.call('DEL', KEYS[2])
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+return redis.call('HMGET', KEYS[1], 'text', 'rev', 'synced', 'synced_version')
+`)
+
+// Изменение принимается, только если следует за последним принятым; рассылка
+// в том же скрипте, поэтому реплики получают изменения в порядке ревизий
+var commitScript = redis.NewScript(`
+local rev = tonumber(redis.call('HGET', KEYS[1], 'rev'))
+if rev == nil or rev + 1 ~= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'text', ARGV[2], 'rev', ARGV[1])
+redis.call('RPUSH', KEYS[2], ARGV[3])
+redis.call('LTRIM', KEYS[2], -tonumber(ARGV[4]), -1)
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+redis.call('PEXPIRE', KEYS[2], ARGV[5])
+redis.call('PUBLISH', ARGV[6], ARGV[7])
+return 1
+`)
+
+var markSyncedScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('HSET', KEYS[1], 'synced', ARGV[1], 'synced_version', ARGV[2])
+end
+return 0
+`)
+
+// RedisStore — документы в Redis, общие для всех реплик. Документ без правок
+// и открытий удаляется через idleTTL; его текст к тому времени уже в заметке.
+type RedisStore struct {
+	rdb          *redis.Client
+	prefix       string
+	historyLimit int
+	idleTTL      time.Duration
+	// origin отличает сообщения этой реплики от чужих
+	origin string
+	pubsub *redis.PubSub
+}
+
+// redisEnvelope — Envelope с репликой-отправителем
+type redisEnvelope struct {
+	Origin string `json:"origin"`
+	Envelope
+}
+
+func NewRedisStore(rdb *redis.Client, prefix string, historyLimit int, idleTTL time.Duration) *RedisStore {
+	return &RedisStore{
+		rdb:          rdb,
+		prefix:       prefix,
+		historyLimit: historyLimit,
+		idleTTL:      idleTTL,
+		origin:       rand.Text(),
+		// Каналы добавляются через Watch; соединение открывается при первой подписке
+		pubsub: rdb.Subscribe(context.Background()),
+	}
+}
+
+// Хеш-тег {id} держит ключи документа в одном слоте Redis Cluster
+func (s *RedisStore) docKey(noteID int32) string {
+	return fmt.Sprintf("%s:{%d}", s.prefix, noteID)
+}
+
+func (s *RedisStore) changesKey(noteID int32) string {
+	return fmt.Sprintf("%s:{%d}:changes", s.prefix, noteID)
+}
+
+func (s *RedisStore) channel(noteID int32) string {
+	return fmt.Sprintf("%s:%d", s.prefix, noteID)
+}
+
+func (s *RedisStore) Load(ctx context.Context, noteID int32, content string, version int64) (Snapshot, error) {
+	res, err := loadScript.Run(ctx, s.rdb, []string{s.docKey(noteID), s.changesKey(noteID)},
+		content, s.idleTTL.Milliseconds(), version).Slice()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(res) != 4 {
+		return Snapshot{}, fmt.Errorf("unexpected collab document reply %v", res)
+	}
+	text, _ := res[0].(string)
+	revStr, _ := res[1].(string)
+	synced, _ := res[2].(string)
+	rev, err := strconv.Atoi(revStr)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("invalid collab document revision %q", revStr)
+	}
+	// Документ, созданный до появления synced_version, сохранится без проверки версии
+	syncedVersion, _ := res[3].(string)
+	version, _ = strconv.ParseInt(syncedVersion, 10, 64)
+	return Snapshot{Text: text, Rev: rev, Synced: synced, SyncedVersion: version}, nil
+}
+
+func (s *RedisStore) Commit(ctx context.Context, noteID int32, change Change, text string) (bool, error) {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return false, err
+	}
+	msg, err := json.Marshal(redisEnvelope{Origin: s.origin, Envelope: Envelope{Change: &change}})
+	if err != nil {
+		return false, err
+	}
+	ok, err := commitScript.Run(ctx, s.rdb, []string{s.docKey(noteID), s.changesKey(noteID)},
+		change.Rev, text, data, s.historyLimit, s.idleTTL.Milliseconds(), s.channel(noteID), msg).Int()
+	return ok == 1, err
+}
+
+func (s *RedisStore) Changes(ctx context.Context, noteID int32, afterRev int) ([]Change, error) {
+	var items *redis.StringSliceCmd
+	var rev *redis.StringCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		items = pipe.LRange(ctx, s.changesKey(noteID), 0, -1)
+		rev = pipe.HGet(ctx, s.docKey(noteID), "rev")
+		return nil
+	})
+	if err == redis.Nil {
+		return nil, ErrHistoryTrimmed
+	}
+	if err != nil {
+		return nil, err
+	}
+	current, err := rev.Int()
+	if err != nil {
+		return nil, fmt.Errorf("invalid collab document revision: %w", err)
+	}
+
+	history := make([]Change, 0, len(items.Val()))
+	for _, item := range items.Val() {
+		var change Change
+		if err := json.Unmarshal([]byte(item), &change); err != nil {
+			return nil, fmt.Errorf("malformed collab change: %w", err)
+		}
+		history = append(history, change)
+	}
+	return changesAfter(history, afterRev, current)
+}
+
+func (s *RedisStore) MarkSynced(ctx context.Context, noteID int32, text string, version int64) error {
+	return markSyncedScript.Run(ctx, s.rdb, []string{s.docKey(noteID)}, text, version).Err()
+}
+
+// Release ничего не делает: документ могут редактировать через другие реплики
+func (s *RedisStore) Release(ctx context.Context, noteID int32) error { return nil }
+
+func (s *RedisStore) Publish(ctx context.Context, noteID int32, env Envelope) error {
+	msg, err := json.Marshal(redisEnvelope{Origin: s.origin, Envelope: env})
+	if err != nil {
+		return err
+	}
+	return s.rdb.Publish(ctx, s.channel(noteID), msg).Err()
+}
+
+func (s *RedisStore) Watch(ctx context.Context, noteID int32) error {
+	return s.pubsub.Subscribe(ctx, s.channel(noteID))
+}
+
+func (s *RedisStore) Unwatch(ctx context.Context, noteID int32) error {
+	return s.pubsub.Unsubscribe(ctx, s.channel(noteID))
+}
+
+// Listen читает подписки, добавленные через Watch. PubSub сам подписывается
+// заново после обрыва соединения; сообщения за это время теряются, поэтому
+// повторная подписка на канал передается как env == nil.
+func (s *RedisStore) Listen(ctx context.Context, receive func(noteID int32, env *Envelope)) {
+	// Receive не следит за ctx; закрытие прерывает ожидание
+	stop := context.AfterFunc(ctx, func() { s.pubsub.Close() })
+	defer stop()
+
+	subscribed := make(map[string]bool)
+	for {
+		msg, err := s.pubsub.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Error receiving collab messages from Redis", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(listenRetry):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			noteID, ok := s.noteID(msg.Channel)
+			if !ok {
+				continue
+			}
+			switch msg.Kind {
+			case "subscribe":
+				if subscribed[msg.Channel] {
+					receive(noteID, nil)
+				}
+				subscribed[msg.Channel] = true
+			case "unsubscribe":
+				delete(subscribed, msg.Channel)
+			}
+		case *redis.Message:
+			noteID, ok := s.noteID(msg.Channel)
+			if !ok {
+				continue
+			}
+			var env redisEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				slog.WarnContext(ctx, "Malformed collab message in Redis", "error", err)
+				continue
+			}
+			if env.Origin == s.origin {
+				continue
+			}
+			receive(noteID, &env.Envelope)
+		}
+	}
+}
+
+func (s *RedisStore) noteID(channel string) (int32, bool) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(channel, s.prefix+":"), 10, 32)
+	return int32(id), err == nil
+}
@@ -0,0 +1,157 @@
+package collab
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrHistoryTrimmed — изменения после запрошенной ревизии уже не хранятся;
+// клиенту нужно заново получить документ целиком
+var ErrHistoryTrimmed = errors.New("collab history is trimmed")
+
+// Change — принятая операция; Rev — ревизия документа после нее
+type Change struct {
+	Rev      int    `json:"rev"`
+	Op       Op     `json:"op"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// Snapshot — состояние документа в хранилище. Synced — текст, который
+// последним совпадал с заметкой в базе, SyncedVersion — версия заметки с этим
+// текстом: если заметка с тех пор изменилась мимо совместного редактирования,
+// документ нужно догнать до нее, а сохранение сливается с ее правками.
+type Snapshot struct {
+	Text          string
+	Rev           int
+	Synced        string
+	SyncedVersion int64
+}
+
+// Envelope — сообщение между репликами, редактирующими одну заметку:
+// принятое изменение, курсор участника или его уход
+type Envelope struct {
+	Change *Change `json:"change,omitempty"`
+	Peer   *Peer   `json:"peer,omitempty"`
+	Left   string  `json:"left,omitempty"`
+}
+
+// Store упорядочивает изменения документа между репликами. Ревизии идут
+// подряд: Commit принимает изменение, только если оно следует за последним
+// принятым, и сам рассылает его остальным репликам.
+type Store interface {
+	// Load возвращает документ заметки; отсутствующий создается из content
+	// версии заметки version с ревизией 0
+	Load(ctx context.Context, noteID int32, content string, version int64) (Snapshot, error)
+	// Commit сохраняет change и текст после него, если текущая ревизия — change.Rev-1;
+	// false — документ уже изменила другая реплика
+	Commit(ctx context.Context, noteID int32, change Change, text string) (bool, error)
+	// Changes возвращает изменения после ревизии afterRev по порядку
+	// или ErrHistoryTrimmed, если часть из них уже удалена
+	Changes(ctx context.Context, noteID int32, afterRev int) ([]Change, error)
+	// MarkSynced запоминает текст, записанный в заметку, и ее версию с ним
+	MarkSynced(ctx context.Context, noteID int32, text string, version int64) error
+	// Release сообщает, что эта реплика закончила работу с документом
+	Release(ctx context.Context, noteID int32) error
+
+	// Publish рассылает сообщение о курсорах другим репликам
+	Publish(ctx context.Context, noteID int32, env Envelope) error
+	// Watch и Unwatch включают и выключают получение сообщений по заметке
+	Watch(ctx context.Context, noteID int32) error
+	Unwatch(ctx context.Context, noteID int32) error
+	// Listen передает receive сообщения других реплик, пока не отменен ctx.
+	// env == nil — сообщения могли потеряться, документ нужно сверить с хранилищем.
+	Listen(ctx context.Context, receive func(noteID int32, env *Envelope))
+}
+
+// MemoryStore — документы в памяти процесса, когда реплика одна
+// (хранилища sqlite и memory)
+type MemoryStore struct {
+	historyLimit int
+
+	mu   sync.Mutex
+	docs map[int32]*memoryDoc
+}
+
+type memoryDoc struct {
+	snapshot Snapshot
+	changes  []Change
+}
+
+func NewMemoryStore(historyLimit int) *MemoryStore {
+	return &MemoryStore{historyLimit: historyLimit, docs: make(map[int32]*memoryDoc)}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, noteID int32, content string, version int64) (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, ok := s.docs[noteID]
+	if !ok {
+		doc = &memoryDoc{snapshot: Snapshot{Text: content, Synced: content, SyncedVersion: version}}
+		s.docs[noteID] = doc
+	}
+	return doc.snapshot, nil
+}
+
+func (s *MemoryStore) Commit(ctx context.Context, noteID int32, change Change, text string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, ok := s.docs[noteID]
+	if !ok || doc.snapshot.Rev+1 != change.Rev {
+		return false, nil
+	}
+	doc.snapshot.Text = text
+	doc.snapshot.Rev = change.Rev
+	doc.changes = append(doc.changes, change)
+	if extra := len(doc.changes) - s.historyLimit; extra > 0 {
+		doc.changes = append([]Change(nil), doc.changes[extra:]...)
+	}
+	return true, nil
+}
+
+func (s *MemoryStore) Changes(ctx context.Context, noteID int32, afterRev int) ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, ok := s.docs[noteID]
+	if !ok {
+		return nil, ErrHistoryTrimmed
+	}
+	return changesAfter(doc.changes, afterRev, doc.snapshot.Rev)
+}
+
+func (s *MemoryStore) MarkSynced(ctx context.Context, noteID int32, text string, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if doc, ok := s.docs[noteID]; ok {
+		doc.snapshot.Synced, doc.snapshot.SyncedVersion = text, version
+	}
+	return nil
+}
+
+// Release забывает документ: других реплик нет, и следующий сеанс начнется
+// с текста заметки из базы
+func (s *MemoryStore) Release(ctx context.Context, noteID int32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.docs, noteID)
+	return nil
+}
+
+func (s *MemoryStore) Publish(ctx context.Context, noteID int32, env Envelope) error { return nil }
+func (s *MemoryStore) Watch(ctx context.Context, noteID int32) error                 { return nil }
+func (s *MemoryStore) Unwatch(ctx context.Context, noteID int32) error               { return nil }
+
+func (s *MemoryStore) Listen(ctx context.Context, receive func(noteID int32, env *Envelope)) {
+	<-ctx.Done()
+}
+
+// changesAfter выбирает из хранимой истории изменения после afterRev до текущей ревизии rev
+func changesAfter(history []Change, afterRev, rev int) ([]Change, error) {
+	if afterRev >= rev {
+		return nil, nil
+	}
+	if len(history) == 0 || history[0].Rev > afterRev+1 {
+		return nil, ErrHistoryTrimmed
+	}
+	return append([]Change(nil), history[afterRev+1-history[0].Rev:]...), nil
+}
@@ -0,0 +1,178 @@
+// Package config описывает настройки notes-service. Загружается один раз при старте.
+package config
+
+import (
+	"time"
+
+	"common/config"
+)
+
+type Config struct {
+	Port           int    `yaml:"port" env:"PORT" default:"8081"`
+	Storage        string `yaml:"storage" env:"NOTES_STORAGE" default:"postgres"`
+	SQLitePath     string `yaml:"sqlite_path" env:"SQLITE_PATH" default:"notes-manager.db"`
+	MigrateOnStart bool   `yaml:"migrate_on_start" env:"MIGRATE_ON_START" default:"true"`
+	JWTSecret      string `yaml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+
+	HTTP      config.HTTP      `yaml:"http"`
+	Tracing   config.Tracing   `yaml:"tracing"`
+	Logging   config.Logging   `yaml:"logging"`
+	Database  config.Database  `yaml:"database"`
+	Redis     config.Redis     `yaml:"redis"`
+	RateLimit config.RateLimit `yaml:"rate_limit"`
+	// Сколько список заметок живет в Redis
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"NOTES_CACHE_TTL" default:"2m"`
+	Limits   Limits        `yaml:"limits"`
+	Webhooks Webhooks      `yaml:"webhooks"`
+	Outbox   Outbox        `yaml:"outbox"`
+	Events   Events        `yaml:"events"`
+	Collab   Collab        `yaml:"collab"`
+	Sync     Sync          `yaml:"sync"`
+}
+
+// Limits — размеры запросов и заметок и квоты пользователя; 0 снимает ограничение
+type Limits struct {
+	MaxBodyBytes int64 `yaml:"max_body_bytes" env:"NOTES_MAX_BODY_BYTES" default:"1048576"`
+	// Заголовок и текст одной заметки в байтах
+	MaxNoteBytes int64 `yaml:"max_note_bytes" env:"NOTES_MAX_NOTE_BYTES" default:"262144"`
+	// Квоты на пользователя
+	MaxNotes      int   `yaml:"max_notes" env:"NOTES_QUOTA_MAX_NOTES" default:"5000"`
+	MaxTotalBytes int64 `yaml:"max_total_bytes" env:"NOTES_QUOTA_MAX_BYTES" default:"104857600"`
+}
+
+// Webhooks — исходящие вебхуки. Доставку выполняет фоновый обработчик в каждой реплике.
+type Webhooks struct {
+	MaxPerUser int `yaml:"max_per_user" env:"WEBHOOKS_MAX_PER_USER" default:"10"`
+	// После MaxAttempts неудачных попыток доставка помечается failed
+	MaxAttempts int `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" default:"8"`
+	// Пауза перед повтором удваивается от BackoffBase до BackoffMax
+	BackoffBase  time.Duration `yaml:"backoff_base" env:"WEBHOOKS_BACKOFF_BASE" default:"30s"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env:"WEBHOOKS_BACKOFF_MAX" default:"6h"`
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" default:"10s"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL" default:"2s"`
+	// Сколько хранится журнал завершенных доставок
+	LogRetention time.Duration `yaml:"log_retention" env:"WEBHOOKS_LOG_RETENTION" default:"168h"`
+	// Разрешает адреса в локальных и частных сетях; по умолчанию запрещены, чтобы
+	// через вебхук нельзя было обратиться к внутренним сервисам
+	AllowPrivate bool `yaml:"allow_private" env:"WEBHOOKS_ALLOW_PRIVATE" default:"false"`
+}
+
+// Outbox — публикация событий об изменении заметок из таблицы note_outbox.
+// С Postgres события уходят в поток Redis Streams.
+type Outbox struct {
+	PollInterval time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE" default:"100"`
+	// Сколько хранятся опубликованные события
+	Retention time.Duration `yaml:"retention" env:"OUTBOX_RETENTION" default:"24h"`
+	Stream    string        `yaml:"stream" env:"OUTBOX_STREAM" default:"notes:events"`
+	// Примерная максимальная длина потока; старые записи Redis удаляет сам
+	StreamMaxLen int64 `yaml:"stream_max_len" env:"OUTBOX_STREAM_MAX_LEN" default:"100000"`
+	// Канал pub/sub, через который события доходят до клиентов всех реплик
+	Channel string `yaml:"channel" env:"OUTBOX_CHANNEL" default:"notes:events:live"`
+}
+
+// Events — поток событий для клиентов, GET /api/notes/events
+type Events struct {
+	// Пустой комментарий раз в Heartbeat не дает прокси закрыть молчащее соединение
+	Heartbeat time.Duration `yaml:"heartbeat" env:"EVENTS_HEARTBEAT" default:"25s"`
+	// Сколько пропущенных событий клиент может дочитать после переподключения;
+	// если больше, он получает reset и перечитывает заметки
+	ReplayLimit int `yaml:"replay_limit" env:"EVENTS_REPLAY_LIMIT" default:"1000"`
+}
+
+// Collab — совместное редактирование заметок через WebSocket, /api/notes/{id}/collab.
+// С Postgres документы сеансов хранятся в Redis и общие для всех реплик.
+type Collab struct {
+	// Через сколько после правки текст сохраняется в заметку
+	SnapshotDelay time.Duration `yaml:"snapshot_delay" env:"COLLAB_SNAPSHOT_DELAY" default:"2s"`
+	// Сколько последних правок хранится, чтобы принять правку клиента,
+	// отставшего на несколько ревизий
+	HistoryLimit int `yaml:"history_limit" env:"COLLAB_HISTORY_LIMIT" default:"500"`
+	// Сколько документ без правок и подключений живет в Redis
+	IdleTTL time.Duration `yaml:"idle_ttl" env:"COLLAB_IDLE_TTL" default:"1h"`
+	// Префикс ключей и каналов Redis
+	RedisPrefix string `yaml:"redis_prefix" env:"COLLAB_REDIS_PREFIX" default:"notes:collab"`
+}
+
+// Sync — синхронизация офлайн-клиентов, /api/notes/sync
+type Sync struct {
+	// Сколько хранятся следы удаленных заметок; клиент, не синхронизировавшийся
+	// дольше, получает reset и все заметки заново
+	TombstoneRetention time.Duration `yaml:"tombstone_retention" env:"SYNC_TOMBSTONE_RETENTION" default:"720h"`
+	// Сколько изменений клиент может прислать в одном запросе
+	MaxMutations int `yaml:"max_mutations" env:"SYNC_MAX_MUTATIONS" default:"100"`
+}
+
+// Load читает конфигурацию из path (может быть пустым), .env и окружения
+// и проверяет ее. Все ошибки возвращаются одним config.Errors.
+func Load(path string) (*Config, error) {
+	var cfg Config
+	if err := config.Load(&cfg, path); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) Validate() error {
+	var errs config.Errors
+
+	config.ValidatePort(&errs, c.Port)
+	c.HTTP.Validate(&errs)
+	c.Tracing.Validate(&errs)
+	c.Logging.Validate(&errs)
+	c.RateLimit.Validate(&errs)
+	if c.Limits.MaxBodyBytes < 0 || c.Limits.MaxNoteBytes < 0 || c.Limits.MaxNotes < 0 || c.Limits.MaxTotalBytes < 0 {
+		errs.Addf("NOTES_MAX_* and NOTES_QUOTA_* limits must not be negative")
+	}
+	if c.Webhooks.MaxPerUser < 0 || c.Webhooks.MaxAttempts <= 0 {
+		errs.Addf("WEBHOOKS_MAX_PER_USER must not be negative and WEBHOOKS_MAX_ATTEMPTS must be positive")
+	}
+	if c.Webhooks.BackoffBase <= 0 || c.Webhooks.BackoffMax < c.Webhooks.BackoffBase {
+		errs.Addf("WEBHOOKS_BACKOFF_BASE must be positive and not exceed WEBHOOKS_BACKOFF_MAX")
+	}
+	if c.Webhooks.Timeout <= 0 || c.Webhooks.PollInterval <= 0 || c.Webhooks.LogRetention <= 0 {
+		errs.Addf("WEBHOOKS_TIMEOUT, WEBHOOKS_POLL_INTERVAL and WEBHOOKS_LOG_RETENTION must be positive")
+	}
+	if c.Outbox.PollInterval <= 0 || c.Outbox.BatchSize <= 0 || c.Outbox.Retention <= 0 {
+		errs.Addf("OUTBOX_POLL_INTERVAL, OUTBOX_BATCH_SIZE and OUTBOX_RETENTION must be positive")
+	}
+	if c.Events.Heartbeat <= 0 || c.Events.ReplayLimit <= 0 {
+		errs.Addf("EVENTS_HEARTBEAT and EVENTS_REPLAY_LIMIT must be positive")
+	}
+	if c.Collab.SnapshotDelay <= 0 || c.Collab.HistoryLimit <= 0 {
+		errs.Addf("COLLAB_SNAPSHOT_DELAY and COLLAB_HISTORY_LIMIT must be positive")
+	}
+	if c.Sync.TombstoneRetention <= 0 || c.Sync.MaxMutations <= 0 {
+		errs.Addf("SYNC_TOMBSTONE_RETENTION and SYNC_MAX_MUTATIONS must be positive")
+	}
+	if c.JWTSecret == "" {
+		errs.Addf("JWT_SECRET is required")
+	}
+
+	switch c.Storage {
+	case "postgres":
+		c.Database.Validate(&errs)
+		c.Redis.Validate(&errs)
+		if c.CacheTTL <= 0 {
+			errs.Addf("NOTES_CACHE_TTL must be positive")
+		}
+		if c.Outbox.Stream == "" || c.Outbox.Channel == "" || c.Outbox.StreamMaxLen <= 0 {
+			errs.Addf("OUTBOX_STREAM and OUTBOX_CHANNEL are required and OUTBOX_STREAM_MAX_LEN must be positive")
+		}
+		if c.Collab.RedisPrefix == "" || c.Collab.IdleTTL <= 0 {
+			errs.Addf("COLLAB_REDIS_PREFIX is required and COLLAB_IDLE_TTL must be positive")
+		}
+	case "sqlite":
+		if c.SQLitePath == "" {
+			errs.Addf("SQLITE_PATH is required for sqlite storage")
+		}
+	case "memory":
+	default:
+		errs.Addf("NOTES_STORAGE must be postgres, sqlite or memory, got %q", c.Storage)
+	}
+
+	return errs.Err()
+}
@@ -0,0 +1,157 @@
+// Package events раздает события об изменении заметок открытым потокам
+// клиентов (SSE). События приходят из outbox: через Redis pub/sub от relay
+// любой реплики или, в режимах без Redis, напрямую от relay этого процесса.
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+// ErrHistoryGap — часть событий после Last-Event-ID уже удалена из outbox
+// или их слишком много; клиенту нужно перечитать заметки целиком
+var ErrHistoryGap = errors.New("note event history is incomplete")
+
+// Сколько событий может ждать отправки одному клиенту. Медленный клиент
+// отключается и дочитывает пропущенное после переподключения.
+const subscriptionBuffer = 64
+
+// Subscription — поток событий одного клиента. C закрывается, когда клиента
+// отключили: при переполнении буфера, потере связи с Redis или остановке сервиса.
+type Subscription struct {
+	C <-chan models.NoteEvent
+
+	c      chan models.NoteEvent
+	userID int32
+}
+
+// Broker хранит подписки клиентов, подключенных к этой реплике
+type Broker struct {
+	history storage.OutboxStore
+
+	mu     sync.Mutex
+	subs   map[int32]map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBroker(history storage.OutboxStore) *Broker {
+	return &Broker{history: history, subs: make(map[int32]map[*Subscription]struct{})}
+}
+
+// Subscribe подписывает на события пользователя. Подписку нужно снять через
+// Unsubscribe, когда клиент отключился.
+func (b *Broker) Subscribe(userID int32) *Subscription {
+	c := make(chan models.NoteEvent, subscriptionBuffer)
+	sub := &Subscription{C: c, c: c, userID: userID}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(c)
+		return sub
+	}
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*Subscription]struct{})
+	}
+	b.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(sub)
+}
+
+// Replay возвращает события пользователя после afterID из outbox, не больше limit.
+// Подписаться нужно до Replay, иначе события между ними потеряются; события,
+// пришедшие и туда и туда, клиент получит дважды, если их не отсеять по id.
+func (b *Broker) Replay(ctx context.Context, userID int32, afterID int64, limit int) ([]models.NoteEvent, error) {
+	oldest, err := b.history.OldestNoteEventID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// id событий идут подряд, кроме номеров откаченных транзакций, поэтому
+	// изредка клиент перечитает заметки без необходимости
+	if oldest > afterID+1 {
+		return nil, ErrHistoryGap
+	}
+	if oldest == 0 {
+		// Outbox пуст: события после afterID были, если с тех пор выдавались id
+		latest, err := b.history.LatestNoteEventID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if latest > afterID {
+			return nil, ErrHistoryGap
+		}
+		return nil, nil
+	}
+
+	events, err := b.history.NoteEventsSince(ctx, userID, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > limit {
+		return nil, ErrHistoryGap
+	}
+	return events, nil
+}
+
+// Publish раздает события подписчикам этой реплики. Подходит как
+// outbox.Publisher в режимах без Redis.
+func (b *Broker) Publish(ctx context.Context, events []models.NoteEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, event := range events {
+		for sub := range b.subs[event.UserID] {
+			select {
+			case sub.c <- event:
+			default:
+				b.drop(sub)
+			}
+		}
+	}
+	return nil
+}
+
+// Reset отключает всех клиентов; после переподключения они дочитают
+// пропущенное из outbox по Last-Event-ID
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, subs := range b.subs {
+		for sub := range subs {
+			b.drop(sub)
+		}
+	}
+}
+
+// Close отключает всех клиентов и отклоняет новые подписки, чтобы
+// открытые потоки не задерживали остановку сервера
+func (b *Broker) Close() {
+	b.Reset()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+// drop вызывается под b.mu
+func (b *Broker) drop(sub *Subscription) {
+	subs := b.subs[sub.userID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subs, sub.userID)
+	}
+	close(sub.c)
+}
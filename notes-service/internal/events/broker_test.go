@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+func TestBrokerReplay(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemoryRepository()
+	broker := NewBroker(repo)
+
+	// Пустая история: клиенту с любым id нечего дочитывать
+	if events, err := broker.Replay(ctx, 1, 5, 10); err != nil || len(events) != 0 {
+		t.Fatalf("Replay of empty outbox = %+v, %v; want nothing", events, err)
+	}
+
+	now := time.Now()
+	for _, title := range []string{"first", "second", "third"} {
+		if _, err := repo.CreateNote(ctx, models.Note{Title: title, UserID: 1, CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+	}
+	events, err := broker.Replay(ctx, 1, 1, 10)
+	if err != nil || len(events) != 2 || events[0].ID != 2 || events[1].ID != 3 {
+		t.Fatalf("Replay after 1 = %+v, %v; want events 2 and 3", events, err)
+	}
+	if _, err := broker.Replay(ctx, 1, 0, 2); !errors.Is(err, ErrHistoryGap) {
+		t.Errorf("Replay over the limit: err = %v, want ErrHistoryGap", err)
+	}
+
+	// После публикации и очистки outbox пуст, но id событий уже выдавались
+	if _, err := repo.PublishOutbox(ctx, 10, func(context.Context, []models.NoteEvent) error { return nil }); err != nil {
+		t.Fatalf("PublishOutbox: %v", err)
+	}
+	if _, err := repo.PruneOutbox(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatalf("PruneOutbox: %v", err)
+	}
+	tests := []struct {
+		name    string
+		afterID int64
+		gap     bool
+	}{
+		{"stale Last-Event-ID", 1, true},
+		{"before the latest event", 2, true},
+		{"up to date", 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := broker.Replay(ctx, 1, tt.afterID, 10)
+			if tt.gap && !errors.Is(err, ErrHistoryGap) {
+				t.Errorf("Replay after %d = %+v, %v; want ErrHistoryGap", tt.afterID, events, err)
+			}
+			if !tt.gap && (err != nil || len(events) != 0) {
+				t.Errorf("Replay after %d = %+v, %v; want nothing", tt.afterID, events, err)
+			}
+		})
+	}
+}
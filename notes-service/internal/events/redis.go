@@ -0,0 +1,59 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"notes-service/internal/models"
+)
+
+// Пауза перед повтором, пока Redis недоступен
+const listenRetry = time.Second
+
+// Listen раздает события из канала Redis pub/sub, куда их публикует relay
+// (outbox.RedisPublisher), пока не отменен ctx. Сообщения, пришедшие без
+// соединения, теряются, поэтому после переподключения клиенты отключаются
+// и дочитывают пропущенное из outbox.
+func (b *Broker) Listen(ctx context.Context, rdb *redis.Client, channel string) {
+	pubsub := rdb.Subscribe(ctx, channel)
+	// Receive не следит за ctx; закрытие прерывает ожидание
+	stop := context.AfterFunc(ctx, func() { pubsub.Close() })
+	defer stop()
+
+	subscribed := false
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Error receiving note events from Redis", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(listenRetry):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if subscribed {
+				slog.InfoContext(ctx, "Resubscribed to note events, resetting streams")
+				b.Reset()
+			}
+			subscribed = true
+		case *redis.Message:
+			var event models.NoteEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				slog.WarnContext(ctx, "Malformed note event in Redis", "error", err)
+				continue
+			}
+			b.Publish(ctx, []models.NoteEvent{event})
+		}
+	}
+}
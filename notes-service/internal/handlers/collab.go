@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+
+	"common/apierror"
+	"common/audit"
+	"common/auth"
+)
+
+// Проверка Origin по умолчанию: браузер подключается только со страницы того же
+// хоста, иначе чужой сайт открыл бы сеанс с cookie пользователя
+var upgrader = websocket.Upgrader{}
+
+// NoteCollabHandler — GET /api/notes/{id}/collab: WebSocket для совместного
+// редактирования текста заметки владельцем и теми, кому он ее открыл.
+// Протокол описан в README.
+func (s *Server) NoteCollabHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	principal := auth.FromContext(r.Context())
+	noteID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid note ID"))
+		return
+	}
+	note, err := s.notes.GetSharedNote(r.Context(), int32(noteID), principal.UserID)
+	if err != nil {
+		writeStorageError(w, r, err, "Error fetching note")
+		return
+	}
+
+	// Ответ об ошибке рукопожатия Upgrade отправляет сам
+	conn, err := upgrader.Upgrade(hijacker{w}, r, nil)
+	if err != nil {
+		return
+	}
+	s.audit.Record(r, audit.Event{Action: audit.ActionNoteCollabJoin, UserID: note.UserID, TargetID: strconv.Itoa(int(note.ID))})
+	s.collab.Serve(r.Context(), conn, note, principal, s.limits.MaxBodyBytes)
+}
+
+// hijacker дает Upgrade доступ к соединению через обертки middleware,
+// которые сами Hijack не реализуют, но умеют Unwrap
+type hijacker struct {
+	http.ResponseWriter
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"common/apierror"
+	"notes-service/internal/storage"
+)
+
+const (
+	codeRequestTooLarge = "request_too_large"
+	codeNoteTooLarge    = "note_too_large"
+	codeQuotaExceeded   = "quota_exceeded"
+	codeWebhookLimit    = "webhook_limit_exceeded"
+	codeMergeConflict   = "merge_conflict"
+)
+
+// writeStorageError переводит ошибки хранилища в HTTP-статусы; все, что не
+// является известной ошибкой, логируется и отдается как 500 с message.
+func writeStorageError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	apiErr := storageAPIError(err)
+	if apiErr == nil {
+		slog.ErrorContext(r.Context(), message, "error", err)
+		apiErr = apierror.Internal(message)
+	}
+	apierror.Write(w, r, apiErr)
+}
+
+// storageAPIError — ответ на известную ошибку хранилища; nil для остальных
+func storageAPIError(err error) *apierror.Error {
+	var quota *storage.QuotaError
+	var conflict *storage.MergeConflictError
+	switch {
+	case errors.As(err, &quota):
+		return apierror.New(http.StatusForbidden, codeQuotaExceeded, "Storage quota exceeded").WithDetails(map[string]interface{}{
+			"resource": quota.Resource,
+			"limit":    quota.Limit,
+			"used":     quota.Used,
+		})
+	case errors.Is(err, storage.ErrNoteTooLarge):
+		return apierror.New(http.StatusRequestEntityTooLarge, codeNoteTooLarge, "Note exceeds the maximum size")
+	case errors.Is(err, storage.ErrNotFound):
+		return apierror.NotFound("Note not found")
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		return apierror.NotFound("Webhook not found")
+	case errors.Is(err, storage.ErrForbidden):
+		return apierror.Forbidden("Access to the note is denied")
+	// Раньше ErrVersionConflict: клиенту нужен результат слияния с маркерами
+	case errors.As(err, &conflict):
+		return apierror.New(http.StatusConflict, codeMergeConflict, "Edits conflict with changes on the server").WithDetails(map[string]interface{}{
+			"server":    conflict.Server,
+			"client":    conflict.Client,
+			"merged":    conflict.Merged,
+			"conflicts": conflict.Conflicts,
+		})
+	case errors.Is(err, storage.ErrVersionConflict):
+		return apierror.Conflict("Note was changed since the base version")
+	default:
+		return nil
+	}
+}
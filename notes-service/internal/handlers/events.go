@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"common/apierror"
+	"common/auth"
+	"notes-service/internal/events"
+	"notes-service/internal/models"
+)
+
+// Через сколько браузер переподключается после обрыва потока
+const sseRetry = 3 * time.Second
+
+// NoteEventsHandler — GET /api/notes/events: поток Server-Sent Events с событиями
+// note.created, note.updated и note.deleted пользователя. id события — id в outbox;
+// после обрыва браузер сам присылает его в Last-Event-ID, и пропущенное дочитывается.
+// Если дочитать нельзя, первым приходит событие reset: заметки нужно перечитать.
+func (s *Server) NoteEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	userID := auth.FromContext(r.Context()).UserID
+	afterID, err := lastEventID(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid Last-Event-ID"))
+		return
+	}
+
+	// Поток живет дольше таймаутов чтения и записи сервера
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.ErrorContext(r.Context(), "Event stream is not supported", "error", err)
+		apierror.Write(w, r, apierror.Internal("Event stream is not supported"))
+		return
+	}
+	rc.SetReadDeadline(time.Time{})
+
+	// Подписка раньше чтения outbox: иначе события между ними потеряются
+	sub := s.events.Subscribe(userID)
+	defer s.events.Unsubscribe(sub)
+
+	var replay []models.NoteEvent
+	reset := false
+	if afterID > 0 {
+		replay, err = s.events.Replay(r.Context(), userID, afterID, s.eventsCfg.ReplayLimit)
+		if errors.Is(err, events.ErrHistoryGap) {
+			reset = true
+		} else if err != nil {
+			writeStorageError(w, r, err, "Error fetching note events")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx не должен буферизовать поток
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	if reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+
+	// Событие может прийти и из outbox, и из подписки; отправляется один раз
+	sent := make(map[int64]bool, len(replay))
+	for _, event := range replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+		sent[event.ID] = true
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(s.eventsCfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ":\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			// Брокер отключил клиента; браузер переподключится с Last-Event-ID
+			if !ok {
+				return
+			}
+			if sent[event.ID] {
+				continue
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// lastEventID берет id из заголовка Last-Event-ID или, для первого подключения
+// EventSource, которому заголовок не задать, из параметра last_event_id
+func lastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid event id %q", value)
+	}
+	return id, nil
+}
+
+func writeEvent(w http.ResponseWriter, event models.NoteEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -1,173 +1,203 @@
-package handlers
-
-import (
-	"encoding/json"
-	"log"
-	"net/http"
-	"strconv"
-	"time"
-
-	"notes-service/internal/models"
-	"notes-service/internal/storage"
-	"notes-service/internal/tools"
-)
-
-func CreateNoteHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	
-	userID, err := tools.ExtractUserIDFromToken(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	var req models.CreateNoteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Println("Error decoding request body:", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	
-	note := models.Note{
-		Title:     req.Title,
-		Content:   req.Content,
-		UserID:    userID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	
-	id, err := storage.CreateNote(r.Context(), note)
-	if err != nil {
-		log.Println("Error creating note:", err)
-		http.Error(w, "Error creating note", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Note created successfully",
-		"note_id": id,
-	})
-}
-
-func GetNotesHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	
-	userID, err := tools.ExtractUserIDFromToken(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	
-	notes, err := storage.GetUserNotes(r.Context(), userID)
-	if err != nil {
-		log.Println("Error fetching notes:", err)
-		http.Error(w, "Error fetching notes", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"notes": notes,
-	})
-}
-
-func UpdateNoteHandler(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodPut {
-        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-        return
-    }
-
-    userID, err := tools.ExtractUserIDFromToken(r)
-    if err != nil {
-        http.Error(w, "Unauthorized", http.StatusUnauthorized)
-        return
-    }
-
-    // Получаем ID из query параметра вместо пути
-    noteIDStr := r.URL.Query().Get("id")
-    noteID, err := strconv.Atoi(noteIDStr)
-    if err != nil || noteIDStr == "" {
-        http.Error(w, "Invalid note ID", http.StatusBadRequest)
-        return
-    }
-
-    var req models.UpdateNoteRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        log.Println("Error decoding request body:", err)
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
-        return
-    }
-
-    err = storage.UpdateNote(r.Context(), int32(noteID), userID, req.Title, req.Content)
-    if err != nil {
-        log.Println("Error updating note:", err)
-        http.Error(w, "Error updating note", http.StatusInternalServerError)
-        return
-    }
-
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(map[string]string{
-        "message": "Note updated successfully",
-    })
-}
-
-func DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodDelete {
-        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-        return
-    }
-
-    userID, err := tools.ExtractUserIDFromToken(r)
-    if err != nil {
-        http.Error(w, "Unauthorized", http.StatusUnauthorized)
-        return
-    }
-
-    // Получаем ID из query параметра вместо пути
-    noteIDStr := r.URL.Query().Get("id")
-    noteID, err := strconv.Atoi(noteIDStr)
-    if err != nil || noteIDStr == "" {
-        http.Error(w, "Invalid note ID", http.StatusBadRequest)
-        return
-    }
-
-    err = storage.DeleteNote(r.Context(), int32(noteID), userID)
-    if err != nil {
-        log.Println("Error deleting note:", err)
-        http.Error(w, "Error deleting note", http.StatusInternalServerError)
-        return
-    }
-
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(map[string]string{
-        "message": "Note deleted successfully",
-    })
-}
-
-func HealthHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
-
-func NoteDetailHandler(w http.ResponseWriter, r *http.Request) {
-    switch r.Method {
-    case http.MethodPut:
-        UpdateNoteHandler(w, r)
-    case http.MethodDelete:
-        DeleteNoteHandler(w, r)
-    default:
-        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-    }
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"notes-service/internal/models"
+	"common/apierror"
+	"common/auth"
+)
+
+func (s *Server) CreateNoteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	
+	userID := auth.FromContext(r.Context()).UserID
+
+	var req models.CreateNoteRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+
+	
+	note := models.Note{
+		Title:     req.Title,
+		Content:   req.Content,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	
+	id, err := s.notes.CreateNote(r.Context(), note)
+	if err != nil {
+		writeStorageError(w, r, err, "Error creating note")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Note created successfully",
+		"note_id": id,
+	})
+}
+
+func (s *Server) GetNotesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	
+	userID := auth.FromContext(r.Context()).UserID
+
+	
+	notes, err := s.notes.GetUserNotes(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error fetching notes", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error fetching notes"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"notes": notes,
+	})
+}
+
+func (s *Server) SearchNotesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	userID := auth.FromContext(r.Context()).UserID
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		apierror.Write(w, r, apierror.BadRequest("Query parameter q is required"))
+		return
+	}
+
+	notes, err := s.notes.SearchNotes(r.Context(), userID, query)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error searching notes", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error searching notes"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"notes": notes,
+	})
+}
+
+// UsageHandler сообщает, сколько места занимает пользователь и каковы его лимиты
+func (s *Server) UsageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	userID := auth.FromContext(r.Context()).UserID
+
+	usage, err := s.notes.GetUsage(r.Context(), userID)
+	if err != nil {
+		writeStorageError(w, r, err, "Error fetching usage")
+		return
+	}
+
+	// 0 в limits означает отсутствие ограничения
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"usage": usage,
+		"limits": map[string]int64{
+			"max_notes":       int64(s.limits.MaxNotes),
+			"max_total_bytes": s.limits.MaxTotalBytes,
+			"max_note_bytes":  s.limits.MaxNoteBytes,
+			"max_body_bytes":  s.limits.MaxBodyBytes,
+		},
+	})
+}
+
+func (s *Server) UpdateNoteHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPut {
+        apierror.Write(w, r, apierror.MethodNotAllowed())
+        return
+    }
+
+    userID := auth.FromContext(r.Context()).UserID
+
+    // Получаем ID из query параметра вместо пути
+    noteIDStr := r.URL.Query().Get("id")
+    noteID, err := strconv.Atoi(noteIDStr)
+    if err != nil || noteIDStr == "" {
+        apierror.Write(w, r, apierror.BadRequest("Invalid note ID"))
+        return
+    }
+
+    var req models.UpdateNoteRequest
+    if !s.decodeBody(w, r, &req) {
+        return
+    }
+
+    note, err := s.notes.UpdateNote(r.Context(), int32(noteID), userID, req.Title, req.Content, req.BaseVersion)
+    if err != nil {
+        writeStorageError(w, r, err, "Error updating note")
+        return
+    }
+
+    // merged — правка слита с чужими изменениями, и текст отличается от отправленного
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "message": "Note updated successfully",
+        "note":    note,
+        "merged":  note.Title != req.Title || note.Content != req.Content,
+    })
+}
+
+func (s *Server) DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodDelete {
+        apierror.Write(w, r, apierror.MethodNotAllowed())
+        return
+    }
+
+    userID := auth.FromContext(r.Context()).UserID
+
+    // Получаем ID из query параметра вместо пути
+    noteIDStr := r.URL.Query().Get("id")
+    noteID, err := strconv.Atoi(noteIDStr)
+    if err != nil || noteIDStr == "" {
+        apierror.Write(w, r, apierror.BadRequest("Invalid note ID"))
+        return
+    }
+
+    err = s.notes.DeleteNote(r.Context(), int32(noteID), userID, 0)
+    if err != nil {
+        writeStorageError(w, r, err, "Error deleting note")
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(map[string]string{
+        "message": "Note deleted successfully",
+    })
+}
+
+func (s *Server) NoteDetailHandler(w http.ResponseWriter, r *http.Request) {
+    switch r.Method {
+    case http.MethodPut:
+        s.UpdateNoteHandler(w, r)
+    case http.MethodDelete:
+        s.DeleteNoteHandler(w, r)
+    default:
+        apierror.Write(w, r, apierror.MethodNotAllowed())
+    }
 }
\ No newline at end of file
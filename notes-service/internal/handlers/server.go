@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"common/apierror"
+	"common/audit"
+	"common/auth"
+	"common/config"
+	"common/health"
+	"common/metrics"
+	"common/ratelimit"
+	"common/route"
+	"notes-service/internal/collab"
+	notesconfig "notes-service/internal/config"
+	"notes-service/internal/events"
+	"notes-service/internal/storage"
+)
+
+// rateLimits — встроенные лимиты маршрутов; остальные получают RATE_LIMIT_DEFAULT
+var rateLimits = map[string]config.Rate{
+	"/api/notes":        {Requests: 60, Window: time.Minute},
+	"/api/notes/search": {Requests: 120, Window: time.Minute},
+	// Каждый ping — исходящий запрос на адрес пользователя
+	"/api/webhooks/{id}/ping": {Requests: 10, Window: time.Minute},
+	"/healthz":                {},
+	"/readyz":                 {},
+	"/health":                 {},
+	"/metrics":                {},
+}
+
+// Server держит зависимости обработчиков; хранилище передается снаружи,
+// поэтому обработчики можно проверять на MemoryRepository без Postgres.
+type Server struct {
+	notes   storage.NoteRepository
+	health  *health.Checks
+	authn   *auth.Authenticator
+	limiter *ratelimit.Limiter
+	audit   *audit.Recorder
+	limits  notesconfig.Limits
+
+	hooks    storage.WebhookStore
+	hooksCfg notesconfig.Webhooks
+
+	events    *events.Broker
+	eventsCfg notesconfig.Events
+
+	collab *collab.Hub
+
+	syncCfg notesconfig.Sync
+}
+
+func NewServer(notes storage.NoteRepository, hooks storage.WebhookStore, broker *events.Broker, hub *collab.Hub, checks *health.Checks, authn *auth.Authenticator, limiter *ratelimit.Limiter, recorder *audit.Recorder, cfg *notesconfig.Config) *Server {
+	return &Server{
+		notes:     notes,
+		health:    checks,
+		authn:     authn,
+		limiter:   limiter,
+		audit:     recorder,
+		limits:    cfg.Limits,
+		hooks:     hooks,
+		hooksCfg:  cfg.Webhooks,
+		events:    broker,
+		eventsCfg: cfg.Events,
+		collab:    hub,
+		syncCfg:   cfg.Sync,
+	}
+}
+
+// NewLimiter создает ограничитель частоты запросов с лимитами notes-service:
+// запросы с токеном считаются по id пользователя, остальные — по IP.
+func NewLimiter(store ratelimit.Store, cfg config.RateLimit) (*ratelimit.Limiter, error) {
+	return ratelimit.New(store, cfg, rateLimits, auth.RateLimitKey)
+}
+
+func (s *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/notes", auth.RequireScope(auth.ScopeNotesWrite, s.CreateNoteHandler))
+	mux.HandleFunc("/api/notes/list", auth.RequireScope(auth.ScopeNotesRead, s.GetNotesHandler))
+	mux.HandleFunc("/api/notes/search", auth.RequireScope(auth.ScopeNotesRead, s.SearchNotesHandler))
+	mux.HandleFunc("/api/notes/usage", auth.RequireScope(auth.ScopeNotesRead, s.UsageHandler))
+	mux.HandleFunc("/api/notes/events", auth.RequireScope(auth.ScopeNotesRead, s.NoteEventsHandler))
+	// Права проверяются по методу: GET читает, POST еще и меняет заметки
+	mux.HandleFunc("/api/notes/sync", s.NoteSyncHandler)
+	mux.HandleFunc("/api/notes/{id}/collab", auth.RequireScope(auth.ScopeNotesWrite, s.NoteCollabHandler))
+	mux.HandleFunc("/api/notes/{id}/shares", auth.RequireScope(auth.ScopeNotesWrite, s.NoteSharesHandler))
+	mux.HandleFunc("/api/notes/{id}/shares/{user_id}", auth.RequireScope(auth.ScopeNotesWrite, s.NoteShareHandler))
+	mux.HandleFunc("/api/notes/", auth.RequireScope(auth.ScopeNotesWrite, s.NoteDetailHandler))
+	mux.HandleFunc("/healthz", s.health.Liveness)
+	mux.HandleFunc("/readyz", s.health.Readiness)
+	// Старый адрес оставлен для совместимости с существующими проверками
+	mux.HandleFunc("/health", s.health.Liveness)
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/api/notes/update", auth.RequireScope(auth.ScopeNotesWrite, s.UpdateNoteHandler))
+	mux.HandleFunc("/api/notes/delete", auth.RequireScope(auth.ScopeNotesWrite, s.DeleteNoteHandler))
+	// Вебхук получает тексты заметок и сам меняет настройки, поэтому нужен notes:write
+	mux.HandleFunc("/api/webhooks", auth.RequireScope(auth.ScopeNotesWrite, s.WebhooksHandler))
+	mux.HandleFunc("/api/webhooks/{id}", auth.RequireScope(auth.ScopeNotesWrite, s.WebhookHandler))
+	mux.HandleFunc("/api/webhooks/{id}/deliveries", auth.RequireScope(auth.ScopeNotesWrite, s.WebhookDeliveriesHandler))
+	mux.HandleFunc("/api/webhooks/{id}/ping", auth.RequireScope(auth.ScopeNotesWrite, s.WebhookPingHandler))
+	// Пользователь нужен ограничителю частоты, поэтому токен проверяется раньше.
+	// Ограничитель передает mux тот же запрос, так что маршрут виден route.Record.
+	// IP и User-Agent в контексте нужны журналу аудита, который пишет хранилище
+	return s.authn.Middleware(s.audit.Middleware(route.Record(s.limiter.Middleware(mux))))
+}
+
+// decodeBody читает JSON-тело не длиннее NOTES_MAX_BODY_BYTES. При ошибке ответ
+// уже отправлен (413 или 400), и обработчик должен просто вернуться.
+func (s *Server) decodeBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if s.limits.MaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)
+	}
+
+	err := json.NewDecoder(r.Body).Decode(dst)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &tooLarge):
+		apierror.Write(w, r, apierror.New(http.StatusRequestEntityTooLarge, codeRequestTooLarge, "Request body is too large").WithDetails(map[string]int64{
+			"max_body_bytes": tooLarge.Limit,
+		}))
+	default:
+		slog.WarnContext(r.Context(), "Error decoding request body", "error", err)
+		apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+	}
+	return false
+}
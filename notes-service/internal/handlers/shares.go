@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"common/apierror"
+	"common/auth"
+)
+
+// NoteSharesHandler — GET /api/notes/{id}/shares: кому владелец открыл заметку
+func (s *Server) NoteSharesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	noteID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid note ID"))
+		return
+	}
+	users, err := s.notes.GetNoteShares(r.Context(), int32(noteID), auth.FromContext(r.Context()).UserID)
+	if err != nil {
+		writeStorageError(w, r, err, "Error fetching note shares")
+		return
+	}
+	if users == nil {
+		users = []int32{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_ids": users,
+	})
+}
+
+// NoteShareHandler — PUT|DELETE /api/notes/{id}/shares/{user_id}: открыть
+// пользователю заметку для совместного редактирования или закрыть ее
+func (s *Server) NoteShareHandler(w http.ResponseWriter, r *http.Request) {
+	noteID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid note ID"))
+		return
+	}
+	userID, err := strconv.ParseInt(r.PathValue("user_id"), 10, 32)
+	if err != nil || userID <= 0 {
+		apierror.Write(w, r, apierror.BadRequest("Invalid user ID"))
+		return
+	}
+	ownerID := auth.FromContext(r.Context()).UserID
+	if int32(userID) == ownerID {
+		apierror.Write(w, r, apierror.BadRequest("Cannot share a note with yourself"))
+		return
+	}
+
+	var message string
+	switch r.Method {
+	case http.MethodPut:
+		err = s.notes.ShareNote(r.Context(), int32(noteID), ownerID, int32(userID))
+		message = "Note shared successfully"
+	case http.MethodDelete:
+		err = s.notes.UnshareNote(r.Context(), int32(noteID), ownerID, int32(userID))
+		message = "Note unshared successfully"
+	default:
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "Error changing note shares")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": message,
+	})
+}
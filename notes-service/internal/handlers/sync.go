@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"common/apierror"
+	"common/audit"
+	"common/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+// syncResult — итог одного изменения клиента. При конфликте Server — текущая
+// заметка, Client — отклоненное изменение, Merged — слияние с маркерами;
+// Deleted — заметку удалили. Повтор уже примененного создания тоже
+// возвращает Server — заметку, созданную в первый раз.
+type syncResult struct {
+	Index    int                       `json:"index"`
+	Status   string                    `json:"status"`
+	ID       int32                     `json:"id,omitempty"`
+	ClientID string                    `json:"client_id,omitempty"`
+	Version  int64                     `json:"version,omitempty"`
+	Server   *models.Note              `json:"server,omitempty"`
+	Client   *models.SyncMutation      `json:"client,omitempty"`
+	Merged   *models.UpdateNoteRequest `json:"merged,omitempty"`
+	Deleted  bool                      `json:"deleted,omitempty"`
+	Error    *apierror.Error           `json:"error,omitempty"`
+}
+
+// Итог изменения клиента; merged — применено после слияния с изменениями
+// сервера, и текст заметки отличается от присланного
+const (
+	syncApplied  = "applied"
+	syncMerged   = "merged"
+	syncConflict = "conflict"
+	syncError    = "error"
+)
+
+// NoteSyncHandler — /api/notes/sync для клиентов, работающих без связи.
+// GET ?sync_token= отдает изменения после курсора; POST сначала применяет
+// изменения клиента, затем отдает то же самое. Читать можно с notes:read.
+func (s *Server) NoteSyncHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.RequireScope(auth.ScopeNotesRead, s.pullNotes)(w, r)
+	case http.MethodPost:
+		auth.RequireScope(auth.ScopeNotesWrite, s.pushNotes)(w, r)
+	default:
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+	}
+}
+
+func (s *Server) pullNotes(w http.ResponseWriter, r *http.Request) {
+	afterSeq, ok := parseSyncToken(r.URL.Query().Get("sync_token"))
+	if !ok {
+		apierror.Write(w, r, apierror.BadRequest("Invalid sync token"))
+		return
+	}
+	s.writeNoteChanges(w, r, afterSeq, nil)
+}
+
+func (s *Server) pushNotes(w http.ResponseWriter, r *http.Request) {
+	var req models.SyncRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	afterSeq, ok := parseSyncToken(req.SyncToken)
+	if !ok {
+		apierror.Write(w, r, apierror.BadRequest("Invalid sync token"))
+		return
+	}
+	if len(req.Mutations) > s.syncCfg.MaxMutations {
+		apierror.Write(w, r, apierror.BadRequest("At most "+strconv.Itoa(s.syncCfg.MaxMutations)+" mutations per request"))
+		return
+	}
+	// Проверка до применения: неверный запрос не меняет ничего
+	for i, m := range req.Mutations {
+		if msg := validateMutation(m); msg != "" {
+			apierror.Write(w, r, apierror.BadRequest(fmt.Sprintf("Mutation %d: %s", i, msg)))
+			return
+		}
+	}
+
+	results := make([]syncResult, len(req.Mutations))
+	for i, m := range req.Mutations {
+		results[i] = s.applyMutation(r, i, m)
+	}
+	s.writeNoteChanges(w, r, afterSeq, results)
+}
+
+// parseSyncToken читает курсор; пустой — первая синхронизация
+func parseSyncToken(token string) (int64, bool) {
+	if token == "" {
+		return -1, true
+	}
+	seq, err := strconv.ParseInt(token, 10, 64)
+	return seq, err == nil && seq >= 0
+}
+
+// maxClientIDLength — длина client_id, которую хранит note_client_ids
+const maxClientIDLength = 128
+
+func validateMutation(m models.SyncMutation) string {
+	switch m.Op {
+	case models.SyncCreate:
+		if len(m.ClientID) > maxClientIDLength {
+			return "client_id is too long"
+		}
+		return ""
+	case models.SyncUpdate, models.SyncDelete:
+		if m.ID <= 0 {
+			return "id is required"
+		}
+		if m.BaseVersion <= 0 {
+			return "base_version is required"
+		}
+		return ""
+	default:
+		return "unknown op " + strconv.Quote(m.Op)
+	}
+}
+
+// applyMutation применяет одно изменение клиента. Изменения независимы:
+// конфликт или ошибка одного не отменяет остальные.
+func (s *Server) applyMutation(r *http.Request, index int, m models.SyncMutation) syncResult {
+	// Журнал аудита отличает изменения синхронизации от обычных запросов
+	ctx := audit.WithDetail(r.Context(), "sync")
+	userID := auth.FromContext(ctx).UserID
+	result := syncResult{Index: index, ID: m.ID, ClientID: m.ClientID}
+
+	var err error
+	switch m.Op {
+	case models.SyncCreate:
+		now := time.Now()
+		note := models.Note{Title: m.Title, Content: m.Content, UserID: userID, CreatedAt: now, UpdatedAt: now, ClientID: m.ClientID}
+		result.ID, err = s.notes.CreateNote(ctx, note)
+		var duplicate *storage.DuplicateNoteError
+		if errors.As(err, &duplicate) {
+			return s.replayCreate(ctx, result, duplicate.NoteID, userID)
+		}
+		if err == nil {
+			result.Version = 1
+		}
+	case models.SyncUpdate:
+		var note *models.Note
+		if note, err = s.notes.UpdateNote(ctx, m.ID, userID, m.Title, m.Content, m.BaseVersion); err == nil {
+			result.Version = note.Version
+			if note.Title != m.Title || note.Content != m.Content {
+				result.Status = syncMerged
+				result.Server = note
+			}
+		}
+	case models.SyncDelete:
+		err = s.notes.DeleteNote(ctx, m.ID, userID, m.BaseVersion)
+		// Заметку уже удалили: клиент добился своего
+		if errors.Is(err, storage.ErrNotFound) {
+			return syncResult{Index: index, Status: syncApplied, ID: m.ID}
+		}
+	}
+
+	var mergeErr *storage.MergeConflictError
+	switch {
+	case err == nil:
+		if result.Status == "" {
+			result.Status = syncApplied
+		}
+	case errors.As(err, &mergeErr):
+		result.Status = syncConflict
+		result.Version = mergeErr.Server.Version
+		result.Server = mergeErr.Server
+		result.Client = &m
+		result.Merged = &mergeErr.Merged
+	// Правка удаленной заметки — тоже конфликт: клиент решает, создать ли ее заново
+	case errors.Is(err, storage.ErrVersionConflict), m.Op == models.SyncUpdate && errors.Is(err, storage.ErrNotFound):
+		result.Status = syncConflict
+		result.Client = &m
+		note, getErr := s.notes.GetNoteByID(ctx, m.ID, userID)
+		switch {
+		case getErr == nil:
+			result.Version = note.Version
+			result.Server = note
+		case errors.Is(getErr, storage.ErrNotFound):
+			result.Deleted = true
+		default:
+			slog.ErrorContext(ctx, "Error fetching conflicting note", "note_id", m.ID, "error", getErr)
+		}
+	default:
+		result.Status = syncError
+		result.Error = storageAPIError(err)
+		if result.Error == nil {
+			slog.ErrorContext(ctx, "Error applying sync mutation", "op", m.Op, "note_id", m.ID, "error", err)
+			result.Error = apierror.Internal("Error applying mutation")
+		}
+	}
+	return result
+}
+
+// replayCreate отвечает на повтор создания, которое уже применено (например,
+// клиент не дождался ответа): клиент получает ту же заметку в ее нынешнем виде
+func (s *Server) replayCreate(ctx context.Context, result syncResult, noteID, userID int32) syncResult {
+	result.ID, result.Status = noteID, syncApplied
+	note, err := s.notes.GetNoteByID(ctx, noteID, userID)
+	switch {
+	case err == nil:
+		result.Version = note.Version
+		result.Server = note
+	case errors.Is(err, storage.ErrNotFound):
+		result.Deleted = true
+	default:
+		slog.ErrorContext(ctx, "Error fetching replayed note", "note_id", noteID, "error", err)
+	}
+	return result
+}
+
+// writeNoteChanges отдает изменения после курсора; собственные изменения
+// клиента из этого запроса тоже в них попадают
+func (s *Server) writeNoteChanges(w http.ResponseWriter, r *http.Request, afterSeq int64, results []syncResult) {
+	changes, err := s.notes.NoteChanges(r.Context(), auth.FromContext(r.Context()).UserID, afterSeq)
+	if err != nil {
+		writeStorageError(w, r, err, "Error fetching note changes")
+		return
+	}
+
+	if changes.Notes == nil {
+		changes.Notes = []models.Note{}
+	}
+	if changes.Deleted == nil {
+		changes.Deleted = []models.Tombstone{}
+	}
+	response := map[string]interface{}{
+		"sync_token": strconv.FormatInt(changes.Seq, 10),
+		"reset":      changes.Reset,
+		"notes":      changes.Notes,
+		"deleted":    changes.Deleted,
+	}
+	if results != nil {
+		response["results"] = results
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"common/apierror"
+	"common/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/webhooks"
+)
+
+const (
+	maxWebhookURLLength = 2048
+	defaultDeliveries   = 50
+	maxDeliveries       = 200
+)
+
+func (s *Server) WebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listWebhooks(w, r)
+	case http.MethodPost:
+		s.createWebhook(w, r)
+	default:
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+	}
+}
+
+func (s *Server) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := s.hooks.GetUserWebhooks(r.Context(), auth.FromContext(r.Context()).UserID)
+	if err != nil {
+		writeStorageError(w, r, err, "Error fetching webhooks")
+		return
+	}
+	if hooks == nil {
+		hooks = []models.Webhook{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhooks": hooks,
+	})
+}
+
+// createWebhook создает вебхук; ключ подписи возвращается только в этом ответе
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := auth.FromContext(r.Context()).UserID
+
+	var req models.CreateWebhookRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+
+	if len(req.URL) > maxWebhookURLLength {
+		apierror.Write(w, r, apierror.BadRequest("Webhook url is too long"))
+		return
+	}
+	if err := webhooks.ValidateURL(req.URL, s.hooksCfg.AllowPrivate); err != nil {
+		apierror.Write(w, r, apierror.BadRequest(err.Error()))
+		return
+	}
+	// Без списка событий вебхук подписывается на все
+	events := models.WebhookEvents
+	if len(req.Events) > 0 {
+		events = nil
+		for _, event := range req.Events {
+			if !slices.Contains(models.WebhookEvents, event) {
+				apierror.Write(w, r, apierror.BadRequest("Unknown webhook event "+strconv.Quote(event)))
+				return
+			}
+			if !slices.Contains(events, event) {
+				events = append(events, event)
+			}
+		}
+	}
+
+	if s.hooksCfg.MaxPerUser > 0 {
+		existing, err := s.hooks.GetUserWebhooks(r.Context(), userID)
+		if err != nil {
+			writeStorageError(w, r, err, "Error creating webhook")
+			return
+		}
+		if len(existing) >= s.hooksCfg.MaxPerUser {
+			apierror.Write(w, r, apierror.New(http.StatusForbidden, codeWebhookLimit, "Webhook limit reached").WithDetails(map[string]int{
+				"limit": s.hooksCfg.MaxPerUser,
+			}))
+			return
+		}
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error creating webhook", "error", err)
+		apierror.Write(w, r, apierror.Internal("Error creating webhook"))
+		return
+	}
+
+	hook, err := s.hooks.CreateWebhook(r.Context(), models.Webhook{
+		UserID: userID,
+		URL:    req.URL,
+		Events: events,
+		Secret: secret,
+	})
+	if err != nil {
+		writeStorageError(w, r, err, "Error creating webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+// WebhookHandler: GET возвращает вебхук без ключа, DELETE удаляет его вместе с журналом доставок
+func (s *Server) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	hook, ok := s.userWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		hook.Secret = ""
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hook)
+	case http.MethodDelete:
+		if err := s.hooks.DeleteWebhook(r.Context(), hook.ID, hook.UserID); err != nil {
+			writeStorageError(w, r, err, "Error deleting webhook")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Webhook deleted successfully",
+		})
+	default:
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+	}
+}
+
+// WebhookDeliveriesHandler отдает журнал последних доставок вебхука
+func (s *Server) WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	limit := defaultDeliveries
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDeliveries {
+			apierror.Write(w, r, apierror.BadRequest("limit must be between 1 and "+strconv.Itoa(maxDeliveries)))
+			return
+		}
+		limit = n
+	}
+
+	hook, ok := s.userWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := s.hooks.GetDeliveries(r.Context(), hook.ID, limit)
+	if err != nil {
+		writeStorageError(w, r, err, "Error fetching webhook deliveries")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deliveries": deliveries,
+	})
+}
+
+// WebhookPingHandler ставит в очередь проверочную доставку события ping
+func (s *Server) WebhookPingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Write(w, r, apierror.MethodNotAllowed())
+		return
+	}
+
+	hook, ok := s.userWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	payload := webhooks.Payload(models.WebhookEventPing, map[string]int32{"webhook_id": hook.ID})
+	id, err := s.hooks.EnqueueDelivery(r.Context(), hook.ID, models.WebhookEventPing, payload)
+	if err != nil {
+		writeStorageError(w, r, err, "Error sending ping")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Ping queued",
+		"delivery_id": id,
+	})
+}
+
+// userWebhook находит вебхук из пути запроса среди вебхуков пользователя.
+// При ошибке ответ уже отправлен.
+func (s *Server) userWebhook(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid webhook ID"))
+		return nil, false
+	}
+
+	hook, err := s.hooks.GetWebhook(r.Context(), int32(id), auth.FromContext(r.Context()).UserID)
+	if err != nil {
+		writeStorageError(w, r, err, "Error fetching webhook")
+		return nil, false
+	}
+	return hook, true
+}
@@ -0,0 +1,199 @@
+// Package merge — трехстороннее слияние текста по строкам (diff3): правки
+// сервера и клиента относительно общей базовой версии объединяются, если
+// затрагивают разные строки.
+package merge
+
+import "strings"
+
+// Маркеры конфликта в стиле git: сначала вариант сервера, затем клиента
+const (
+	markerServer = "<<<<<<< server\n"
+	markerSep    = "=======\n"
+	markerClient = ">>>>>>> client\n"
+)
+
+// Дальше этого числа правок строки не сопоставляются: середина считается
+// замененной целиком. Ограничивает время и память на совсем разных текстах.
+const maxEdits = 1000
+
+// Merge сливает изменения server и client относительно base. Возвращает
+// результат и число конфликтов; каждый конфликт в результате обрамлен
+// маркерами и содержит оба варианта.
+func Merge(base, server, client string) (string, int) {
+	o, a, b := splitLines(base), splitLines(server), splitLines(client)
+	ma, mb := match(o, a), match(o, b)
+
+	var out strings.Builder
+	conflicts := 0
+	io, ia, ib := 0, 0, 0
+	for io < len(o) || ia < len(a) || ib < len(b) {
+		// Строки базы, оставшиеся на месте в обоих вариантах
+		k := 0
+		for io+k < len(o) && ma[io+k] == ia+k && mb[io+k] == ib+k {
+			k++
+		}
+		if k > 0 {
+			writeLines(&out, o[io:io+k])
+			io, ia, ib = io+k, ia+k, ib+k
+			continue
+		}
+
+		// Участок до следующей строки базы, сохраненной в обоих вариантах
+		no, na, nb := io, len(a), len(b)
+		for no < len(o) && (ma[no] < 0 || mb[no] < 0) {
+			no++
+		}
+		if no < len(o) {
+			na, nb = ma[no], mb[no]
+		}
+		chunkO, chunkA, chunkB := o[io:no], a[ia:na], b[ib:nb]
+		switch {
+		case equal(chunkA, chunkO):
+			writeLines(&out, chunkB)
+		case equal(chunkB, chunkO), equal(chunkA, chunkB):
+			writeLines(&out, chunkA)
+		default:
+			conflicts++
+			out.WriteString(markerServer)
+			writeBlock(&out, chunkA)
+			out.WriteString(markerSep)
+			writeBlock(&out, chunkB)
+			out.WriteString(markerClient)
+		}
+		io, ia, ib = no, na, nb
+	}
+	return out.String(), conflicts
+}
+
+// splitLines делит текст на строки вместе с переводом строки в конце каждой
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+}
+
+// writeBlock пишет вариант внутри маркеров; маркер всегда начинается с новой строки
+func writeBlock(out *strings.Builder, lines []string) {
+	writeLines(out, lines)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		out.WriteByte('\n')
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// match сопоставляет строки base со строками other по наибольшей общей
+// подпоследовательности: m[i] — номер строки в other или -1
+func match(base, other []string) []int {
+	m := make([]int, len(base))
+	for i := range m {
+		m[i] = -1
+	}
+
+	prefix := 0
+	for prefix < len(base) && prefix < len(other) && base[prefix] == other[prefix] {
+		m[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(base)-prefix && suffix < len(other)-prefix && base[len(base)-1-suffix] == other[len(other)-1-suffix] {
+		m[len(base)-1-suffix] = len(other) - 1 - suffix
+		suffix++
+	}
+
+	for _, p := range myers(base[prefix:len(base)-suffix], other[prefix:len(other)-suffix]) {
+		m[prefix+p[0]] = prefix + p[1]
+	}
+	return m
+}
+
+// myers возвращает пары совпадающих строк кратчайшего редакционного
+// предписания (алгоритм Майерса) в порядке возрастания
+func myers(a, b []string) [][2]int {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return nil
+	}
+
+	limit := min(n+m, maxEdits)
+	// v[k+limit] — самый дальний x на диагонали k; trace[d] — v после шага d
+	// для диагоналей -d..d
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[k-1+limit] < v[k+1+limit] {
+				x = v[k+1+limit]
+			} else {
+				x = v[k-1+limit] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+limit] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+		trace = append(trace, append([]int(nil), v[limit-d:limit+d+1]...))
+	}
+	if !found {
+		return nil
+	}
+
+	var pairs [][2]int
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			pairs = append(pairs, [2]int{x, y})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		pairs = append(pairs, [2]int{x, y})
+	}
+
+	for i, j := 0, len(pairs)-1; i < j; i, j = i+1, j-1 {
+		pairs[i], pairs[j] = pairs[j], pairs[i]
+	}
+	return pairs
+}
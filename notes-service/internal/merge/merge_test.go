@@ -0,0 +1,188 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name                 string
+		base, server, client string
+		want                 string
+		conflicts            int
+	}{
+		{
+			name:   "edits of different lines",
+			base:   "one\ntwo\nthree\n",
+			server: "ONE\ntwo\nthree\n",
+			client: "one\ntwo\nTHREE\n",
+			want:   "ONE\ntwo\nTHREE\n",
+		},
+		{
+			// Строки сопоставляются целиком: правки разных мест одной строки — конфликт
+			name:      "edits of different words in one line",
+			base:      "the quick fox\n",
+			server:    "the slow fox\n",
+			client:    "the quick dog\n",
+			want:      "<<<<<<< server\nthe slow fox\n=======\nthe quick dog\n>>>>>>> client\n",
+			conflicts: 1,
+		},
+		{
+			// Как в diff3: соседние строки без общей строки между ними — один участок
+			name:      "edits of adjacent lines",
+			base:      "one\ntwo\n",
+			server:    "ONE\ntwo\n",
+			client:    "one\nTWO\n",
+			want:      "<<<<<<< server\nONE\ntwo\n=======\none\nTWO\n>>>>>>> client\n",
+			conflicts: 1,
+		},
+		{
+			name:      "inserts at the same position",
+			base:      "one\nthree\n",
+			server:    "one\ntwo (server)\nthree\n",
+			client:    "one\ntwo (client)\nthree\n",
+			want:      "one\n<<<<<<< server\ntwo (server)\n=======\ntwo (client)\n>>>>>>> client\nthree\n",
+			conflicts: 1,
+		},
+		{
+			name:   "inserts at different positions",
+			base:   "one\ntwo\nthree\n",
+			server: "zero\none\ntwo\nthree\n",
+			client: "one\ntwo\nthree\nfour\n",
+			want:   "zero\none\ntwo\nthree\nfour\n",
+		},
+		{
+			name:      "delete against edit",
+			base:      "one\ntwo\nthree\n",
+			server:    "one\nthree\n",
+			client:    "one\nTWO\nthree\n",
+			want:      "one\n<<<<<<< server\n=======\nTWO\n>>>>>>> client\nthree\n",
+			conflicts: 1,
+		},
+		{
+			name:   "delete against edit elsewhere",
+			base:   "one\ntwo\nthree\nfour\n",
+			server: "two\nthree\nfour\n",
+			client: "one\ntwo\nthree\nFOUR\n",
+			want:   "two\nthree\nFOUR\n",
+		},
+		{
+			name:   "identical changes on both sides",
+			base:   "one\ntwo\n",
+			server: "one\n2\nthree\n",
+			client: "one\n2\nthree\n",
+			want:   "one\n2\nthree\n",
+		},
+		{
+			name:   "last line without newline",
+			base:   "one\ntwo\nthree",
+			server: "ONE\ntwo\nthree",
+			client: "one\ntwo\nthree!",
+			want:   "ONE\ntwo\nthree!",
+		},
+		{
+			// Маркер после варианта без перевода строки начинается с новой строки
+			name:      "conflict in last line without newline",
+			base:      "one\ntwo",
+			server:    "one\nserver",
+			client:    "one\nclient",
+			want:      "one\n<<<<<<< server\nserver\n=======\nclient\n>>>>>>> client\n",
+			conflicts: 1,
+		},
+		{
+			name:   "only one side changed",
+			base:   "one\n",
+			server: "one\n",
+			client: "",
+			want:   "",
+		},
+		{
+			name:   "empty base",
+			base:   "",
+			server: "",
+			client: "new\n",
+			want:   "new\n",
+		},
+		{
+			name:      "two separate conflicts",
+			base:      "a\nb\nc\n",
+			server:    "A1\nb\nC1\n",
+			client:    "A2\nb\nC2\n",
+			want:      "<<<<<<< server\nA1\n=======\nA2\n>>>>>>> client\nb\n<<<<<<< server\nC1\n=======\nC2\n>>>>>>> client\n",
+			conflicts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := Merge(tt.base, tt.server, tt.client)
+			if got != tt.want || conflicts != tt.conflicts {
+				t.Errorf("Merge = %q, %d conflicts; want %q, %d", got, conflicts, tt.want, tt.conflicts)
+			}
+		})
+	}
+}
+
+// Заголовок и текст сливаются по отдельности (как в storage.MergeRepository):
+// конфликт в одном поле не задевает другое
+func TestMergeTitleAndContent(t *testing.T) {
+	title, titleConflicts := Merge("Plan", "Plan (server)", "Plan (client)")
+	if want := "<<<<<<< server\nPlan (server)\n=======\nPlan (client)\n>>>>>>> client\n"; title != want || titleConflicts != 1 {
+		t.Errorf("title = %q, %d conflicts; want %q, 1", title, titleConflicts, want)
+	}
+	content, contentConflicts := Merge("one\ntwo\n", "one\ntwo\nthree\n", "zero\none\ntwo\n")
+	if want := "zero\none\ntwo\nthree\n"; content != want || contentConflicts != 0 {
+		t.Errorf("content = %q, %d conflicts; want %q, 0", content, contentConflicts, want)
+	}
+
+	title, titleConflicts = Merge("Plan", "Plan", "Plan v2")
+	if title != "Plan v2" || titleConflicts != 0 {
+		t.Errorf("title = %q, %d conflicts; want \"Plan v2\", 0", title, titleConflicts)
+	}
+	content, contentConflicts = Merge("x\n", "server\n", "client\n")
+	if want := "<<<<<<< server\nserver\n=======\nclient\n>>>>>>> client\n"; content != want || contentConflicts != 1 {
+		t.Errorf("content = %q, %d conflicts; want %q, 1", content, contentConflicts, want)
+	}
+}
+
+// Больше maxEdits правок строки не сопоставляются: середина считается
+// замененной целиком, и даже далекие правки конфликтуют
+func TestMergeTooManyEdits(t *testing.T) {
+	const n = maxEdits + 200
+	var base, server, client strings.Builder
+	for i := range n {
+		line := fmt.Sprintf("line %d\n", i)
+		base.WriteString(line)
+		// Сервер меняет каждую нечетную строку: правок больше maxEdits
+		if i%2 == 1 {
+			server.WriteString(fmt.Sprintf("changed %d\n", i))
+		} else {
+			server.WriteString(line)
+		}
+		if i == n-1 {
+			client.WriteString("client end\n")
+		} else {
+			client.WriteString(line)
+		}
+	}
+
+	got, conflicts := Merge(base.String(), server.String(), client.String())
+	if conflicts != 1 {
+		t.Fatalf("conflicts = %d, want 1", conflicts)
+	}
+	want := "line 0\n" + markerServer + strings.TrimPrefix(server.String(), "line 0\n") +
+		markerSep + strings.TrimPrefix(client.String(), "line 0\n") + markerClient
+	if got != want {
+		t.Errorf("Merge with too many edits is not a whole-text conflict after the common prefix")
+	}
+
+	// С меньшим числом правок те же изменения сливаются без конфликта
+	small := strings.Join(strings.SplitAfter(base.String(), "\n")[:100], "")
+	smallServer := strings.Replace(small, "line 1\n", "changed 1\n", 1)
+	smallClient := strings.Replace(small, "line 99\n", "client end\n", 1)
+	if got, conflicts := Merge(small, smallServer, smallClient); conflicts != 0 ||
+		got != strings.Replace(smallServer, "line 99\n", "client end\n", 1) {
+		t.Errorf("Merge of a small text = %d conflicts, want a clean merge", conflicts)
+	}
+}
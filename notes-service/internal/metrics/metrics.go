@@ -0,0 +1,110 @@
+// Package metrics — метрики notes-service. HTTP-метрики и пул pgx в common/metrics.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Доля попаданий: rate(notes_cache_requests_total{result="hit"}[5m]) / rate(notes_cache_requests_total[5m])
+var cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notes_cache_requests_total",
+	Help: "Redis lookups of user note lists by result (hit, miss, error).",
+}, []string{"result"})
+
+func CacheHit()   { cacheRequests.WithLabelValues("hit").Inc() }
+func CacheMiss()  { cacheRequests.WithLabelValues("miss").Inc() }
+func CacheError() { cacheRequests.WithLabelValues("error").Inc() }
+
+var webhookAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notes_webhook_attempts_total",
+	Help: "Webhook delivery attempts by result (success, retry, failed).",
+}, []string{"result"})
+
+// WebhookAttempt: result — success, retry (будет повтор) или failed (попытки исчерпаны)
+func WebhookAttempt(result string) { webhookAttempts.WithLabelValues(result).Inc() }
+
+var (
+	outboxPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notes_outbox_published_events_total",
+		Help: "Note change events published from the outbox.",
+	})
+	outboxErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notes_outbox_publish_errors_total",
+		Help: "Failed attempts to publish a batch of outbox events.",
+	})
+)
+
+func OutboxPublished(n int) { outboxPublished.Add(float64(n)) }
+func OutboxError()          { outboxErrors.Inc() }
+
+var collabConnections = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "notes_collab_connections",
+	Help: "Open WebSocket connections for collaborative note editing.",
+})
+
+func CollabConnected()    { collabConnections.Inc() }
+func CollabDisconnected() { collabConnections.Dec() }
+
+// NotesCollector считает заметки по пользователям в момент скрейпа.
+// Ряд на каждого пользователя раздул бы Prometheus, поэтому отдается
+// распределение (гистограмма) и максимум.
+type NotesCollector struct {
+	count func(ctx context.Context) (map[int32]int, error)
+
+	total   *prometheus.Desc
+	users   *prometheus.Desc
+	max     *prometheus.Desc
+	perUser *prometheus.Desc
+}
+
+var notesPerUserBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// RegisterNotes регистрирует NotesCollector в стандартном реестре.
+func RegisterNotes(count func(ctx context.Context) (map[int32]int, error)) error {
+	return prometheus.Register(&NotesCollector{
+		count:   count,
+		total:   prometheus.NewDesc("notes_total", "Number of notes stored.", nil, nil),
+		users:   prometheus.NewDesc("notes_users", "Number of users with at least one note.", nil, nil),
+		max:     prometheus.NewDesc("notes_per_user_max", "Largest number of notes owned by one user.", nil, nil),
+		perUser: prometheus.NewDesc("notes_per_user", "Distribution of note counts across users.", nil, nil),
+	})
+}
+
+func (c *NotesCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *NotesCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	counts, err := c.count(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error collecting note metrics", "error", err)
+		return
+	}
+
+	total, max := 0, 0
+	buckets := make(map[float64]uint64, len(notesPerUserBuckets))
+	for _, n := range counts {
+		total += n
+		if n > max {
+			max = n
+		}
+		for _, b := range notesPerUserBuckets {
+			if float64(n) <= b {
+				buckets[b]++
+			}
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(total))
+	ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(len(counts)))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(max))
+	ch <- prometheus.MustNewConstHistogram(c.perUser, uint64(len(counts)), float64(total), buckets)
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Типы событий об изменении заметок
+const (
+    NoteCreated = "note.created"
+    NoteUpdated = "note.updated"
+    NoteDeleted = "note.deleted"
+)
+
+// NoteEvent — событие из outbox. Payload содержит заметку после изменения,
+// для удаления — только id и user_id.
+type NoteEvent struct {
+    ID        int64           `json:"id"`
+    Type      string          `json:"type"`
+    NoteID    int32           `json:"note_id"`
+    UserID    int32           `json:"user_id"`
+    Payload   json.RawMessage `json:"payload"`
+    CreatedAt time.Time       `json:"created_at"`
+}
@@ -1,22 +1,60 @@
-package models
-
-import "time"
-
-type Note struct {
-    ID        int32     `json:"id"`
-    Title     string    `json:"title"`
-    Content   string    `json:"content"`
-    UserID    int32     `json:"user_id"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
-}
-
-type CreateNoteRequest struct {
-    Title   string `json:"title"`
-    Content string `json:"content"`
-}
-
-type UpdateNoteRequest struct {
-    Title   string `json:"title"`
-    Content string `json:"content"`
-}
\ No newline at end of file
+package models
+
+import "time"
+
+type Note struct {
+    ID        int32     `json:"id"`
+    Title     string    `json:"title"`
+    Content   string    `json:"content"`
+    UserID    int32     `json:"user_id"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+    // Version растет с каждым изменением; клиент передает ее как базовую версию правки
+    Version int64 `json:"version"`
+    // ClientID — метка, под которой заметку создал клиент синхронизации;
+    // повторное создание с той же меткой возвращает уже созданную заметку
+    ClientID string `json:"-"`
+}
+
+type CreateNoteRequest struct {
+    Title   string `json:"title"`
+    Content string `json:"content"`
+}
+
+type UpdateNoteRequest struct {
+    Title   string `json:"title"`
+    Content string `json:"content"`
+    // Версия, с которой начата правка; если заметку успели изменить,
+    // правки сливаются. 0 — перезаписать без проверки
+    BaseVersion int64 `json:"base_version,omitempty"`
+}
+
+// Tombstone — след удаленной заметки для синхронизации клиентов
+type Tombstone struct {
+    ID        int32     `json:"id"`
+    Version   int64     `json:"version"`
+    DeletedAt time.Time `json:"deleted_at"`
+}
+
+// NoteChanges — изменения заметок пользователя после курсора синхронизации
+type NoteChanges struct {
+    // Созданные и измененные заметки в порядке изменения
+    Notes   []Note
+    Deleted []Tombstone
+    // Seq — курсор, после которого изменений еще нет
+    Seq int64
+    // Reset — первая синхронизация или следы части удалений уже удалены: Notes
+    // содержит все заметки пользователя, остальные клиент должен удалить у себя
+    Reset bool
+}
+
+// Usage — сколько заметок и байт (заголовок и текст в UTF-8) занимает пользователь
+type Usage struct {
+    Notes int   `json:"notes"`
+    Bytes int64 `json:"bytes"`
+}
+
+// NoteSize — размер заметки в байтах, как его считает квота
+func NoteSize(title, content string) int64 {
+    return int64(len(title) + len(content))
+}
@@ -0,0 +1,27 @@
+package models
+
+// Операции изменений, которые клиент присылает в синхронизацию
+const (
+    SyncCreate = "create"
+    SyncUpdate = "update"
+    SyncDelete = "delete"
+)
+
+// SyncRequest — тело POST /api/notes/sync. SyncToken — курсор из прошлого
+// ответа; пустой при первой синхронизации.
+type SyncRequest struct {
+    SyncToken string         `json:"sync_token"`
+    Mutations []SyncMutation `json:"mutations"`
+}
+
+// SyncMutation — изменение, сделанное клиентом без связи. ClientID — метка
+// новой заметки на клиенте, по ней клиент узнает выданный id. BaseVersion —
+// версия заметки, от которой клиент начал правку.
+type SyncMutation struct {
+    Op          string `json:"op"`
+    ID          int32  `json:"id,omitempty"`
+    ClientID    string `json:"client_id,omitempty"`
+    BaseVersion int64  `json:"base_version,omitempty"`
+    Title       string `json:"title"`
+    Content     string `json:"content"`
+}
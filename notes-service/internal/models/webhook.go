@@ -0,0 +1,59 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// События вебхуков
+const (
+    WebhookEventNoteCreated = NoteCreated
+    WebhookEventNoteUpdated = NoteUpdated
+    WebhookEventNoteDeleted = NoteDeleted
+    // Проверочная доставка; на нее не подписываются, ее отправляет ping
+    WebhookEventPing = "ping"
+)
+
+// WebhookEvents — события, на которые можно подписаться
+var WebhookEvents = []string{WebhookEventNoteCreated, WebhookEventNoteUpdated, WebhookEventNoteDeleted}
+
+// Состояния доставки
+const (
+    DeliveryPending   = "pending"
+    DeliverySucceeded = "succeeded"
+    DeliveryFailed    = "failed"
+)
+
+type Webhook struct {
+    ID     int32    `json:"id"`
+    UserID int32    `json:"user_id"`
+    URL    string   `json:"url"`
+    Events []string `json:"events"`
+    // Ключ подписи HMAC; показывается только в ответе на создание
+    Secret    string    `json:"secret,omitempty"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+    URL    string   `json:"url"`
+    Events []string `json:"events"`
+}
+
+// WebhookDelivery — доставка одного события одному вебхуку; она же запись журнала доставок
+type WebhookDelivery struct {
+    ID             int64           `json:"id"`
+    WebhookID      int32           `json:"webhook_id"`
+    Event          string          `json:"event"`
+    Payload        json.RawMessage `json:"payload"`
+    Status         string          `json:"status"`
+    Attempts       int             `json:"attempts"`
+    ResponseStatus int             `json:"response_status,omitempty"`
+    LastError      string          `json:"last_error,omitempty"`
+    NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+    CreatedAt      time.Time       `json:"created_at"`
+    DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+
+    // Адрес и ключ вебхука, заполняются при выборке доставки на отправку
+    URL    string `json:"-"`
+    Secret string `json:"-"`
+}
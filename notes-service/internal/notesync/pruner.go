@@ -0,0 +1,45 @@
+// Package notesync обслуживает синхронизацию офлайн-клиентов: удаляет
+// устаревшие следы удаленных заметок.
+package notesync
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"notes-service/internal/config"
+	"notes-service/internal/storage"
+)
+
+const pruneInterval = time.Hour
+
+// Pruner удаляет следы удаленных заметок старше TombstoneRetention. Клиент
+// с курсором до удаленного следа при следующей синхронизации получает reset.
+type Pruner struct {
+	notes storage.NoteRepository
+	cfg   config.Sync
+}
+
+func NewPruner(notes storage.NoteRepository, cfg config.Sync) *Pruner {
+	return &Pruner{notes: notes, cfg: cfg}
+}
+
+// Run удаляет следы раз в час, пока не отменен ctx
+func (p *Pruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.notes.PruneTombstones(ctx, time.Now().Add(-p.cfg.TombstoneRetention))
+			if err != nil {
+				slog.ErrorContext(ctx, "Error pruning note tombstones", "error", err)
+			} else if n > 0 {
+				slog.InfoContext(ctx, "Pruned note tombstones", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"notes-service/internal/cache"
+	"notes-service/internal/models"
+)
+
+// RedisPublisher добавляет события в поток Redis Streams, рассылает их
+// репликам через канал pub/sub (JSON models.NoteEvent) и в той же транзакции
+// MULTI сбрасывает кэш списков заметок их владельцев.
+// Поля записи потока: id (id события в outbox), type, note_id, user_id, payload, created_at.
+type RedisPublisher struct {
+	rdb     *redis.Client
+	stream  string
+	maxLen  int64
+	channel string
+}
+
+func NewRedisPublisher(rdb *redis.Client, stream string, maxLen int64, channel string) *RedisPublisher {
+	return &RedisPublisher{rdb: rdb, stream: stream, maxLen: maxLen, channel: channel}
+}
+
+func (p *RedisPublisher) Publish(ctx context.Context, events []models.NoteEvent) error {
+	pipe := p.rdb.TxPipeline()
+	users := make(map[int32]bool)
+	for _, event := range events {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: p.stream,
+			MaxLen: p.maxLen,
+			Approx: true,
+			Values: map[string]any{
+				"id":         event.ID,
+				"type":       event.Type,
+				"note_id":    event.NoteID,
+				"user_id":    event.UserID,
+				"payload":    string(event.Payload),
+				"created_at": event.CreatedAt.Format(time.RFC3339Nano),
+			},
+		})
+		message, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		pipe.Publish(ctx, p.channel, message)
+		users[event.UserID] = true
+	}
+	// Сброс после фиксации изменения в базе убирает и список, который успел
+	// закэшировать параллельный запрос, прочитавший базу до изменения
+	for userID := range users {
+		pipe.Del(ctx, cache.UserNotesKey(userID))
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
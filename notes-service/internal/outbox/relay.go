@@ -0,0 +1,99 @@
+// Package outbox публикует события об изменении заметок, записанные
+// репозиторием в note_outbox, и повторяет публикацию до успеха.
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"notes-service/internal/config"
+	"notes-service/internal/metrics"
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+const (
+	// Сколько может длиться публикация одной пачки; транзакция outbox открыта все это время
+	publishTimeout = 10 * time.Second
+	pruneInterval  = time.Hour
+)
+
+// Publisher доставляет пачку событий. Событие помечается опубликованным,
+// только если Publish вернул nil, иначе пачка придет снова: подписчики
+// должны отсеивать повторы по id события.
+type Publisher interface {
+	Publish(ctx context.Context, events []models.NoteEvent) error
+}
+
+// Publishers передает пачку каждому Publisher по очереди и останавливается на
+// первой ошибке; после нее пачка придет снова всем, включая уже получивших.
+type Publishers []Publisher
+
+func (ps Publishers) Publish(ctx context.Context, events []models.NoteEvent) error {
+	for _, p := range ps {
+		if err := p.Publish(ctx, events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Relay переносит события из outbox в Publisher. Реплики работают с общей
+// таблицей: PublishOutbox пропускает проход, если публикует другая реплика.
+type Relay struct {
+	store     storage.OutboxStore
+	publisher Publisher
+	cfg       config.Outbox
+}
+
+func NewRelay(store storage.OutboxStore, publisher Publisher, cfg config.Outbox) *Relay {
+	return &Relay{store: store, publisher: publisher, cfg: cfg}
+}
+
+// Run публикует события, пока не отменен ctx. Неопубликованное к остановке
+// остается в таблице и уйдет после перезапуска.
+func (r *Relay) Run(ctx context.Context) {
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			r.relay(ctx)
+		case <-prune.C:
+			n, err := r.store.PruneOutbox(ctx, time.Now().Add(-r.cfg.Retention))
+			if err != nil {
+				slog.ErrorContext(ctx, "Error pruning note outbox", "error", err)
+			} else if n > 0 {
+				slog.InfoContext(ctx, "Pruned note outbox", "count", n)
+			}
+		}
+	}
+}
+
+// relay публикует накопившиеся события пачками по BatchSize
+func (r *Relay) relay(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := r.publishBatch(ctx)
+		if err != nil {
+			metrics.OutboxError()
+			slog.ErrorContext(ctx, "Error publishing note events", "error", err)
+			return
+		}
+		metrics.OutboxPublished(n)
+		if n < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	return r.store.PublishOutbox(ctx, r.cfg.BatchSize, r.publisher.Publish)
+}
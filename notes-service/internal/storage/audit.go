@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+
+	"common/audit"
+	"notes-service/internal/models"
+)
+
+// AuditRepository пишет в журнал аудита каждое изменение заметок. Он стоит
+// над остальными обертками, поэтому через него проходят правки из REST,
+// синхронизации и совместного редактирования, в том числе слитые.
+// Актор, IP и источник изменения берутся из контекста (audit.RecordContext).
+type AuditRepository struct {
+	NoteRepository
+	audit *audit.Recorder
+}
+
+func NewAuditRepository(repo NoteRepository, recorder *audit.Recorder) *AuditRepository {
+	return &AuditRepository{NoteRepository: repo, audit: recorder}
+}
+
+func (r *AuditRepository) CreateNote(ctx context.Context, note models.Note) (int32, error) {
+	id, err := r.NoteRepository.CreateNote(ctx, note)
+	if err == nil {
+		r.record(ctx, audit.ActionNoteCreate, id, note.UserID, "")
+	}
+	return id, err
+}
+
+func (r *AuditRepository) CreateNoteWithin(ctx context.Context, note models.Note, quota Quota) (int32, error) {
+	id, err := r.NoteRepository.CreateNoteWithin(ctx, note, quota)
+	if err == nil {
+		r.record(ctx, audit.ActionNoteCreate, id, note.UserID, "")
+	}
+	return id, err
+}
+
+func (r *AuditRepository) UpdateNote(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64) (*models.Note, error) {
+	note, err := r.NoteRepository.UpdateNote(ctx, noteID, userID, title, content, baseVersion)
+	if err == nil {
+		r.record(ctx, audit.ActionNoteUpdate, noteID, userID, mergedDetail(ctx, note, title, content))
+	}
+	return note, err
+}
+
+func (r *AuditRepository) UpdateNoteWithin(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64, quota Quota) (*models.Note, error) {
+	note, err := r.NoteRepository.UpdateNoteWithin(ctx, noteID, userID, title, content, baseVersion, quota)
+	if err == nil {
+		r.record(ctx, audit.ActionNoteUpdate, noteID, userID, mergedDetail(ctx, note, title, content))
+	}
+	return note, err
+}
+
+func (r *AuditRepository) DeleteNote(ctx context.Context, noteID int32, userID int32, baseVersion int64) error {
+	err := r.NoteRepository.DeleteNote(ctx, noteID, userID, baseVersion)
+	if err == nil {
+		r.record(ctx, audit.ActionNoteDelete, noteID, userID, "")
+	}
+	return err
+}
+
+func (r *AuditRepository) ShareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error {
+	err := r.NoteRepository.ShareNote(ctx, noteID, ownerID, userID)
+	if err == nil {
+		r.record(ctx, audit.ActionNoteShare, noteID, ownerID, "user_id="+strconv.Itoa(int(userID)))
+	}
+	return err
+}
+
+func (r *AuditRepository) UnshareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error {
+	err := r.NoteRepository.UnshareNote(ctx, noteID, ownerID, userID)
+	if err == nil {
+		r.record(ctx, audit.ActionNoteUnshare, noteID, ownerID, "user_id="+strconv.Itoa(int(userID)))
+	}
+	return err
+}
+
+func (r *AuditRepository) record(ctx context.Context, action string, noteID, userID int32, detail string) {
+	r.audit.RecordContext(ctx, audit.Event{Action: action, UserID: userID, TargetID: strconv.Itoa(int(noteID)), Detail: detail})
+}
+
+// mergedDetail отмечает правку, которую слили с чужими изменениями: записанный
+// текст отличается от отправленного. Пустой результат оставляет Detail из контекста.
+func mergedDetail(ctx context.Context, note *models.Note, title, content string) string {
+	if note.Title == title && note.Content == content {
+		return ""
+	}
+	if source := audit.DetailFromContext(ctx); source != "" {
+		return source + ",merged"
+	}
+	return "merged"
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"notes-service/internal/cache"
+	"notes-service/internal/models"
+)
+
+// CachedRepository кэширует списки заметок пользователя в Redis
+// и сбрасывает кэш после каждого изменения. Этот сброс лишь ускоряет
+// появление изменений: он может не дойти до Redis, поэтому кэш сбрасывается
+// еще раз при публикации события из outbox (outbox.RedisPublisher).
+type CachedRepository struct {
+	NoteRepository
+	ttl time.Duration
+}
+
+func NewCachedRepository(repo NoteRepository, ttl time.Duration) *CachedRepository {
+	return &CachedRepository{NoteRepository: repo, ttl: ttl}
+}
+
+func (r *CachedRepository) CreateNote(ctx context.Context, note models.Note) (int32, error) {
+	return r.CreateNoteWithin(ctx, note, Quota{})
+}
+
+func (r *CachedRepository) CreateNoteWithin(ctx context.Context, note models.Note, quota Quota) (int32, error) {
+	id, err := r.NoteRepository.CreateNoteWithin(ctx, note, quota)
+	if err != nil {
+		return 0, err
+	}
+
+	// Очищаем кэш пользователя
+	cache.InvalidateUserCache(ctx, note.UserID)
+
+	return id, nil
+}
+
+func (r *CachedRepository) GetUserNotes(ctx context.Context, userID int32) ([]models.Note, error) {
+	// Сначала пробуем получить из кэша
+	cachedNotes, err := cache.GetCachedUserNotes(ctx, userID)
+	if err == nil && len(cachedNotes) > 0 {
+		slog.DebugContext(ctx, "Notes served from cache", "user_id", userID)
+		return cachedNotes, nil
+	}
+
+	// Если нет в кэше - идем в хранилище
+	notes, err := r.NoteRepository.GetUserNotes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(notes) > 0 {
+		cache.CacheUserNotes(ctx, userID, notes, r.ttl)
+	}
+
+	return notes, nil
+}
+
+func (r *CachedRepository) UpdateNote(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64) (*models.Note, error) {
+	return r.UpdateNoteWithin(ctx, noteID, userID, title, content, baseVersion, Quota{})
+}
+
+func (r *CachedRepository) UpdateNoteWithin(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64, quota Quota) (*models.Note, error) {
+	note, err := r.NoteRepository.UpdateNoteWithin(ctx, noteID, userID, title, content, baseVersion, quota)
+	if err != nil {
+		return nil, err
+	}
+
+	// Очищаем кэш пользователя после обновления
+	cache.InvalidateUserCache(ctx, userID)
+	return note, nil
+}
+
+func (r *CachedRepository) DeleteNote(ctx context.Context, noteID int32, userID int32, baseVersion int64) error {
+	if err := r.NoteRepository.DeleteNote(ctx, noteID, userID, baseVersion); err != nil {
+		return err
+	}
+
+	// Очищаем кэш пользователя после удаления
+	cache.InvalidateUserCache(ctx, userID)
+	return nil
+}
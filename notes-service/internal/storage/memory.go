@@ -0,0 +1,514 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"notes-service/internal/models"
+)
+
+// MemoryRepository хранит заметки в памяти процесса. Подходит для тестов
+// и локального запуска без Postgres.
+type MemoryRepository struct {
+	mu     sync.RWMutex
+	notes  map[int32]models.Note
+	nextID int32
+
+	// Синхронизация: последний выданный sync_seq, sync_seq каждой заметки,
+	// следы удаленных и наибольший sync_seq удаленных следов пользователя
+	seq        int64
+	noteSeq    map[int32]int64
+	tombstones map[int32]memoryTombstone
+	prunedSeq  map[int32]int64
+
+	// Последние revisionLimit версий каждой заметки, от старых к новым
+	revisions map[int32][]models.Note
+	// shares — кому открыта каждая заметка
+	shares map[int32]map[int32]bool
+	// clientIDs — заметки, созданные с ClientID; метка удаленной живет, пока жив ее след
+	clientIDs map[memoryClientID]int32
+
+	// outbox: события в порядке id и время публикации каждого
+	events      []models.NoteEvent
+	nextEventID int64
+	publishedAt map[int64]time.Time
+	// publishMu не дает двум проходам relay публиковать одновременно
+	publishMu sync.Mutex
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		notes:       make(map[int32]models.Note),
+		noteSeq:     make(map[int32]int64),
+		tombstones:  make(map[int32]memoryTombstone),
+		prunedSeq:   make(map[int32]int64),
+		revisions:   make(map[int32][]models.Note),
+		shares:      make(map[int32]map[int32]bool),
+		clientIDs:   make(map[memoryClientID]int32),
+		publishedAt: make(map[int64]time.Time),
+	}
+}
+
+type memoryTombstone struct {
+	models.Tombstone
+	userID int32
+	seq    int64
+}
+
+type memoryClientID struct {
+	userID   int32
+	clientID string
+}
+
+func (r *MemoryRepository) CreateNote(ctx context.Context, note models.Note) (int32, error) {
+	return r.CreateNoteWithin(ctx, note, Quota{})
+}
+
+func (r *MemoryRepository) CreateNoteWithin(ctx context.Context, note models.Note, quota Quota) (int32, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clientID := memoryClientID{userID: note.UserID, clientID: note.ClientID}
+	if id, ok := r.clientIDs[clientID]; ok {
+		return 0, &DuplicateNoteError{NoteID: id}
+	}
+	usage := r.usage(note.UserID)
+	if err := quota.check(usage, models.Usage{
+		Notes: usage.Notes + 1,
+		Bytes: usage.Bytes + models.NoteSize(note.Title, note.Content),
+	}); err != nil {
+		return 0, err
+	}
+
+	r.nextID++
+	note.ID = r.nextID
+
+	now := time.Now()
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = now
+	}
+	if note.UpdatedAt.IsZero() {
+		note.UpdatedAt = now
+	}
+	note.Version = 1
+	note.ClientID = ""
+
+	if err := r.addEvent(models.NoteCreated, note); err != nil {
+		return 0, err
+	}
+	if clientID.clientID != "" {
+		r.clientIDs[clientID] = note.ID
+	}
+	r.notes[note.ID] = note
+	r.addRevision(note)
+	r.seq++
+	r.noteSeq[note.ID] = r.seq
+	return note.ID, nil
+}
+
+func (r *MemoryRepository) GetUserNotes(ctx context.Context, userID int32) ([]models.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var notes []models.Note
+	for _, note := range r.notes {
+		if note.UserID == userID {
+			notes = append(notes, note)
+		}
+	}
+
+	// Как и в Postgres: сначала новые, при равном времени — по убыванию id
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].CreatedAt.Equal(notes[j].CreatedAt) {
+			return notes[i].CreatedAt.After(notes[j].CreatedAt)
+		}
+		return notes[i].ID > notes[j].ID
+	})
+	return notes, nil
+}
+
+func (r *MemoryRepository) CountNotesByUser(ctx context.Context) (map[int32]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[int32]int)
+	for _, note := range r.notes {
+		counts[note.UserID]++
+	}
+	return counts, nil
+}
+
+func (r *MemoryRepository) GetUsage(ctx context.Context, userID int32) (models.Usage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.usage(userID), nil
+}
+
+// usage считает занятое пользователем место; вызывается под r.mu
+func (r *MemoryRepository) usage(userID int32) models.Usage {
+	var usage models.Usage
+	for _, note := range r.notes {
+		if note.UserID == userID {
+			usage.Notes++
+			usage.Bytes += models.NoteSize(note.Title, note.Content)
+		}
+	}
+	return usage
+}
+
+func (r *MemoryRepository) SearchNotes(ctx context.Context, userID int32, query string) ([]models.Note, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	notes, _ := r.GetUserNotes(ctx, userID)
+
+	var found []models.Note
+	for _, note := range notes {
+		words := make(map[string]bool)
+		for _, word := range searchTerms(note.Title + " " + note.Content) {
+			words[word] = true
+		}
+
+		matches := true
+		for _, term := range terms {
+			if !words[term] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			found = append(found, note)
+		}
+	}
+	return found, nil
+}
+
+func (r *MemoryRepository) GetNoteByID(ctx context.Context, noteID int32, userID int32) (*models.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	note, err := r.ownedNote(noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (r *MemoryRepository) UpdateNote(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64) (*models.Note, error) {
+	return r.UpdateNoteWithin(ctx, noteID, userID, title, content, baseVersion, Quota{})
+}
+
+func (r *MemoryRepository) UpdateNoteWithin(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64, quota Quota) (*models.Note, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, err := r.versionedNote(noteID, userID, baseVersion)
+	if err != nil {
+		return nil, err
+	}
+	usage := r.usage(userID)
+	growth := models.NoteSize(title, content) - models.NoteSize(note.Title, note.Content)
+	if err := quota.check(usage, models.Usage{Notes: usage.Notes, Bytes: usage.Bytes + growth}); err != nil {
+		return nil, err
+	}
+
+	note.Title = title
+	note.Content = content
+	note.UpdatedAt = time.Now()
+	note.Version++
+	if err := r.addEvent(models.NoteUpdated, note); err != nil {
+		return nil, err
+	}
+	r.notes[noteID] = note
+	r.addRevision(note)
+	r.seq++
+	r.noteSeq[noteID] = r.seq
+	return &note, nil
+}
+
+func (r *MemoryRepository) DeleteNote(ctx context.Context, noteID int32, userID int32, baseVersion int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, err := r.versionedNote(noteID, userID, baseVersion)
+	if err != nil {
+		return err
+	}
+
+	if err := r.addEvent(models.NoteDeleted, note); err != nil {
+		return err
+	}
+	delete(r.notes, noteID)
+	delete(r.noteSeq, noteID)
+	delete(r.revisions, noteID)
+	delete(r.shares, noteID)
+	r.seq++
+	r.tombstones[noteID] = memoryTombstone{
+		Tombstone: models.Tombstone{ID: noteID, Version: note.Version, DeletedAt: time.Now()},
+		userID:    userID,
+		seq:       r.seq,
+	}
+	return nil
+}
+
+func (r *MemoryRepository) NoteChanges(ctx context.Context, userID int32, afterSeq int64) (models.NoteChanges, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prunedSeq := r.prunedSeq[userID]
+	changes := models.NoteChanges{
+		Reset: afterSeq < 0 || afterSeq < prunedSeq,
+		Seq:   max(afterSeq, prunedSeq, 0),
+	}
+	from := afterSeq
+	if changes.Reset {
+		from = 0
+	}
+
+	for id, note := range r.notes {
+		if seq := r.noteSeq[id]; note.UserID == userID && seq > from {
+			changes.Notes = append(changes.Notes, note)
+			changes.Seq = max(changes.Seq, seq)
+		}
+	}
+	sort.Slice(changes.Notes, func(i, j int) bool {
+		return r.noteSeq[changes.Notes[i].ID] < r.noteSeq[changes.Notes[j].ID]
+	})
+
+	for _, tombstone := range r.tombstones {
+		if tombstone.userID != userID || tombstone.seq <= from {
+			continue
+		}
+		// После сброса клиент удаляет все, чего нет в Notes, следы ему не нужны
+		if !changes.Reset {
+			changes.Deleted = append(changes.Deleted, tombstone.Tombstone)
+		}
+		changes.Seq = max(changes.Seq, tombstone.seq)
+	}
+	sort.Slice(changes.Deleted, func(i, j int) bool {
+		return r.tombstones[changes.Deleted[i].ID].seq < r.tombstones[changes.Deleted[j].ID].seq
+	})
+	return changes, nil
+}
+
+func (r *MemoryRepository) PruneTombstones(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pruned int64
+	for id, tombstone := range r.tombstones {
+		if tombstone.DeletedAt.Before(before) {
+			r.prunedSeq[tombstone.userID] = max(r.prunedSeq[tombstone.userID], tombstone.seq)
+			delete(r.tombstones, id)
+			pruned++
+		}
+	}
+	for clientID, id := range r.clientIDs {
+		if _, ok := r.notes[id]; !ok {
+			if _, ok := r.tombstones[id]; !ok {
+				delete(r.clientIDs, clientID)
+			}
+		}
+	}
+	return pruned, nil
+}
+
+func (r *MemoryRepository) GetNoteRevision(ctx context.Context, noteID int32, userID int32, version int64) (*models.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, err := r.ownedNote(noteID, userID); err != nil {
+		return nil, err
+	}
+	for _, revision := range r.revisions[noteID] {
+		if revision.Version == version {
+			return &revision, nil
+		}
+	}
+	return nil, ErrRevisionNotFound
+}
+
+func (r *MemoryRepository) ShareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.ownedNote(noteID, ownerID); err != nil {
+		return err
+	}
+	if r.shares[noteID] == nil {
+		r.shares[noteID] = make(map[int32]bool)
+	}
+	r.shares[noteID][userID] = true
+	return nil
+}
+
+func (r *MemoryRepository) UnshareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.ownedNote(noteID, ownerID); err != nil {
+		return err
+	}
+	delete(r.shares[noteID], userID)
+	return nil
+}
+
+func (r *MemoryRepository) GetNoteShares(ctx context.Context, noteID int32, ownerID int32) ([]int32, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, err := r.ownedNote(noteID, ownerID); err != nil {
+		return nil, err
+	}
+	var users []int32
+	for userID := range r.shares[noteID] {
+		users = append(users, userID)
+	}
+	slices.Sort(users)
+	return users, nil
+}
+
+func (r *MemoryRepository) GetSharedNote(ctx context.Context, noteID int32, userID int32) (*models.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	note, ok := r.notes[noteID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if note.UserID != userID && !r.shares[noteID][userID] {
+		return nil, ErrForbidden
+	}
+	return &note, nil
+}
+
+// addRevision запоминает версию заметки; вызывается под r.mu
+func (r *MemoryRepository) addRevision(note models.Note) {
+	revisions := append(r.revisions[note.ID], note)
+	if len(revisions) > revisionLimit {
+		revisions = revisions[len(revisions)-revisionLimit:]
+	}
+	r.revisions[note.ID] = revisions
+}
+
+// versionedNote — ownedNote, которая еще не изменилась после baseVersion
+func (r *MemoryRepository) versionedNote(noteID int32, userID int32, baseVersion int64) (models.Note, error) {
+	note, err := r.ownedNote(noteID, userID)
+	if err != nil {
+		return models.Note{}, err
+	}
+	if baseVersion != 0 && note.Version != baseVersion {
+		return models.Note{}, ErrVersionConflict
+	}
+	return note, nil
+}
+
+func (r *MemoryRepository) ownedNote(noteID int32, userID int32) (models.Note, error) {
+	note, ok := r.notes[noteID]
+	if !ok {
+		return models.Note{}, ErrNotFound
+	}
+	if note.UserID != userID {
+		return models.Note{}, ErrForbidden
+	}
+	return note, nil
+}
+
+// addEvent добавляет событие в outbox; вызывается под r.mu вместе с изменением
+func (r *MemoryRepository) addEvent(eventType string, note models.Note) error {
+	event, err := newNoteEvent(eventType, note)
+	if err != nil {
+		return err
+	}
+	r.nextEventID++
+	event.ID = r.nextEventID
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *MemoryRepository) PublishOutbox(ctx context.Context, limit int, publish func(context.Context, []models.NoteEvent) error) (int, error) {
+	r.publishMu.Lock()
+	defer r.publishMu.Unlock()
+
+	r.mu.RLock()
+	var pending []models.NoteEvent
+	for _, event := range r.events {
+		if len(pending) == limit {
+			break
+		}
+		if _, ok := r.publishedAt[event.ID]; !ok {
+			pending = append(pending, event)
+		}
+	}
+	r.mu.RUnlock()
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	if err := publish(ctx, pending); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, event := range pending {
+		r.publishedAt[event.ID] = now
+	}
+	return len(pending), nil
+}
+
+func (r *MemoryRepository) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.events[:0]
+	for _, event := range r.events {
+		if at, ok := r.publishedAt[event.ID]; ok && at.Before(before) {
+			delete(r.publishedAt, event.ID)
+			continue
+		}
+		kept = append(kept, event)
+	}
+	pruned := int64(len(r.events) - len(kept))
+	r.events = kept
+	return pruned, nil
+}
+
+func (r *MemoryRepository) NoteEventsSince(ctx context.Context, userID int32, afterID int64, limit int) ([]models.NoteEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []models.NoteEvent
+	for _, event := range r.events {
+		if len(events) == limit {
+			break
+		}
+		if event.UserID == userID && event.ID > afterID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *MemoryRepository) OldestNoteEventID(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.events) == 0 {
+		return 0, nil
+	}
+	return r.events[0].ID, nil
+}
+
+func (r *MemoryRepository) LatestNoteEventID(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.nextEventID, nil
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"notes-service/internal/merge"
+	"notes-service/internal/models"
+)
+
+// mergeAttempts — сколько раз слияние повторяется, если заметку снова
+// изменили, пока правки сливались
+const mergeAttempts = 3
+
+// MergeConflictError — правки клиента и сервера затрагивают одни и те же
+// строки. Merged содержит результат с маркерами конфликтов; отправив его
+// исправленным с BaseVersion, клиент завершит слияние.
+type MergeConflictError struct {
+	Server    *models.Note
+	Client    models.UpdateNoteRequest
+	Merged    models.UpdateNoteRequest
+	Conflicts int
+}
+
+func (e *MergeConflictError) Error() string {
+	return "note edits conflict"
+}
+
+// MergeRepository сливает правку, начатую с устаревшей версии, с тем, что
+// успели записать на сервере: базой служит сохраненная версия baseVersion.
+// Если база уже забыта, остается ErrVersionConflict.
+type MergeRepository struct {
+	NoteRepository
+}
+
+func NewMergeRepository(repo NoteRepository) *MergeRepository {
+	return &MergeRepository{NoteRepository: repo}
+}
+
+func (r *MergeRepository) UpdateNote(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64) (*models.Note, error) {
+	note, err := r.NoteRepository.UpdateNote(ctx, noteID, userID, title, content, baseVersion)
+	if baseVersion == 0 || !errors.Is(err, ErrVersionConflict) {
+		return note, err
+	}
+
+	base, err := r.GetNoteRevision(ctx, noteID, userID, baseVersion)
+	if errors.Is(err, ErrRevisionNotFound) {
+		return nil, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < mergeAttempts; attempt++ {
+		current, err := r.GetNoteByID(ctx, noteID, userID)
+		if err != nil {
+			return nil, err
+		}
+		mergedTitle, titleConflicts := merge.Merge(base.Title, current.Title, title)
+		mergedContent, contentConflicts := merge.Merge(base.Content, current.Content, content)
+		if conflicts := titleConflicts + contentConflicts; conflicts > 0 {
+			return nil, &MergeConflictError{
+				Server:    current,
+				Client:    models.UpdateNoteRequest{Title: title, Content: content, BaseVersion: baseVersion},
+				Merged:    models.UpdateNoteRequest{Title: mergedTitle, Content: mergedContent, BaseVersion: current.Version},
+				Conflicts: conflicts,
+			}
+		}
+
+		note, err = r.NoteRepository.UpdateNote(ctx, noteID, userID, mergedTitle, mergedContent, current.Version)
+		if !errors.Is(err, ErrVersionConflict) {
+			return note, err
+		}
+	}
+	return nil, ErrVersionConflict
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"database/sql"
+	"embed"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"common/migrate"
+)
+
+// Таблица версий своя у каждого сервиса, чтобы они не мешали друг другу
+// в общей базе Postgres или в общем SQLite-файле.
+const migrationsTable = "notes_schema_migrations"
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+func PostgresMigrator(pool *pgxpool.Pool) (*migrate.Migrator, error) {
+	migrations, err := migrate.Load(migrationFiles, "migrations/postgres")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(migrate.Postgres(pool, migrationsTable), migrations), nil
+}
+
+func SQLiteMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	migrations, err := migrate.Load(migrationFiles, "migrations/sqlite")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(migrate.SQLite(db, migrationsTable), migrations), nil
+}
@@ -0,0 +1,15 @@
+-- Базовая схема, раньше создавалась init.sql. IF NOT EXISTS позволяет
+-- накатить миграцию на уже существующую базу.
+-- Внешнего ключа на users нет: таблицей пользователей владеет auth-service,
+-- и сервисы могут мигрировать в любом порядке.
+CREATE TABLE IF NOT EXISTS notes (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(200) NOT NULL,
+    content TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_user_id ON notes(user_id);
+CREATE INDEX IF NOT EXISTS idx_notes_created_at ON notes(created_at DESC);
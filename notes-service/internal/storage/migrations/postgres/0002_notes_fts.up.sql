@@ -0,0 +1,2 @@
+-- Индекс под SearchNotes: выражение должно совпадать с запросом
+CREATE INDEX IF NOT EXISTS idx_notes_fts ON notes USING GIN (to_tsvector('simple', title || ' ' || content));
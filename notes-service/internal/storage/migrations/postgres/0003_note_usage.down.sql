@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS notes_usage ON notes;
+DROP FUNCTION IF EXISTS note_usage_track();
+DROP TABLE IF EXISTS note_usage;
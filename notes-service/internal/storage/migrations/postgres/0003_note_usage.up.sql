@@ -0,0 +1,36 @@
+-- Занятое место по пользователям для квот. Счетчики поддерживает триггер
+-- в той же транзакции, что и изменение заметки.
+CREATE TABLE IF NOT EXISTS note_usage (
+    user_id INTEGER PRIMARY KEY,
+    notes_count INTEGER NOT NULL DEFAULT 0,
+    total_bytes BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO note_usage (user_id, notes_count, total_bytes)
+SELECT user_id, COUNT(*), SUM(octet_length(title) + octet_length(content))
+FROM notes
+GROUP BY user_id
+ON CONFLICT (user_id) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION note_usage_track() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE note_usage
+        SET notes_count = notes_count - CASE WHEN TG_OP = 'DELETE' THEN 1 ELSE 0 END,
+            total_bytes = total_bytes - (octet_length(OLD.title) + octet_length(OLD.content))
+        WHERE user_id = OLD.user_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO note_usage (user_id, notes_count, total_bytes)
+        VALUES (NEW.user_id, CASE WHEN TG_OP = 'INSERT' THEN 1 ELSE 0 END, octet_length(NEW.title) + octet_length(NEW.content))
+        ON CONFLICT (user_id) DO UPDATE
+        SET notes_count = note_usage.notes_count + EXCLUDED.notes_count,
+            total_bytes = note_usage.total_bytes + EXCLUDED.total_bytes;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notes_usage ON notes;
+CREATE TRIGGER notes_usage AFTER INSERT OR UPDATE OR DELETE ON notes
+    FOR EACH ROW EXECUTE FUNCTION note_usage_track();
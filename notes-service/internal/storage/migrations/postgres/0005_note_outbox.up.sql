@@ -0,0 +1,14 @@
+-- События об изменении заметок пишутся в одной транзакции с изменением;
+-- relay публикует их в Redis Streams и проставляет published_at
+CREATE TABLE IF NOT EXISTS note_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event VARCHAR(32) NOT NULL,
+    note_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_outbox_pending ON note_outbox(id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_note_outbox_published_at ON note_outbox(published_at);
@@ -0,0 +1,2 @@
+-- Клиенты потока событий дочитывают пропущенное по своему user_id
+CREATE INDEX IF NOT EXISTS idx_note_outbox_user_id ON note_outbox(user_id, id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS note_sync_state;
+DROP TABLE IF EXISTS note_tombstones;
+DROP INDEX IF EXISTS idx_notes_user_sync;
+ALTER TABLE notes DROP COLUMN IF EXISTS sync_seq;
+ALTER TABLE notes DROP COLUMN IF EXISTS version;
+DROP SEQUENCE IF EXISTS note_sync_seq;
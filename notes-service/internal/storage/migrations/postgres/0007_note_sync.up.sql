@@ -0,0 +1,26 @@
+-- Синхронизация клиентов: version растет с каждым изменением заметки, sync_seq
+-- берется из общей последовательности при каждом изменении и удалении. Курсор
+-- клиента — наибольший полученный sync_seq; удаления остаются в note_tombstones.
+CREATE SEQUENCE IF NOT EXISTS note_sync_seq;
+
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS sync_seq BIGINT NOT NULL DEFAULT nextval('note_sync_seq');
+CREATE INDEX IF NOT EXISTS idx_notes_user_sync ON notes(user_id, sync_seq);
+
+CREATE TABLE IF NOT EXISTS note_tombstones (
+    note_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    version BIGINT NOT NULL,
+    sync_seq BIGINT NOT NULL DEFAULT nextval('note_sync_seq'),
+    deleted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_tombstones_user_sync ON note_tombstones(user_id, sync_seq);
+CREATE INDEX IF NOT EXISTS idx_note_tombstones_deleted_at ON note_tombstones(deleted_at);
+
+-- pruned_seq — наибольший sync_seq удаленных следов пользователя: клиент
+-- с курсором меньше мог пропустить удаление и получает все заметки заново
+CREATE TABLE IF NOT EXISTS note_sync_state (
+    user_id INTEGER PRIMARY KEY,
+    pruned_seq BIGINT NOT NULL
+);
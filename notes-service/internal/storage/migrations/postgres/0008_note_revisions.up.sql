@@ -0,0 +1,14 @@
+-- Последние версии заметок: по версии, от которой шла правка, сервер
+-- сливает ее с изменениями, сделанными с тех пор
+CREATE TABLE IF NOT EXISTS note_revisions (
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    version BIGINT NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (note_id, version)
+);
+
+INSERT INTO note_revisions (note_id, version, title, content, created_at)
+SELECT id, version, title, content, COALESCE(updated_at, CURRENT_TIMESTAMP) FROM notes
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Пользователи, которым владелец открыл заметку для совместного редактирования
+CREATE TABLE note_shares (
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (note_id, user_id)
+);
@@ -0,0 +1,9 @@
+-- Метки, под которыми клиенты синхронизации создали заметки: повтор того же
+-- создания возвращает уже созданную заметку. Метка удаленной заметки живет,
+-- пока жив ее след в note_tombstones.
+CREATE TABLE note_client_ids (
+    user_id INTEGER NOT NULL,
+    client_id VARCHAR(128) NOT NULL,
+    note_id INTEGER NOT NULL,
+    PRIMARY KEY (user_id, client_id)
+);
@@ -0,0 +1,24 @@
+-- Полнотекстовый индекс по заголовку и тексту, синхронизируется триггерами.
+-- unicode61 без удаления диакритики ведет себя как конфигурация 'simple' в Postgres.
+CREATE VIRTUAL TABLE notes_fts USING fts5(
+    title,
+    content,
+    content='notes',
+    content_rowid='id',
+    tokenize='unicode61 remove_diacritics 0'
+);
+
+CREATE TRIGGER notes_fts_insert AFTER INSERT ON notes BEGIN
+    INSERT INTO notes_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+CREATE TRIGGER notes_fts_delete AFTER DELETE ON notes BEGIN
+    INSERT INTO notes_fts(notes_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+END;
+
+CREATE TRIGGER notes_fts_update AFTER UPDATE ON notes BEGIN
+    INSERT INTO notes_fts(notes_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+    INSERT INTO notes_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+INSERT INTO notes_fts(notes_fts) VALUES ('rebuild');
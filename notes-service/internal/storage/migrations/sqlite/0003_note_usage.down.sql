@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS note_usage_update;
+DROP TRIGGER IF EXISTS note_usage_delete;
+DROP TRIGGER IF EXISTS note_usage_insert;
+DROP TABLE IF EXISTS note_usage;
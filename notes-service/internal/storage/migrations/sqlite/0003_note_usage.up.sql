@@ -0,0 +1,35 @@
+-- Занятое место по пользователям для квот, поддерживается триггерами.
+-- Размер считается в байтах, как octet_length в Postgres.
+CREATE TABLE note_usage (
+    user_id INTEGER PRIMARY KEY,
+    notes_count INTEGER NOT NULL DEFAULT 0,
+    total_bytes INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO note_usage (user_id, notes_count, total_bytes)
+SELECT user_id, COUNT(*), SUM(length(CAST(title AS BLOB)) + length(CAST(content AS BLOB)))
+FROM notes
+GROUP BY user_id;
+
+CREATE TRIGGER note_usage_insert AFTER INSERT ON notes BEGIN
+    INSERT INTO note_usage (user_id, notes_count, total_bytes)
+    VALUES (new.user_id, 1, length(CAST(new.title AS BLOB)) + length(CAST(new.content AS BLOB)))
+    ON CONFLICT (user_id) DO UPDATE
+    SET notes_count = notes_count + 1,
+        total_bytes = total_bytes + excluded.total_bytes;
+END;
+
+CREATE TRIGGER note_usage_delete AFTER DELETE ON notes BEGIN
+    UPDATE note_usage
+    SET notes_count = notes_count - 1,
+        total_bytes = total_bytes - (length(CAST(old.title AS BLOB)) + length(CAST(old.content AS BLOB)))
+    WHERE user_id = old.user_id;
+END;
+
+CREATE TRIGGER note_usage_update AFTER UPDATE OF title, content ON notes BEGIN
+    UPDATE note_usage
+    SET total_bytes = total_bytes
+        - (length(CAST(old.title AS BLOB)) + length(CAST(old.content AS BLOB)))
+        + (length(CAST(new.title AS BLOB)) + length(CAST(new.content AS BLOB)))
+    WHERE user_id = new.user_id;
+END;
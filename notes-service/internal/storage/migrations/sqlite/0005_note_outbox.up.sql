@@ -0,0 +1,15 @@
+-- События об изменении заметок пишутся в одной транзакции с изменением;
+-- relay публикует их и проставляет published_at. AUTOINCREMENT не дает
+-- переиспользовать id удаленных событий.
+CREATE TABLE note_outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event VARCHAR(32) NOT NULL,
+    note_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_note_outbox_pending ON note_outbox(id) WHERE published_at IS NULL;
+CREATE INDEX idx_note_outbox_published_at ON note_outbox(published_at);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_note_outbox_user_id;
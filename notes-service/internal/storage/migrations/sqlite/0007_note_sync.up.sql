@@ -0,0 +1,33 @@
+-- Синхронизация клиентов: version растет с каждым изменением заметки, sync_seq
+-- берется из счетчика note_sync_seq при каждом изменении и удалении. Курсор
+-- клиента — наибольший полученный sync_seq; удаления остаются в note_tombstones.
+ALTER TABLE notes ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notes ADD COLUMN sync_seq INTEGER NOT NULL DEFAULT 0;
+UPDATE notes SET sync_seq = id;
+CREATE INDEX idx_notes_user_sync ON notes(user_id, sync_seq);
+
+-- seq — последний выданный sync_seq
+CREATE TABLE note_sync_seq (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    seq INTEGER NOT NULL
+);
+
+INSERT INTO note_sync_seq (id, seq) SELECT 1, COALESCE(MAX(id), 0) FROM notes;
+
+CREATE TABLE note_tombstones (
+    note_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    sync_seq INTEGER NOT NULL,
+    deleted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_note_tombstones_user_sync ON note_tombstones(user_id, sync_seq);
+CREATE INDEX idx_note_tombstones_deleted_at ON note_tombstones(deleted_at);
+
+-- pruned_seq — наибольший sync_seq удаленных следов пользователя: клиент
+-- с курсором меньше мог пропустить удаление и получает все заметки заново
+CREATE TABLE note_sync_state (
+    user_id INTEGER PRIMARY KEY,
+    pruned_seq INTEGER NOT NULL
+);
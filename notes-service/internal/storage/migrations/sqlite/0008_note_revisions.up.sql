@@ -0,0 +1,13 @@
+-- Последние версии заметок: по версии, от которой шла правка, сервер
+-- сливает ее с изменениями, сделанными с тех пор
+CREATE TABLE note_revisions (
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (note_id, version)
+);
+
+INSERT INTO note_revisions (note_id, version, title, content, created_at)
+SELECT id, version, title, content, updated_at FROM notes;
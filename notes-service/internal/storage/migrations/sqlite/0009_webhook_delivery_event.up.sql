@@ -0,0 +1,5 @@
+-- Событие outbox, по которому поставлена доставка: relay может передать
+-- пачку повторно, и доставка одного события вебхуку не должна задвоиться
+ALTER TABLE webhook_deliveries ADD COLUMN event_id BIGINT;
+
+CREATE UNIQUE INDEX idx_webhook_deliveries_event ON webhook_deliveries(webhook_id, event_id) WHERE event_id IS NOT NULL;
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"time"
+
+	"notes-service/internal/models"
+)
+
+// OutboxStore — события об изменении заметок. Репозитории записывают событие
+// в той же транзакции, что и само изменение, поэтому событие не теряется
+// и не появляется без изменения.
+type OutboxStore interface {
+	// PublishOutbox передает publish до limit неопубликованных событий в порядке id
+	// и помечает их опубликованными, только если publish вернул nil. Одновременно
+	// события публикует одна реплика; остальные получают 0. Возвращает число
+	// опубликованных событий.
+	PublishOutbox(ctx context.Context, limit int, publish func(context.Context, []models.NoteEvent) error) (int, error)
+	// PruneOutbox удаляет события, опубликованные раньше before
+	PruneOutbox(ctx context.Context, before time.Time) (int64, error)
+	// NoteEventsSince возвращает до limit событий пользователя с id больше afterID
+	// в порядке id, опубликованные и нет
+	NoteEventsSince(ctx context.Context, userID int32, afterID int64, limit int) ([]models.NoteEvent, error)
+	// OldestNoteEventID — id самого старого хранимого события; 0, если событий нет
+	OldestNoteEventID(ctx context.Context) (int64, error)
+	// LatestNoteEventID — id последнего записанного события, даже если его уже
+	// удалили; 0, если событий не было
+	LatestNoteEventID(ctx context.Context) (int64, error)
+}
+
+// Ключ advisory-блокировки Postgres, под которой публикует одна реплика
+var outboxLockID = func() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("note_outbox"))
+	return int64(h.Sum64())
+}()
+
+// newNoteEvent собирает событие для outbox; id назначает хранилище
+func newNoteEvent(eventType string, note models.Note) (models.NoteEvent, error) {
+	var data any = note
+	if eventType == models.NoteDeleted {
+		data = struct {
+			ID     int32 `json:"id"`
+			UserID int32 `json:"user_id"`
+		}{note.ID, note.UserID}
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return models.NoteEvent{}, err
+	}
+	return models.NoteEvent{
+		Type:      eventType,
+		NoteID:    note.ID,
+		UserID:    note.UserID,
+		Payload:   payload,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,613 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"notes-service/internal/models"
+)
+
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+func (r *PostgresRepository) CreateNote(ctx context.Context, note models.Note) (int32, error) {
+	return r.CreateNoteWithin(ctx, note, Quota{})
+}
+
+func (r *PostgresRepository) CreateNoteWithin(ctx context.Context, note models.Note, quota Quota) (int32, error) {
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		// Блокировка пользователя не дает двум повторам одного создания разминуться
+		if err := lockPostgresSync(ctx, tx, note.UserID); err != nil {
+			return err
+		}
+		if note.ClientID != "" {
+			var id int32
+			err := tx.QueryRow(ctx,
+				"SELECT note_id FROM note_client_ids WHERE user_id = $1 AND client_id = $2",
+				note.UserID, note.ClientID).Scan(&id)
+			if err == nil {
+				return &DuplicateNoteError{NoteID: id}
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+		}
+		err := checkPostgresQuota(ctx, tx, note.UserID, quota, func() error {
+			return tx.QueryRow(ctx,
+				"INSERT INTO notes (title, content, user_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, version",
+				note.Title, note.Content, note.UserID, note.CreatedAt, note.UpdatedAt).Scan(&note.ID, &note.Version)
+		})
+		if err != nil {
+			return err
+		}
+		if note.ClientID != "" {
+			_, err := tx.Exec(ctx,
+				"INSERT INTO note_client_ids (user_id, client_id, note_id) VALUES ($1, $2, $3)",
+				note.UserID, note.ClientID, note.ID)
+			if err != nil {
+				return err
+			}
+		}
+		if err := writePostgresRevision(ctx, tx, note); err != nil {
+			return err
+		}
+		return writePostgresEvent(ctx, tx, models.NoteCreated, note)
+	})
+
+	var quotaErr *QuotaError
+	var duplicate *DuplicateNoteError
+	if errors.As(err, &quotaErr) || errors.As(err, &duplicate) {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error inserting note: %w", err)
+	}
+
+	return note.ID, nil
+}
+
+func (r *PostgresRepository) GetUserNotes(ctx context.Context, userID int32) ([]models.Note, error) {
+	rows, err := r.pool.Query(ctx,
+		"SELECT "+postgresNoteColumns+" FROM notes WHERE user_id = $1 ORDER BY created_at DESC",
+		userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("error fetching notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPostgresNotes(rows)
+}
+
+func (r *PostgresRepository) CountNotesByUser(ctx context.Context) (map[int32]int, error) {
+	rows, err := r.pool.Query(ctx, "SELECT user_id, COUNT(*) FROM notes GROUP BY user_id")
+	if err != nil {
+		return nil, fmt.Errorf("error counting notes: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int32]int)
+	for rows.Next() {
+		var userID int32
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, fmt.Errorf("error scanning note count: %w", err)
+		}
+		counts[userID] = count
+	}
+	return counts, rows.Err()
+}
+
+func (r *PostgresRepository) GetUsage(ctx context.Context, userID int32) (models.Usage, error) {
+	var usage models.Usage
+	err := r.pool.QueryRow(ctx,
+		"SELECT notes_count, total_bytes FROM note_usage WHERE user_id = $1",
+		userID).Scan(&usage.Notes, &usage.Bytes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Usage{}, nil
+	}
+	if err != nil {
+		return models.Usage{}, fmt.Errorf("error fetching usage: %w", err)
+	}
+	return usage, nil
+}
+
+func (r *PostgresRepository) SearchNotes(ctx context.Context, userID int32, query string) ([]models.Note, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	// Выражение совпадает с индексом idx_notes_fts из миграции 0002_notes_fts
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+postgresNoteColumns+` FROM notes
+		 WHERE user_id = $1 AND to_tsvector('simple', title || ' ' || content) @@ plainto_tsquery('simple', $2)
+		 ORDER BY ts_rank(to_tsvector('simple', title || ' ' || content), plainto_tsquery('simple', $2)) DESC, created_at DESC`,
+		userID, strings.Join(terms, " "))
+	if err != nil {
+		return nil, fmt.Errorf("error searching notes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPostgresNotes(rows)
+}
+
+func (r *PostgresRepository) UpdateNote(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64) (*models.Note, error) {
+	return r.UpdateNoteWithin(ctx, noteID, userID, title, content, baseVersion, Quota{})
+}
+
+func (r *PostgresRepository) UpdateNoteWithin(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64, quota Quota) (*models.Note, error) {
+	var note models.Note
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockPostgresSync(ctx, tx, userID); err != nil {
+			return err
+		}
+		err := checkPostgresQuota(ctx, tx, userID, quota, func() error {
+			return tx.QueryRow(ctx,
+				`UPDATE notes SET title = $1, content = $2, updated_at = NOW(), version = version + 1, sync_seq = nextval('note_sync_seq')
+				 WHERE id = $3 AND user_id = $4 AND ($5::bigint = 0 OR version = $5)
+				 RETURNING `+postgresNoteColumns,
+				title, content, noteID, userID, baseVersion).Scan(postgresNoteFields(&note)...)
+		})
+		if err != nil {
+			return err
+		}
+		if err := writePostgresRevision(ctx, tx, note); err != nil {
+			return err
+		}
+		return writePostgresEvent(ctx, tx, models.NoteUpdated, note)
+	})
+
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.noteAccessError(ctx, noteID, userID, baseVersion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error updating note: %w", err)
+	}
+
+	return &note, nil
+}
+
+func (r *PostgresRepository) DeleteNote(ctx context.Context, noteID int32, userID int32, baseVersion int64) error {
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockPostgresSync(ctx, tx, userID); err != nil {
+			return err
+		}
+		note := models.Note{ID: noteID, UserID: userID}
+		if err := tx.QueryRow(ctx,
+			"DELETE FROM notes WHERE id = $1 AND user_id = $2 AND ($3::bigint = 0 OR version = $3) RETURNING id, version",
+			noteID, userID, baseVersion).Scan(&note.ID, &note.Version); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO note_tombstones (note_id, user_id, version, deleted_at) VALUES ($1, $2, $3, $4)",
+			note.ID, note.UserID, note.Version, time.Now().UTC()); err != nil {
+			return err
+		}
+		return writePostgresEvent(ctx, tx, models.NoteDeleted, note)
+	})
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.noteAccessError(ctx, noteID, userID, baseVersion)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting note: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) GetNoteByID(ctx context.Context, noteID int32, userID int32) (*models.Note, error) {
+	var note models.Note
+	err := r.pool.QueryRow(ctx,
+		"SELECT "+postgresNoteColumns+" FROM notes WHERE id = $1 AND user_id = $2",
+		noteID, userID).Scan(postgresNoteFields(&note)...)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.noteAccessError(ctx, noteID, userID, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note: %w", err)
+	}
+
+	return &note, nil
+}
+
+func (r *PostgresRepository) ShareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error {
+	tag, err := r.pool.Exec(ctx,
+		`INSERT INTO note_shares (note_id, user_id, created_at)
+		 SELECT id, $3, $4 FROM notes WHERE id = $1 AND user_id = $2
+		 ON CONFLICT DO NOTHING`,
+		noteID, ownerID, userID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("error sharing note: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return r.sharedNoteError(ctx, noteID, ownerID)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UnshareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM note_shares
+		 WHERE note_id = $1 AND user_id = $3 AND EXISTS (SELECT 1 FROM notes WHERE id = $1 AND user_id = $2)`,
+		noteID, ownerID, userID)
+	if err != nil {
+		return fmt.Errorf("error unsharing note: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return r.sharedNoteError(ctx, noteID, ownerID)
+	}
+	return nil
+}
+
+// sharedNoteError — ошибка для запроса к доступу, который ничего не изменил:
+// владельцу это не ошибка (доступ уже был открыт или закрыт)
+func (r *PostgresRepository) sharedNoteError(ctx context.Context, noteID int32, ownerID int32) error {
+	_, err := r.GetNoteByID(ctx, noteID, ownerID)
+	return err
+}
+
+func (r *PostgresRepository) GetNoteShares(ctx context.Context, noteID int32, ownerID int32) ([]int32, error) {
+	if _, err := r.GetNoteByID(ctx, noteID, ownerID); err != nil {
+		return nil, err
+	}
+	rows, err := r.pool.Query(ctx, "SELECT user_id FROM note_shares WHERE note_id = $1 ORDER BY user_id", noteID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note shares: %w", err)
+	}
+	users, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note shares: %w", err)
+	}
+	return users, nil
+}
+
+func (r *PostgresRepository) GetSharedNote(ctx context.Context, noteID int32, userID int32) (*models.Note, error) {
+	var note models.Note
+	err := r.pool.QueryRow(ctx,
+		`SELECT `+postgresNoteColumns+` FROM notes
+		 WHERE id = $1 AND (user_id = $2 OR EXISTS (SELECT 1 FROM note_shares WHERE note_id = $1 AND user_id = $2))`,
+		noteID, userID).Scan(postgresNoteFields(&note)...)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.noteAccessError(ctx, noteID, userID, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note: %w", err)
+	}
+
+	return &note, nil
+}
+
+// noteAccessError объясняет, почему запрос по (noteID, userID) ничего не затронул:
+// заметки нет совсем, она принадлежит другому пользователю или ее версия
+// уже не baseVersion.
+func (r *PostgresRepository) noteAccessError(ctx context.Context, noteID int32, userID int32, baseVersion int64) error {
+	var ownerID int32
+	var version int64
+	err := r.pool.QueryRow(ctx, "SELECT user_id, version FROM notes WHERE id = $1", noteID).Scan(&ownerID, &version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error checking note owner: %w", err)
+	}
+	if ownerID != userID {
+		return ErrForbidden
+	}
+	if baseVersion != 0 && version != baseVersion {
+		return ErrVersionConflict
+	}
+	return ErrNotFound
+}
+
+const postgresNoteColumns = "id, title, content, user_id, created_at, updated_at, version"
+
+// postgresNoteFields — поля заметки в порядке postgresNoteColumns
+func postgresNoteFields(note *models.Note) []any {
+	return []any{&note.ID, &note.Title, &note.Content, &note.UserID, &note.CreatedAt, &note.UpdatedAt, &note.Version}
+}
+
+func scanPostgresNotes(rows pgx.Rows) ([]models.Note, error) {
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(postgresNoteFields(&note)...); err != nil {
+			return nil, fmt.Errorf("error scanning note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// Класс advisory-блокировок Postgres, под которыми меняются заметки одного пользователя
+var syncLockClass = func() int32 {
+	h := fnv.New32a()
+	h.Write([]byte("note_sync"))
+	return int32(h.Sum32())
+}()
+
+// checkPostgresQuota выполняет write и сверяет квоту с note_usage до и после
+// записи: счетчики обновляют триггеры в той же транзакции, а блокировка
+// lockPostgresSync не дает параллельным записям пользователя вклиниться.
+func checkPostgresQuota(ctx context.Context, tx pgx.Tx, userID int32, quota Quota, write func() error) error {
+	if quota.unlimited() {
+		return write()
+	}
+	before, err := postgresUsage(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	after, err := postgresUsage(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	return quota.check(before, after)
+}
+
+func postgresUsage(ctx context.Context, tx pgx.Tx, userID int32) (models.Usage, error) {
+	var usage models.Usage
+	err := tx.QueryRow(ctx,
+		"SELECT notes_count, total_bytes FROM note_usage WHERE user_id = $1",
+		userID).Scan(&usage.Notes, &usage.Bytes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Usage{}, nil
+	}
+	return usage, err
+}
+
+// lockPostgresSync упорядочивает изменения заметок пользователя до конца
+// транзакции: иначе изменение с меньшим sync_seq могло бы зафиксироваться
+// позже клиента, уже получившего курсор больше, и он бы его пропустил
+func lockPostgresSync(ctx context.Context, tx pgx.Tx, userID int32) error {
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1, $2)", syncLockClass, userID)
+	return err
+}
+
+func (r *PostgresRepository) NoteChanges(ctx context.Context, userID int32, afterSeq int64) (models.NoteChanges, error) {
+	var changes models.NoteChanges
+	// Заметки и следы читаются из одного снимка, иначе курсор мог бы
+	// перескочить через изменение, сделанное между запросами
+	err := pgx.BeginTxFunc(ctx, r.pool, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		var prunedSeq int64
+		err := tx.QueryRow(ctx, "SELECT pruned_seq FROM note_sync_state WHERE user_id = $1", userID).Scan(&prunedSeq)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		changes.Reset = afterSeq < 0 || afterSeq < prunedSeq
+		changes.Seq = max(afterSeq, prunedSeq, 0)
+		from := afterSeq
+		if changes.Reset {
+			from = 0
+		}
+
+		rows, err := tx.Query(ctx,
+			"SELECT "+postgresNoteColumns+", sync_seq FROM notes WHERE user_id = $1 AND sync_seq > $2 ORDER BY sync_seq",
+			userID, from)
+		if err != nil {
+			return err
+		}
+		changes.Notes, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Note, error) {
+			var note models.Note
+			var seq int64
+			err := row.Scan(append(postgresNoteFields(&note), &seq)...)
+			changes.Seq = max(changes.Seq, seq)
+			return note, err
+		})
+		if err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx,
+			"SELECT note_id, version, deleted_at, sync_seq FROM note_tombstones WHERE user_id = $1 AND sync_seq > $2 ORDER BY sync_seq",
+			userID, from)
+		if err != nil {
+			return err
+		}
+		deleted, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Tombstone, error) {
+			var tombstone models.Tombstone
+			var seq int64
+			err := row.Scan(&tombstone.ID, &tombstone.Version, &tombstone.DeletedAt, &seq)
+			changes.Seq = max(changes.Seq, seq)
+			return tombstone, err
+		})
+		// После сброса клиент удаляет все, чего нет в Notes, следы ему не нужны
+		if !changes.Reset {
+			changes.Deleted = deleted
+		}
+		return err
+	})
+
+	if err != nil {
+		return models.NoteChanges{}, fmt.Errorf("error fetching note changes: %w", err)
+	}
+	return changes, nil
+}
+
+func (r *PostgresRepository) PruneTombstones(ctx context.Context, before time.Time) (int64, error) {
+	// Изменяющие CTE выполняются целиком, даже если на них не ссылаются
+	var pruned int64
+	err := r.pool.QueryRow(ctx,
+		`WITH pruned AS (
+			DELETE FROM note_tombstones WHERE deleted_at < $1 RETURNING user_id, sync_seq
+		 ), state AS (
+			INSERT INTO note_sync_state (user_id, pruned_seq)
+			SELECT user_id, MAX(sync_seq) FROM pruned GROUP BY user_id
+			ON CONFLICT (user_id) DO UPDATE SET pruned_seq = GREATEST(note_sync_state.pruned_seq, EXCLUDED.pruned_seq)
+		 )
+		 SELECT COUNT(*) FROM pruned`,
+		before.UTC()).Scan(&pruned)
+	if err != nil {
+		return 0, fmt.Errorf("error pruning note tombstones: %w", err)
+	}
+	// Изменяющий CTE не видит собственных удалений, поэтому метки — отдельным запросом
+	_, err = r.pool.Exec(ctx,
+		`DELETE FROM note_client_ids c
+		 WHERE NOT EXISTS (SELECT 1 FROM notes n WHERE n.id = c.note_id)
+		   AND NOT EXISTS (SELECT 1 FROM note_tombstones t WHERE t.note_id = c.note_id)`)
+	if err != nil {
+		return 0, fmt.Errorf("error pruning note client ids: %w", err)
+	}
+	return pruned, nil
+}
+
+func (r *PostgresRepository) GetNoteRevision(ctx context.Context, noteID int32, userID int32, version int64) (*models.Note, error) {
+	note := models.Note{ID: noteID, UserID: userID, Version: version}
+	err := r.pool.QueryRow(ctx,
+		`SELECT v.title, v.content, n.created_at, v.created_at FROM note_revisions v JOIN notes n ON n.id = v.note_id
+		 WHERE v.note_id = $1 AND n.user_id = $2 AND v.version = $3`,
+		noteID, userID, version).Scan(&note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Заметки нет или она чужая — та же ошибка, что и у GetNoteByID
+		if _, err := r.GetNoteByID(ctx, noteID, userID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note revision: %w", err)
+	}
+	return &note, nil
+}
+
+// writePostgresRevision сохраняет версию заметки и забывает версии старше revisionLimit
+func writePostgresRevision(ctx context.Context, tx pgx.Tx, note models.Note) error {
+	_, err := tx.Exec(ctx,
+		"INSERT INTO note_revisions (note_id, version, title, content, created_at) VALUES ($1, $2, $3, $4, $5)",
+		note.ID, note.Version, note.Title, note.Content, note.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error writing note revision: %w", err)
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM note_revisions WHERE note_id = $1 AND version <= $2",
+		note.ID, note.Version-revisionLimit)
+	if err != nil {
+		return fmt.Errorf("error pruning note revisions: %w", err)
+	}
+	return nil
+}
+
+// writePostgresEvent добавляет событие в outbox внутри транзакции изменения
+func writePostgresEvent(ctx context.Context, tx pgx.Tx, eventType string, note models.Note) error {
+	event, err := newNoteEvent(eventType, note)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		"INSERT INTO note_outbox (event, note_id, user_id, payload, created_at) VALUES ($1, $2, $3, $4, $5)",
+		event.Type, event.NoteID, event.UserID, string(event.Payload), event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error writing note event: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) PublishOutbox(ctx context.Context, limit int, publish func(context.Context, []models.NoteEvent) error) (int, error) {
+	var events []models.NoteEvent
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		// Блокировка снимается вместе с транзакцией; реплика, не получившая ее, пропускает проход
+		var locked bool
+		if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", outboxLockID).Scan(&locked); err != nil || !locked {
+			return err
+		}
+
+		rows, err := tx.Query(ctx,
+			"SELECT id, event, note_id, user_id, payload, created_at FROM note_outbox WHERE published_at IS NULL ORDER BY id LIMIT $1",
+			limit)
+		if err != nil {
+			return err
+		}
+		events, err = pgx.CollectRows(rows, scanPostgresEvent)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		if err := publish(ctx, events); err != nil {
+			return err
+		}
+		ids := make([]int64, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		_, err = tx.Exec(ctx, "UPDATE note_outbox SET published_at = $1 WHERE id = ANY($2)", time.Now().UTC(), ids)
+		return err
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("error publishing note events: %w", err)
+	}
+	return len(events), nil
+}
+
+func (r *PostgresRepository) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, "DELETE FROM note_outbox WHERE published_at < $1", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("error pruning note events: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *PostgresRepository) NoteEventsSince(ctx context.Context, userID int32, afterID int64, limit int) ([]models.NoteEvent, error) {
+	rows, err := r.pool.Query(ctx,
+		"SELECT id, event, note_id, user_id, payload, created_at FROM note_outbox WHERE user_id = $1 AND id > $2 ORDER BY id LIMIT $3",
+		userID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note events: %w", err)
+	}
+	events, err := pgx.CollectRows(rows, scanPostgresEvent)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning note event: %w", err)
+	}
+	return events, nil
+}
+
+func (r *PostgresRepository) OldestNoteEventID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.pool.QueryRow(ctx, "SELECT COALESCE(MIN(id), 0) FROM note_outbox").Scan(&id); err != nil {
+		return 0, fmt.Errorf("error fetching oldest note event: %w", err)
+	}
+	return id, nil
+}
+
+// LatestNoteEventID берет последнее значение последовательности id: оно не
+// уменьшается, когда события удаляют
+func (r *PostgresRepository) LatestNoteEventID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.pool.QueryRow(ctx,
+		"SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM note_outbox_id_seq").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching latest note event: %w", err)
+	}
+	return id, nil
+}
+
+func scanPostgresEvent(row pgx.CollectableRow) (models.NoteEvent, error) {
+	var event models.NoteEvent
+	var payload string
+	err := row.Scan(&event.ID, &event.Type, &event.NoteID, &event.UserID, &payload, &event.CreatedAt)
+	event.Payload = []byte(payload)
+	return event, err
+}
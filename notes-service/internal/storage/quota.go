@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"notes-service/internal/config"
+	"notes-service/internal/models"
+)
+
+var ErrNoteTooLarge = errors.New("note exceeds maximum size")
+
+// QuotaError — изменение превысило бы квоту пользователя
+type QuotaError struct {
+	// notes или bytes
+	Resource string
+	Limit    int64
+	Used     int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s quota exceeded: %d of %d used", e.Resource, e.Used, e.Limit)
+}
+
+// Quota — лимиты пользователя для CreateNoteWithin и UpdateNoteWithin; 0 — без ограничения
+type Quota struct {
+	MaxNotes int64
+	MaxBytes int64
+}
+
+// check сравнивает занятое место до и после записи в той же транзакции.
+// Ошибка — только если запись увеличила показатель сверх лимита:
+// уменьшить заметку можно и при уже превышенной квоте.
+func (q Quota) check(before, after models.Usage) error {
+	if q.MaxNotes > 0 && after.Notes > before.Notes && int64(after.Notes) > q.MaxNotes {
+		return &QuotaError{Resource: "notes", Limit: q.MaxNotes, Used: int64(before.Notes)}
+	}
+	if q.MaxBytes > 0 && after.Bytes > before.Bytes && after.Bytes > q.MaxBytes {
+		return &QuotaError{Resource: "bytes", Limit: q.MaxBytes, Used: before.Bytes}
+	}
+	return nil
+}
+
+func (q Quota) unlimited() bool {
+	return q.MaxNotes <= 0 && q.MaxBytes <= 0
+}
+
+// QuotaRepository проверяет размер заметки и передает квоты пользователя
+// хранилищу: оно сверяет их с note_usage в транзакции записи, так что
+// параллельные запросы не превысят квоту.
+type QuotaRepository struct {
+	NoteRepository
+	limits config.Limits
+}
+
+func NewQuotaRepository(repo NoteRepository, limits config.Limits) *QuotaRepository {
+	return &QuotaRepository{NoteRepository: repo, limits: limits}
+}
+
+func (r *QuotaRepository) CreateNote(ctx context.Context, note models.Note) (int32, error) {
+	if err := r.checkSize(models.NoteSize(note.Title, note.Content)); err != nil {
+		return 0, err
+	}
+	return r.CreateNoteWithin(ctx, note, r.quota())
+}
+
+func (r *QuotaRepository) UpdateNote(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64) (*models.Note, error) {
+	if err := r.checkSize(models.NoteSize(title, content)); err != nil {
+		return nil, err
+	}
+	return r.UpdateNoteWithin(ctx, noteID, userID, title, content, baseVersion, r.quota())
+}
+
+func (r *QuotaRepository) quota() Quota {
+	return Quota{MaxNotes: int64(r.limits.MaxNotes), MaxBytes: r.limits.MaxTotalBytes}
+}
+
+func (r *QuotaRepository) checkSize(size int64) error {
+	if r.limits.MaxNoteBytes > 0 && size > r.limits.MaxNoteBytes {
+		return ErrNoteTooLarge
+	}
+	return nil
+}
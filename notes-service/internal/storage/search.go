@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+)
+
+// searchTerms разбивает поисковый запрос на слова в нижнем регистре так же,
+// как это делают парсер 'simple' в Postgres и токенизатор unicode61 в SQLite.
+// Заметка подходит, если содержит все слова запроса.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
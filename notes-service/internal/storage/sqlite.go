@@ -0,0 +1,660 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"notes-service/internal/models"
+)
+
+// OpenSQLite открывает (или создает) файл базы для однонодового режима.
+// Тот же файл может одновременно использовать auth-service.
+func OpenSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error connecting to sqlite database: %w", err)
+	}
+	return db, nil
+}
+
+// SQLiteRepository хранит заметки в SQLite; поиск идет через FTS5.
+// Время хранится в UTC, чтобы сортировка по строковому created_at была корректной.
+type SQLiteRepository struct {
+	db *sql.DB
+	// publishMu не дает двум проходам relay публиковать одновременно
+	publishMu sync.Mutex
+}
+
+func NewSQLiteRepository(ctx context.Context, db *sql.DB) (*SQLiteRepository, error) {
+	migrator, err := SQLiteMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrator.Up(ctx); err != nil {
+		return nil, err
+	}
+	return &SQLiteRepository{db: db}, nil
+}
+
+const sqliteNoteColumns = "id, title, content, user_id, created_at, updated_at, version"
+
+// sqliteNoteFields — поля заметки в порядке sqliteNoteColumns
+func sqliteNoteFields(note *models.Note) []any {
+	return []any{&note.ID, &note.Title, &note.Content, &note.UserID, &note.CreatedAt, &note.UpdatedAt, &note.Version}
+}
+
+func (r *SQLiteRepository) CreateNote(ctx context.Context, note models.Note) (int32, error) {
+	return r.CreateNoteWithin(ctx, note, Quota{})
+}
+
+func (r *SQLiteRepository) CreateNoteWithin(ctx context.Context, note models.Note, quota Quota) (int32, error) {
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if note.ClientID != "" {
+			var id int32
+			err := tx.QueryRowContext(ctx,
+				"SELECT note_id FROM note_client_ids WHERE user_id = ? AND client_id = ?",
+				note.UserID, note.ClientID).Scan(&id)
+			if err == nil {
+				return &DuplicateNoteError{NoteID: id}
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+		seq, err := nextSQLiteSeq(ctx, tx)
+		if err != nil {
+			return err
+		}
+		err = checkSQLiteQuota(ctx, tx, note.UserID, quota, func() error {
+			return tx.QueryRowContext(ctx,
+				"INSERT INTO notes (title, content, user_id, created_at, updated_at, sync_seq) VALUES (?, ?, ?, ?, ?, ?) RETURNING id, version",
+				note.Title, note.Content, note.UserID, note.CreatedAt.UTC(), note.UpdatedAt.UTC(), seq).Scan(&note.ID, &note.Version)
+		})
+		if err != nil {
+			return err
+		}
+		if note.ClientID != "" {
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO note_client_ids (user_id, client_id, note_id) VALUES (?, ?, ?)",
+				note.UserID, note.ClientID, note.ID)
+			if err != nil {
+				return err
+			}
+		}
+		if err := writeSQLiteRevision(ctx, tx, note); err != nil {
+			return err
+		}
+		return writeSQLiteEvent(ctx, tx, models.NoteCreated, note)
+	})
+	var quotaErr *QuotaError
+	var duplicate *DuplicateNoteError
+	if errors.As(err, &quotaErr) || errors.As(err, &duplicate) {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error inserting note: %w", err)
+	}
+	return note.ID, nil
+}
+
+func (r *SQLiteRepository) GetUserNotes(ctx context.Context, userID int32) ([]models.Note, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+sqliteNoteColumns+" FROM notes WHERE user_id = ? ORDER BY created_at DESC, id DESC",
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching notes: %w", err)
+	}
+	return scanSQLiteNotes(rows)
+}
+
+func (r *SQLiteRepository) GetUsage(ctx context.Context, userID int32) (models.Usage, error) {
+	var usage models.Usage
+	err := r.db.QueryRowContext(ctx,
+		"SELECT notes_count, total_bytes FROM note_usage WHERE user_id = ?",
+		userID).Scan(&usage.Notes, &usage.Bytes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Usage{}, nil
+	}
+	if err != nil {
+		return models.Usage{}, fmt.Errorf("error fetching usage: %w", err)
+	}
+	return usage, nil
+}
+
+func (r *SQLiteRepository) SearchNotes(ctx context.Context, userID int32, query string) ([]models.Note, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	// Каждое слово в кавычках — FTS5 не интерпретирует его как оператор; пробел означает AND
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT n.id, n.title, n.content, n.user_id, n.created_at, n.updated_at, n.version
+		 FROM notes_fts f JOIN notes n ON n.id = f.rowid
+		 WHERE notes_fts MATCH ? AND n.user_id = ?
+		 ORDER BY bm25(notes_fts), n.created_at DESC`,
+		strings.Join(quoted, " "), userID)
+	if err != nil {
+		return nil, fmt.Errorf("error searching notes: %w", err)
+	}
+	return scanSQLiteNotes(rows)
+}
+
+func (r *SQLiteRepository) GetNoteByID(ctx context.Context, noteID int32, userID int32) (*models.Note, error) {
+	var note models.Note
+	err := r.db.QueryRowContext(ctx,
+		"SELECT "+sqliteNoteColumns+" FROM notes WHERE id = ? AND user_id = ?",
+		noteID, userID).Scan(sqliteNoteFields(&note)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, r.noteAccessError(ctx, noteID, userID, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note: %w", err)
+	}
+	return &note, nil
+}
+
+func (r *SQLiteRepository) UpdateNote(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64) (*models.Note, error) {
+	return r.UpdateNoteWithin(ctx, noteID, userID, title, content, baseVersion, Quota{})
+}
+
+func (r *SQLiteRepository) UpdateNoteWithin(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64, quota Quota) (*models.Note, error) {
+	var note models.Note
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		seq, err := nextSQLiteSeq(ctx, tx)
+		if err != nil {
+			return err
+		}
+		err = checkSQLiteQuota(ctx, tx, userID, quota, func() error {
+			return tx.QueryRowContext(ctx,
+				`UPDATE notes SET title = ?, content = ?, updated_at = ?, version = version + 1, sync_seq = ?
+				 WHERE id = ? AND user_id = ? AND (? = 0 OR version = ?)
+				 RETURNING `+sqliteNoteColumns,
+				title, content, time.Now().UTC(), seq, noteID, userID, baseVersion, baseVersion).Scan(sqliteNoteFields(&note)...)
+		})
+		if err != nil {
+			return err
+		}
+		if err := writeSQLiteRevision(ctx, tx, note); err != nil {
+			return err
+		}
+		return writeSQLiteEvent(ctx, tx, models.NoteUpdated, note)
+	})
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
+		return nil, err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, r.noteAccessError(ctx, noteID, userID, baseVersion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error updating note: %w", err)
+	}
+	return &note, nil
+}
+
+func (r *SQLiteRepository) DeleteNote(ctx context.Context, noteID int32, userID int32, baseVersion int64) error {
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		seq, err := nextSQLiteSeq(ctx, tx)
+		if err != nil {
+			return err
+		}
+		note := models.Note{UserID: userID}
+		if err := tx.QueryRowContext(ctx,
+			"DELETE FROM notes WHERE id = ? AND user_id = ? AND (? = 0 OR version = ?) RETURNING id, version",
+			noteID, userID, baseVersion, baseVersion).Scan(&note.ID, &note.Version); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO note_tombstones (note_id, user_id, version, sync_seq, deleted_at) VALUES (?, ?, ?, ?, ?)",
+			note.ID, note.UserID, note.Version, seq, time.Now().UTC()); err != nil {
+			return err
+		}
+		return writeSQLiteEvent(ctx, tx, models.NoteDeleted, note)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.noteAccessError(ctx, noteID, userID, baseVersion)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting note: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ShareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO note_shares (note_id, user_id, created_at)
+		 SELECT id, ?, ? FROM notes WHERE id = ? AND user_id = ?
+		 ON CONFLICT DO NOTHING`,
+		userID, time.Now().UTC(), noteID, ownerID)
+	if err != nil {
+		return fmt.Errorf("error sharing note: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return r.sharedNoteError(ctx, noteID, ownerID)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) UnshareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM note_shares
+		 WHERE note_id = ? AND user_id = ? AND EXISTS (SELECT 1 FROM notes WHERE id = ? AND user_id = ?)`,
+		noteID, userID, noteID, ownerID)
+	if err != nil {
+		return fmt.Errorf("error unsharing note: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return r.sharedNoteError(ctx, noteID, ownerID)
+	}
+	return nil
+}
+
+// sharedNoteError — ошибка для запроса к доступу, который ничего не изменил:
+// владельцу это не ошибка (доступ уже был открыт или закрыт)
+func (r *SQLiteRepository) sharedNoteError(ctx context.Context, noteID int32, ownerID int32) error {
+	_, err := r.GetNoteByID(ctx, noteID, ownerID)
+	return err
+}
+
+func (r *SQLiteRepository) GetNoteShares(ctx context.Context, noteID int32, ownerID int32) ([]int32, error) {
+	if _, err := r.GetNoteByID(ctx, noteID, ownerID); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT user_id FROM note_shares WHERE note_id = ? ORDER BY user_id", noteID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note shares: %w", err)
+	}
+	defer rows.Close()
+
+	var users []int32
+	for rows.Next() {
+		var userID int32
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("error fetching note shares: %w", err)
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
+
+func (r *SQLiteRepository) GetSharedNote(ctx context.Context, noteID int32, userID int32) (*models.Note, error) {
+	var note models.Note
+	err := r.db.QueryRowContext(ctx,
+		`SELECT `+sqliteNoteColumns+` FROM notes
+		 WHERE id = ? AND (user_id = ? OR EXISTS (SELECT 1 FROM note_shares WHERE note_id = ? AND user_id = ?))`,
+		noteID, userID, noteID, userID).Scan(sqliteNoteFields(&note)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, r.noteAccessError(ctx, noteID, userID, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note: %w", err)
+	}
+	return &note, nil
+}
+
+func (r *SQLiteRepository) noteAccessError(ctx context.Context, noteID int32, userID int32, baseVersion int64) error {
+	var ownerID int32
+	var version int64
+	err := r.db.QueryRowContext(ctx, "SELECT user_id, version FROM notes WHERE id = ?", noteID).Scan(&ownerID, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error checking note owner: %w", err)
+	}
+	if ownerID != userID {
+		return ErrForbidden
+	}
+	if baseVersion != 0 && version != baseVersion {
+		return ErrVersionConflict
+	}
+	return ErrNotFound
+}
+
+// checkSQLiteQuota выполняет write и сверяет квоту с note_usage до и после
+// записи. Счетчики обновляют триггеры в той же транзакции, а блокировку
+// записи уже взял nextSQLiteSeq, так что параллельные записи ждут.
+func checkSQLiteQuota(ctx context.Context, tx *sql.Tx, userID int32, quota Quota, write func() error) error {
+	if quota.unlimited() {
+		return write()
+	}
+	before, err := sqliteUsage(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	after, err := sqliteUsage(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	return quota.check(before, after)
+}
+
+func sqliteUsage(ctx context.Context, tx *sql.Tx, userID int32) (models.Usage, error) {
+	var usage models.Usage
+	err := tx.QueryRowContext(ctx,
+		"SELECT notes_count, total_bytes FROM note_usage WHERE user_id = ?",
+		userID).Scan(&usage.Notes, &usage.Bytes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Usage{}, nil
+	}
+	return usage, err
+}
+
+// nextSQLiteSeq выдает следующий sync_seq. Запись в счетчик сразу берет
+// блокировку записи SQLite, поэтому изменения фиксируются в порядке sync_seq.
+func nextSQLiteSeq(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var seq int64
+	err := tx.QueryRowContext(ctx, "UPDATE note_sync_seq SET seq = seq + 1 RETURNING seq").Scan(&seq)
+	return seq, err
+}
+
+func (r *SQLiteRepository) NoteChanges(ctx context.Context, userID int32, afterSeq int64) (models.NoteChanges, error) {
+	var changes models.NoteChanges
+	// Чтение в одной транзакции видит один снимок базы
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var prunedSeq int64
+		err := tx.QueryRowContext(ctx, "SELECT pruned_seq FROM note_sync_state WHERE user_id = ?", userID).Scan(&prunedSeq)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		changes.Reset = afterSeq < 0 || afterSeq < prunedSeq
+		changes.Seq = max(afterSeq, prunedSeq, 0)
+		from := afterSeq
+		if changes.Reset {
+			from = 0
+		}
+
+		rows, err := tx.QueryContext(ctx,
+			"SELECT "+sqliteNoteColumns+", sync_seq FROM notes WHERE user_id = ? AND sync_seq > ? ORDER BY sync_seq",
+			userID, from)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var note models.Note
+			var seq int64
+			if err := rows.Scan(append(sqliteNoteFields(&note), &seq)...); err != nil {
+				return err
+			}
+			changes.Notes = append(changes.Notes, note)
+			changes.Seq = max(changes.Seq, seq)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.QueryContext(ctx,
+			"SELECT note_id, version, deleted_at, sync_seq FROM note_tombstones WHERE user_id = ? AND sync_seq > ? ORDER BY sync_seq",
+			userID, from)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var tombstone models.Tombstone
+			var seq int64
+			if err := rows.Scan(&tombstone.ID, &tombstone.Version, &tombstone.DeletedAt, &seq); err != nil {
+				return err
+			}
+			// После сброса клиент удаляет все, чего нет в Notes, следы ему не нужны
+			if !changes.Reset {
+				changes.Deleted = append(changes.Deleted, tombstone)
+			}
+			changes.Seq = max(changes.Seq, seq)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return models.NoteChanges{}, fmt.Errorf("error fetching note changes: %w", err)
+	}
+	return changes, nil
+}
+
+func (r *SQLiteRepository) PruneTombstones(ctx context.Context, before time.Time) (int64, error) {
+	var pruned int64
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO note_sync_state (user_id, pruned_seq)
+			 SELECT user_id, MAX(sync_seq) FROM note_tombstones WHERE deleted_at < ? GROUP BY user_id
+			 ON CONFLICT (user_id) DO UPDATE SET pruned_seq = MAX(pruned_seq, excluded.pruned_seq)`,
+			before.UTC())
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, "DELETE FROM note_tombstones WHERE deleted_at < ?", before.UTC())
+		if err != nil {
+			return err
+		}
+		if pruned, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`DELETE FROM note_client_ids
+			 WHERE NOT EXISTS (SELECT 1 FROM notes WHERE notes.id = note_client_ids.note_id)
+			   AND NOT EXISTS (SELECT 1 FROM note_tombstones WHERE note_tombstones.note_id = note_client_ids.note_id)`)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error pruning note tombstones: %w", err)
+	}
+	return pruned, nil
+}
+
+func (r *SQLiteRepository) CountNotesByUser(ctx context.Context) (map[int32]int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT user_id, COUNT(*) FROM notes GROUP BY user_id")
+	if err != nil {
+		return nil, fmt.Errorf("error counting notes: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int32]int)
+	for rows.Next() {
+		var userID int32
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, fmt.Errorf("error scanning note count: %w", err)
+		}
+		counts[userID] = count
+	}
+	return counts, rows.Err()
+}
+
+func scanSQLiteNotes(rows *sql.Rows) ([]models.Note, error) {
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(sqliteNoteFields(&note)...); err != nil {
+			return nil, fmt.Errorf("error scanning note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// inTx выполняет fn в транзакции и фиксирует ее, если fn вернул nil
+func (r *SQLiteRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteRepository) GetNoteRevision(ctx context.Context, noteID int32, userID int32, version int64) (*models.Note, error) {
+	note := models.Note{ID: noteID, UserID: userID, Version: version}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT v.title, v.content, n.created_at, v.created_at FROM note_revisions v JOIN notes n ON n.id = v.note_id
+		 WHERE v.note_id = ? AND n.user_id = ? AND v.version = ?`,
+		noteID, userID, version).Scan(&note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Заметки нет или она чужая — та же ошибка, что и у GetNoteByID
+		if _, err := r.GetNoteByID(ctx, noteID, userID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note revision: %w", err)
+	}
+	return &note, nil
+}
+
+// writeSQLiteRevision сохраняет версию заметки и забывает версии старше revisionLimit
+func writeSQLiteRevision(ctx context.Context, tx *sql.Tx, note models.Note) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO note_revisions (note_id, version, title, content, created_at) VALUES (?, ?, ?, ?, ?)",
+		note.ID, note.Version, note.Title, note.Content, note.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("error writing note revision: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM note_revisions WHERE note_id = ? AND version <= ?",
+		note.ID, note.Version-revisionLimit)
+	if err != nil {
+		return fmt.Errorf("error pruning note revisions: %w", err)
+	}
+	return nil
+}
+
+// writeSQLiteEvent добавляет событие в outbox внутри транзакции изменения
+func writeSQLiteEvent(ctx context.Context, tx *sql.Tx, eventType string, note models.Note) error {
+	event, err := newNoteEvent(eventType, note)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO note_outbox (event, note_id, user_id, payload, created_at) VALUES (?, ?, ?, ?, ?)",
+		event.Type, event.NoteID, event.UserID, string(event.Payload), event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error writing note event: %w", err)
+	}
+	return nil
+}
+
+// PublishOutbox держит транзакцию записи SQLite на время публикации,
+// поэтому два процесса над одним файлом не публикуют одно событие дважды
+// PublishOutbox вызывает publish вне транзакции: publish пишет в ту же базу
+// (доставки вебхуков), и блокировка записи SQLite заставила бы его ждать ее
+// снятия. С файлом работает один процесс, параллельные проходы исключает publishMu.
+func (r *SQLiteRepository) PublishOutbox(ctx context.Context, limit int, publish func(context.Context, []models.NoteEvent) error) (int, error) {
+	r.publishMu.Lock()
+	defer r.publishMu.Unlock()
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, event, note_id, user_id, payload, created_at FROM note_outbox WHERE published_at IS NULL ORDER BY id LIMIT ?",
+		limit)
+	if err != nil {
+		return 0, fmt.Errorf("error publishing note events: %w", err)
+	}
+	events, err := scanSQLiteEvents(rows)
+	if err != nil {
+		return 0, fmt.Errorf("error publishing note events: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err := publish(ctx, events); err != nil {
+		return 0, fmt.Errorf("error publishing note events: %w", err)
+	}
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now().UTC()
+		for _, event := range events {
+			if _, err := tx.ExecContext(ctx, "UPDATE note_outbox SET published_at = ? WHERE id = ?", now, event.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error publishing note events: %w", err)
+	}
+	return len(events), nil
+}
+
+func (r *SQLiteRepository) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM note_outbox WHERE published_at < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("error pruning note events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *SQLiteRepository) NoteEventsSince(ctx context.Context, userID int32, afterID int64, limit int) ([]models.NoteEvent, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, event, note_id, user_id, payload, created_at FROM note_outbox WHERE user_id = ? AND id > ? ORDER BY id LIMIT ?",
+		userID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching note events: %w", err)
+	}
+	events, err := scanSQLiteEvents(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning note event: %w", err)
+	}
+	return events, nil
+}
+
+func (r *SQLiteRepository) OldestNoteEventID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COALESCE(MIN(id), 0) FROM note_outbox").Scan(&id); err != nil {
+		return 0, fmt.Errorf("error fetching oldest note event: %w", err)
+	}
+	return id, nil
+}
+
+// LatestNoteEventID берет счетчик AUTOINCREMENT: он не уменьшается, когда
+// события удаляют
+func (r *SQLiteRepository) LatestNoteEventID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'note_outbox'), 0)").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching latest note event: %w", err)
+	}
+	return id, nil
+}
+
+func scanSQLiteEvents(rows *sql.Rows) ([]models.NoteEvent, error) {
+	defer rows.Close()
+
+	var events []models.NoteEvent
+	for rows.Next() {
+		var event models.NoteEvent
+		var payload string
+		if err := rows.Scan(&event.ID, &event.Type, &event.NoteID, &event.UserID, &payload, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.Payload = []byte(payload)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
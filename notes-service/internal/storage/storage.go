@@ -1,177 +1,93 @@
-package storage
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"os"
-	"time"
-	"sync"
-	"notes-service/internal/cache"
-	"notes-service/internal/models"
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
-)
-
-var (
-	dbPool *pgxpool.Pool
-	once   sync.Once
-)
-
-func initDB() {
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Error loading .env: %s", err)
-		return
-	}
-
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), 
-		os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), 
-		os.Getenv("DB_NAME"), os.Getenv("DB_SSLMODE"))
-
-	config, err := pgxpool.ParseConfig(connStr)
-	if err != nil {
-		log.Fatalf("Error parsing database config: %s", err)
-	}
-
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
-	if err != nil {
-		log.Fatalf("Error creating database pool: %s", err)
-	}
-
-	dbPool = pool
-	log.Println("Notes service: Database connection established")
-}
-
-func CreateNote(ctx context.Context, note models.Note) (int32, error) {
-	once.Do(initDB)
-	
-	var id int32
-	err := dbPool.QueryRow(ctx, 
-		"INSERT INTO notes (title, content, user_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		note.Title, note.Content, note.UserID, note.CreatedAt, note.UpdatedAt).Scan(&id)
-		
-	if err != nil {
-		return 0, fmt.Errorf("error inserting note: %w", err)
-	}
-
-	// Очищаем кэш пользователя
-	cache.InvalidateUserCache(note.UserID)
-
-	return id, nil
-}
-
-func GetUserNotes(ctx context.Context, userID int32) ([]models.Note, error) {
-	once.Do(initDB)
-	
-	// Сначала пробуем получить из кэша
-	cachedNotes, err := cache.GetCachedUserNotes(userID)
-	if err == nil && len(cachedNotes) > 0 {
-		log.Printf("✅ Got notes from cache for user %d", userID)
-		return cachedNotes, nil
-	}
-	
-	// Если нет в кэше - идем в БД
-	rows, err := dbPool.Query(ctx, 
-		"SELECT id, title, content, user_id, created_at, updated_at FROM notes WHERE user_id = $1 ORDER BY created_at DESC",
-		userID)
-		
-	if err != nil {
-		return nil, fmt.Errorf("error fetching notes: %w", err)
-	}
-	defer rows.Close()
-
-	var notes []models.Note
-	for rows.Next() {
-		var note models.Note
-		err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.UserID, &note.CreatedAt, &note.UpdatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning note: %w", err)
-		}
-		notes = append(notes, note)
-	}
-
-	// Сохраняем в кэш на 2 минуты
-	if len(notes) > 0 {
-		cache.CacheUserNotes(userID, notes, 2*time.Minute)
-	}
-
-	return notes, nil
-}
-
-func UpdateNote(ctx context.Context, noteID int32, userID int32, title, content string) error {
-	once.Do(initDB)
-	
-	log.Printf("🔄 Storage UpdateNote - noteID: %d, userID: %d, title: %s", noteID, userID, title)
-	
-	result, err := dbPool.Exec(ctx,
-		"UPDATE notes SET title = $1, content = $2, updated_at = NOW() WHERE id = $3 AND user_id = $4",
-		title, content, noteID, userID)
-		
-	if err != nil {
-		log.Printf("❌ Storage UpdateNote - DB error: %v", err)
-		return fmt.Errorf("error updating note: %w", err)
-	}
-
-	rowsAffected := result.RowsAffected()
-	log.Printf("📊 Storage UpdateNote - Rows affected: %d", rowsAffected)
-	
-	if rowsAffected == 0 {
-		log.Printf("❌ Storage UpdateNote - No rows affected: noteID=%d, userID=%d", noteID, userID)
-		return fmt.Errorf("note not found or access denied")
-	}
-
-	// Очищаем кэш пользователя после обновления
-	cache.InvalidateUserCache(userID)
-	log.Printf("✅ Storage UpdateNote - Successfully updated note %d for user %d", noteID, userID)
-	
-	return nil
-}
-
-func DeleteNote(ctx context.Context, noteID int32, userID int32) error {
-	once.Do(initDB)
-	
-	log.Printf("🔄 Storage DeleteNote - noteID: %d, userID: %d", noteID, userID)
-	
-	result, err := dbPool.Exec(ctx,
-		"DELETE FROM notes WHERE id = $1 AND user_id = $2",
-		noteID, userID)
-		
-	if err != nil {
-		log.Printf("❌ Storage DeleteNote - DB error: %v", err)
-		return fmt.Errorf("error deleting note: %w", err)
-	}
-
-	rowsAffected := result.RowsAffected()
-	log.Printf("📊 Storage DeleteNote - Rows affected: %d", rowsAffected)
-	
-	if rowsAffected == 0 {
-		log.Printf("❌ Storage DeleteNote - No rows affected: noteID=%d, userID=%d", noteID, userID)
-		return fmt.Errorf("note not found or access denied")
-	}
-
-	// Очищаем кэш пользователя после удаления
-	cache.InvalidateUserCache(userID)
-	log.Printf("✅ Storage DeleteNote - Successfully deleted note %d for user %d", noteID, userID)
-	
-	return nil
-}
-
-func GetNoteByID(ctx context.Context, noteID int32, userID int32) (*models.Note, error) {
-	once.Do(initDB)
-	
-	log.Printf("🔍 Storage GetNoteByID - noteID: %d, userID: %d", noteID, userID)
-	
-	var note models.Note
-	err := dbPool.QueryRow(ctx,
-		"SELECT id, title, content, user_id, created_at, updated_at FROM notes WHERE id = $1 AND user_id = $2",
-		noteID, userID).Scan(&note.ID, &note.Title, &note.Content, &note.UserID, &note.CreatedAt, &note.UpdatedAt)
-		
-	if err != nil {
-		log.Printf("❌ Storage GetNoteByID - DB error: %v", err)
-		return nil, fmt.Errorf("error fetching note: %w", err)
-	}
-
-	log.Printf("✅ Storage GetNoteByID - Found note: ID=%d, Title=%s", note.ID, note.Title)
-	return &note, nil
-}
\ No newline at end of file
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"notes-service/internal/models"
+)
+
+var (
+	ErrNotFound  = errors.New("note not found")
+	ErrForbidden = errors.New("access to note denied")
+	// ErrVersionConflict — заметка изменилась после версии, от которой шла правка
+	ErrVersionConflict = errors.New("note version conflict")
+	// ErrRevisionNotFound — такой версии заметки не было или она уже не хранится
+	ErrRevisionNotFound = errors.New("note revision not found")
+)
+
+// DuplicateNoteError — заметка с этим ClientID уже создана; NoteID — ее id.
+// Заметка могла быть и удалена с тех пор.
+type DuplicateNoteError struct {
+	NoteID int32
+}
+
+func (e *DuplicateNoteError) Error() string {
+	return fmt.Sprintf("note %d was already created with this client id", e.NoteID)
+}
+
+// Сколько последних версий каждой заметки хранится для слияния правок
+const revisionLimit = 50
+
+// NoteRepository — хранилище заметок. Все методы, принимающие userID,
+// проверяют владельца: чужая заметка дает ErrForbidden, отсутствующая — ErrNotFound.
+// baseVersion в UpdateNote и DeleteNote — версия, от которой шла правка: если
+// заметка с тех пор изменилась, ErrVersionConflict; 0 — без проверки.
+type NoteRepository interface {
+	CreateNote(ctx context.Context, note models.Note) (int32, error)
+	GetUserNotes(ctx context.Context, userID int32) ([]models.Note, error)
+	// SearchNotes ищет заметки пользователя, содержащие все слова запроса
+	SearchNotes(ctx context.Context, userID int32, query string) ([]models.Note, error)
+	GetNoteByID(ctx context.Context, noteID int32, userID int32) (*models.Note, error)
+	// UpdateNote возвращает заметку после изменения
+	UpdateNote(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64) (*models.Note, error)
+	// CreateNoteWithin и UpdateNoteWithin — то же, но квота проверяется в одной
+	// транзакции с записью: превышение дает *QuotaError, и запись откатывается
+	CreateNoteWithin(ctx context.Context, note models.Note, quota Quota) (int32, error)
+	UpdateNoteWithin(ctx context.Context, noteID int32, userID int32, title, content string, baseVersion int64, quota Quota) (*models.Note, error)
+	DeleteNote(ctx context.Context, noteID int32, userID int32, baseVersion int64) error
+	// ShareNote открывает заметку пользователю userID для совместного
+	// редактирования, UnshareNote закрывает; оба доступны только владельцу
+	// ownerID, повтор ничего не меняет
+	ShareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error
+	UnshareNote(ctx context.Context, noteID int32, ownerID int32, userID int32) error
+	// GetNoteShares возвращает пользователей, которым владелец открыл заметку
+	GetNoteShares(ctx context.Context, noteID int32, ownerID int32) ([]int32, error)
+	// GetSharedNote — GetNoteByID, который пропускает и тех, кому заметка открыта
+	GetSharedNote(ctx context.Context, noteID int32, userID int32) (*models.Note, error)
+	// GetNoteRevision возвращает заметку в том виде, какой она была в версии version
+	GetNoteRevision(ctx context.Context, noteID int32, userID int32, version int64) (*models.Note, error)
+	// CountNotesByUser возвращает число заметок каждого пользователя (для метрик)
+	CountNotesByUser(ctx context.Context) (map[int32]int, error)
+	// GetUsage возвращает занятое пользователем место для квот
+	GetUsage(ctx context.Context, userID int32) (models.Usage, error)
+	// NoteChanges возвращает заметки пользователя, измененные после курсора
+	// afterSeq, и следы удаленных с тех пор. afterSeq < 0 — первая
+	// синхронизация: все заметки и Reset.
+	NoteChanges(ctx context.Context, userID int32, afterSeq int64) (models.NoteChanges, error)
+	// PruneTombstones удаляет следы заметок, удаленных раньше before
+	PruneTombstones(ctx context.Context, before time.Time) (int64, error)
+}
+
+func NewPostgresPool(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing database config: %w", err)
+	}
+	// Каждый запрос становится дочерним спаном текущей трассировки
+	config.ConnConfig.Tracer = otelpgx.NewTracer()
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating database pool: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Database connection established")
+	return pool, nil
+}
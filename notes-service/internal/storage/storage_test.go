@@ -0,0 +1,127 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"notes-service/internal/storage"
+	"notes-service/internal/storage/storagetest"
+)
+
+// postgresDSNEnv — база для проверок Postgres; без нее они пропускаются.
+// Таблицы заметок очищаются перед каждой проверкой, поэтому база нужна отдельная.
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+func TestMemoryRepository(t *testing.T) {
+	storagetest.RunNoteRepository(t, func(t *testing.T) storage.NoteRepository {
+		return storage.NewMemoryRepository()
+	})
+	storagetest.RunOutbox(t, func(t *testing.T) storagetest.OutboxRepository {
+		return storage.NewMemoryRepository()
+	})
+}
+
+func TestMemoryWebhookStore(t *testing.T) {
+	storagetest.RunWebhookStore(t, func(t *testing.T) storage.WebhookStore {
+		return storage.NewMemoryWebhookStore()
+	})
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	storagetest.RunNoteRepository(t, func(t *testing.T) storage.NoteRepository {
+		return newSQLiteRepository(t, newSQLiteDB(t))
+	})
+	storagetest.RunOutbox(t, func(t *testing.T) storagetest.OutboxRepository {
+		return newSQLiteRepository(t, newSQLiteDB(t))
+	})
+}
+
+func TestSQLiteWebhookStore(t *testing.T) {
+	storagetest.RunWebhookStore(t, func(t *testing.T) storage.WebhookStore {
+		db := newSQLiteDB(t)
+		// Схему создают миграции, которые накатывает репозиторий заметок
+		newSQLiteRepository(t, db)
+		return storage.NewSQLiteWebhookStore(db)
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	pool := newPostgresPool(t)
+	storagetest.RunNoteRepository(t, func(t *testing.T) storage.NoteRepository {
+		resetPostgres(t, pool)
+		return storage.NewPostgresRepository(pool)
+	})
+	storagetest.RunOutbox(t, func(t *testing.T) storagetest.OutboxRepository {
+		resetPostgres(t, pool)
+		return storage.NewPostgresRepository(pool)
+	})
+}
+
+func TestPostgresWebhookStore(t *testing.T) {
+	pool := newPostgresPool(t)
+	storagetest.RunWebhookStore(t, func(t *testing.T) storage.WebhookStore {
+		resetPostgres(t, pool)
+		return storage.NewPostgresWebhookStore(pool)
+	})
+}
+
+func newSQLiteDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := storage.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "notes.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newSQLiteRepository(t *testing.T, db *sql.DB) *storage.SQLiteRepository {
+	t.Helper()
+
+	repo, err := storage.NewSQLiteRepository(context.Background(), db)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %v", err)
+	}
+	return repo
+}
+
+func newPostgresPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skip(postgresDSNEnv + " is not set")
+	}
+	ctx := context.Background()
+	pool, err := storage.NewPostgresPool(ctx, dsn)
+	if err != nil {
+		t.Fatalf("NewPostgresPool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := storage.PostgresMigrator(pool)
+	if err != nil {
+		t.Fatalf("PostgresMigrator: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return pool
+}
+
+func resetPostgres(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+
+	_, err := pool.Exec(context.Background(),
+		`TRUNCATE notes, note_usage, note_outbox, note_tombstones, note_sync_state, note_revisions, note_shares, note_client_ids,
+		 webhooks, webhook_deliveries RESTART IDENTITY CASCADE`)
+	if err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+}
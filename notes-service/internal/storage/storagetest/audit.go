@@ -0,0 +1,81 @@
+package storagetest
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"common/audit"
+	"common/auth"
+	"notes-service/internal/storage"
+)
+
+// testAudit проверяет, что AuditRepository записывает каждое изменение,
+// в том числе слитое, с актором и источником из контекста
+func testAudit(t *testing.T, repo storage.NoteRepository) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: UserA})
+	events := audit.NewMemoryStore()
+	notes := storage.NewAuditRepository(storage.NewMergeRepository(repo), audit.NewRecorder(events, false))
+
+	id, err := notes.CreateNote(ctx, newNote(UserA, "audit", time.Now()))
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	note, err := notes.UpdateNote(ctx, id, UserA, "audit", "one\ntwo\n", 1)
+	if err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	if _, err := notes.UpdateNote(ctx, id, UserA, "audit", "one\ntwo\nthree\n", note.Version); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	// Правка над устаревшей версией сливается и помечается вместе с источником
+	syncCtx := audit.WithDetail(ctx, "sync")
+	if _, err := notes.UpdateNote(syncCtx, id, UserA, "audit", "zero\none\ntwo\n", note.Version); err != nil {
+		t.Fatalf("UpdateNote with stale version: %v", err)
+	}
+	// Фоновое сохранение задает актора без токена
+	collabCtx := audit.WithDetail(audit.WithActor(context.Background(), UserB), "collab")
+	if _, err := notes.UpdateNote(collabCtx, id, UserA, "audit", "collab\n", 0); err != nil {
+		t.Fatalf("UpdateNote from collab: %v", err)
+	}
+	if err := notes.ShareNote(ctx, id, UserA, UserB); err != nil {
+		t.Fatalf("ShareNote: %v", err)
+	}
+	if err := notes.UnshareNote(ctx, id, UserA, UserB); err != nil {
+		t.Fatalf("UnshareNote: %v", err)
+	}
+	// Неудачное изменение в журнал не попадает
+	if err := notes.ShareNote(ctx, id, UserB, UserB); err == nil {
+		t.Fatal("ShareNote by non-owner succeeded")
+	}
+	if err := notes.DeleteNote(ctx, id, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+
+	got, err := events.Query(ctx, audit.Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	want := []audit.Event{
+		{Action: audit.ActionNoteDelete, ActorID: UserA},
+		{Action: audit.ActionNoteUnshare, ActorID: UserA, Detail: "user_id=" + strconv.Itoa(int(UserB))},
+		{Action: audit.ActionNoteShare, ActorID: UserA, Detail: "user_id=" + strconv.Itoa(int(UserB))},
+		{Action: audit.ActionNoteUpdate, ActorID: UserB, Detail: "collab"},
+		{Action: audit.ActionNoteUpdate, ActorID: UserA, Detail: "sync,merged"},
+		{Action: audit.ActionNoteUpdate, ActorID: UserA},
+		{Action: audit.ActionNoteUpdate, ActorID: UserA},
+		{Action: audit.ActionNoteCreate, ActorID: UserA},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d audit events, want %d: %+v", len(got), len(want), got)
+	}
+	target := strconv.Itoa(int(id))
+	for i, e := range got {
+		w := want[i]
+		if e.Action != w.Action || e.ActorID != w.ActorID || e.UserID != UserA || e.TargetID != target || e.Detail != w.Detail {
+			t.Errorf("event %d = %s actor %d user %d target %q detail %q; want %s actor %d user %d target %q detail %q",
+				i, e.Action, e.ActorID, e.UserID, e.TargetID, e.Detail, w.Action, w.ActorID, UserA, target, w.Detail)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"notes-service/internal/storage"
+)
+
+func testRevisions(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	id := mustCreate(t, repo, newNote(UserA, "draft", time.Now()))
+	if _, err := repo.UpdateNote(ctx, id, UserA, "v2", "second", 1); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+
+	first, err := repo.GetNoteRevision(ctx, id, UserA, 1)
+	if err != nil {
+		t.Fatalf("GetNoteRevision(1): %v", err)
+	}
+	if first.Version != 1 || first.Title != "draft" || first.Content != "content of draft" {
+		t.Errorf("GetNoteRevision(1) = %+v, want the created note", first)
+	}
+	second, err := repo.GetNoteRevision(ctx, id, UserA, 2)
+	if err != nil {
+		t.Fatalf("GetNoteRevision(2): %v", err)
+	}
+	if second.Title != "v2" || second.Content != "second" {
+		t.Errorf("GetNoteRevision(2) = %+v, want the updated note", second)
+	}
+
+	if _, err := repo.GetNoteRevision(ctx, id, UserA, 3); !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Errorf("GetNoteRevision of missing version: err = %v, want ErrRevisionNotFound", err)
+	}
+	if _, err := repo.GetNoteRevision(ctx, id, UserB, 1); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("GetNoteRevision by other user: err = %v, want ErrForbidden", err)
+	}
+	if _, err := repo.GetNoteRevision(ctx, id+1000, UserA, 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetNoteRevision of missing note: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testMerge(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	merged := storage.NewMergeRepository(repo)
+	note := newNote(UserA, "title", time.Now())
+	note.Content = "one\ntwo\nthree\n"
+	id := mustCreate(t, repo, note)
+
+	if _, err := merged.UpdateNote(ctx, id, UserA, "title", "one\ntwo\nthree (server)\n", 1); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+
+	// Правки разных строк от версии 1 сливаются
+	updated, err := merged.UpdateNote(ctx, id, UserA, "title", "one (client)\ntwo\nthree\n", 1)
+	if err != nil {
+		t.Fatalf("UpdateNote of other lines: %v", err)
+	}
+	if want := "one (client)\ntwo\nthree (server)\n"; updated.Content != want || updated.Version != 3 {
+		t.Errorf("merged note = version %d %q, want version 3 %q", updated.Version, updated.Content, want)
+	}
+
+	// Правка той же строки — конфликт, заметка не меняется
+	_, err = merged.UpdateNote(ctx, id, UserA, "title", "one\ntwo\nthree (client)\n", 1)
+	var conflict *storage.MergeConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("UpdateNote of the same line: err = %v, want MergeConflictError", err)
+	}
+	if conflict.Conflicts != 1 || conflict.Server.Version != 3 || conflict.Merged.BaseVersion != 3 {
+		t.Errorf("conflict = %+v, want one conflict against version 3", conflict)
+	}
+	if want := "one (client)\ntwo\n<<<<<<< server\nthree (server)\n=======\nthree (client)\n>>>>>>> client\n"; conflict.Merged.Content != want {
+		t.Errorf("merged content = %q, want %q", conflict.Merged.Content, want)
+	}
+	if current, err := repo.GetNoteByID(ctx, id, UserA); err != nil || current.Version != 3 {
+		t.Errorf("note after conflict = %+v, %v, want version 3", current, err)
+	}
+
+	// Без сохраненной базы остается прежний конфликт версий
+	if _, err := merged.UpdateNote(ctx, id, UserA, "title", "x", 99); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("UpdateNote from unknown version: err = %v, want ErrVersionConflict", err)
+	}
+}
@@ -0,0 +1,217 @@
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+// OutboxRepository — хранилище заметок, которое пишет события в outbox
+type OutboxRepository interface {
+	storage.NoteRepository
+	storage.OutboxStore
+}
+
+// OutboxFactory возвращает пустое хранилище для очередной проверки outbox.
+type OutboxFactory func(t *testing.T) OutboxRepository
+
+// RunOutbox — общий набор проверок outbox для реализаций storage.OutboxStore
+func RunOutbox(t *testing.T, newRepo OutboxFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo OutboxRepository)
+	}{
+		{"EventsFollowMutations", testOutboxEventsFollowMutations},
+		{"FailedPublishKeepsEvents", testOutboxFailedPublishKeepsEvents},
+		{"Limit", testOutboxLimit},
+		{"Prune", testOutboxPrune},
+		{"EventsSince", testOutboxEventsSince},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+// collect публикует все события одним проходом и возвращает их
+func collect(t *testing.T, repo OutboxRepository, limit int) []models.NoteEvent {
+	t.Helper()
+	var events []models.NoteEvent
+	n, err := repo.PublishOutbox(context.Background(), limit, func(_ context.Context, batch []models.NoteEvent) error {
+		events = append(events, batch...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PublishOutbox: %v", err)
+	}
+	if n != len(events) {
+		t.Errorf("PublishOutbox = %d, but published %d events", n, len(events))
+	}
+	return events
+}
+
+func testOutboxEventsFollowMutations(t *testing.T, repo OutboxRepository) {
+	ctx := context.Background()
+	id := mustCreate(t, repo, newNote(UserA, "draft", time.Now()))
+	if _, err := repo.UpdateNote(ctx, id, UserA, "final", "new content", 0); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	// Отклоненные изменения событий не дают
+	if _, err := repo.UpdateNote(ctx, id, UserB, "stolen", "", 0); !errors.Is(err, storage.ErrForbidden) {
+		t.Fatalf("UpdateNote by other user: err = %v, want ErrForbidden", err)
+	}
+	if err := repo.DeleteNote(ctx, id+100, UserA, 0); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("DeleteNote of missing note: err = %v, want ErrNotFound", err)
+	}
+	if err := repo.DeleteNote(ctx, id, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+
+	events := collect(t, repo, 100)
+	want := []string{models.NoteCreated, models.NoteUpdated, models.NoteDeleted}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, event := range events {
+		if event.Type != want[i] || event.NoteID != id || event.UserID != UserA {
+			t.Errorf("event %d = %s note %d user %d, want %s note %d user %d",
+				i, event.Type, event.NoteID, event.UserID, want[i], id, UserA)
+		}
+		if i > 0 && event.ID <= events[i-1].ID {
+			t.Errorf("event ids are not increasing: %d after %d", event.ID, events[i-1].ID)
+		}
+	}
+
+	var updated models.Note
+	if err := json.Unmarshal(events[1].Payload, &updated); err != nil {
+		t.Fatalf("update payload: %v", err)
+	}
+	if updated.ID != id || updated.Title != "final" || updated.Content != "new content" {
+		t.Errorf("update payload = %+v, want the note after update", updated)
+	}
+	var deleted map[string]any
+	if err := json.Unmarshal(events[2].Payload, &deleted); err != nil {
+		t.Fatalf("delete payload: %v", err)
+	}
+	if _, ok := deleted["title"]; ok {
+		t.Errorf("delete payload = %s, want only id and user_id", events[2].Payload)
+	}
+
+	if again := collect(t, repo, 100); len(again) != 0 {
+		t.Errorf("published events returned again: %+v", again)
+	}
+}
+
+func testOutboxFailedPublishKeepsEvents(t *testing.T, repo OutboxRepository) {
+	mustCreate(t, repo, newNote(UserA, "first", time.Now()))
+
+	failure := errors.New("redis is down")
+	n, err := repo.PublishOutbox(context.Background(), 100, func(context.Context, []models.NoteEvent) error {
+		return failure
+	})
+	if !errors.Is(err, failure) || n != 0 {
+		t.Fatalf("PublishOutbox with failing publish = %d, %v; want 0 and the publish error", n, err)
+	}
+
+	if events := collect(t, repo, 100); len(events) != 1 || events[0].Type != models.NoteCreated {
+		t.Errorf("events after failed publish = %+v, want the created event", events)
+	}
+}
+
+func testOutboxLimit(t *testing.T, repo OutboxRepository) {
+	for _, title := range []string{"one", "two", "three"} {
+		mustCreate(t, repo, newNote(UserA, title, time.Now()))
+	}
+
+	first := collect(t, repo, 2)
+	rest := collect(t, repo, 2)
+	if len(first) != 2 || len(rest) != 1 {
+		t.Fatalf("batches of %d and %d events, want 2 and 1", len(first), len(rest))
+	}
+	if rest[0].ID <= first[1].ID {
+		t.Errorf("second batch starts at id %d, not after %d", rest[0].ID, first[1].ID)
+	}
+}
+
+func testOutboxPrune(t *testing.T, repo OutboxRepository) {
+	ctx := context.Background()
+	mustCreate(t, repo, newNote(UserA, "published", time.Now()))
+	collect(t, repo, 100)
+	mustCreate(t, repo, newNote(UserA, "pending", time.Now()))
+
+	// Неопубликованное событие остается, даже если оно старше границы
+	pruned, err := repo.PruneOutbox(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("PruneOutbox: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("PruneOutbox = %d, want 1", pruned)
+	}
+
+	events := collect(t, repo, 100)
+	if len(events) != 1 {
+		t.Fatalf("got %d events after prune, want the pending one", len(events))
+	}
+	var note models.Note
+	if err := json.Unmarshal(events[0].Payload, &note); err != nil || note.Title != "pending" {
+		t.Errorf("event after prune = %s, %v; want the pending note", events[0].Payload, err)
+	}
+}
+
+func testOutboxEventsSince(t *testing.T, repo OutboxRepository) {
+	ctx := context.Background()
+	if oldest, err := repo.OldestNoteEventID(ctx); err != nil || oldest != 0 {
+		t.Fatalf("OldestNoteEventID of empty outbox = %d, %v; want 0", oldest, err)
+	}
+	if latest, err := repo.LatestNoteEventID(ctx); err != nil || latest != 0 {
+		t.Fatalf("LatestNoteEventID of empty outbox = %d, %v; want 0", latest, err)
+	}
+
+	first := mustCreate(t, repo, newNote(UserA, "first", time.Now()))
+	mustCreate(t, repo, newNote(UserB, "someone else's", time.Now()))
+	second := mustCreate(t, repo, newNote(UserA, "second", time.Now()))
+
+	all, err := repo.NoteEventsSince(ctx, UserA, 0, 100)
+	if err != nil {
+		t.Fatalf("NoteEventsSince: %v", err)
+	}
+	if len(all) != 2 || all[0].NoteID != first || all[1].NoteID != second {
+		t.Fatalf("NoteEventsSince(UserA, 0) = %+v, want events of notes %d and %d", all, first, second)
+	}
+
+	oldest, err := repo.OldestNoteEventID(ctx)
+	if err != nil || oldest != all[0].ID {
+		t.Errorf("OldestNoteEventID = %d, %v; want %d", oldest, err, all[0].ID)
+	}
+
+	rest, err := repo.NoteEventsSince(ctx, UserA, all[0].ID, 100)
+	if err != nil || len(rest) != 1 || rest[0].ID != all[1].ID {
+		t.Errorf("NoteEventsSince after first = %+v, %v; want only the second event", rest, err)
+	}
+	limited, err := repo.NoteEventsSince(ctx, UserA, 0, 1)
+	if err != nil || len(limited) != 1 || limited[0].ID != all[0].ID {
+		t.Errorf("NoteEventsSince with limit 1 = %+v, %v; want the first event", limited, err)
+	}
+
+	// Последний id переживает удаление событий: по нему видно, что клиент отстал
+	if latest, err := repo.LatestNoteEventID(ctx); err != nil || latest != all[1].ID {
+		t.Errorf("LatestNoteEventID = %d, %v; want %d", latest, err, all[1].ID)
+	}
+	collect(t, repo, 100)
+	if _, err := repo.PruneOutbox(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PruneOutbox: %v", err)
+	}
+	if oldest, err := repo.OldestNoteEventID(ctx); err != nil || oldest != 0 {
+		t.Errorf("OldestNoteEventID after prune = %d, %v; want 0", oldest, err)
+	}
+	if latest, err := repo.LatestNoteEventID(ctx); err != nil || latest != all[1].ID {
+		t.Errorf("LatestNoteEventID after prune = %d, %v; want %d", latest, err, all[1].ID)
+	}
+}
@@ -0,0 +1,500 @@
+// Package storagetest содержит общий набор проверок, которому должна
+// соответствовать каждая реализация storage.NoteRepository.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+// Пользователи, от имени которых работают проверки. Для хранилищ с внешним
+// ключом на users (Postgres) они должны существовать заранее.
+const (
+	UserA int32 = 1
+	UserB int32 = 2
+)
+
+// Factory возвращает пустое хранилище для очередной проверки.
+type Factory func(t *testing.T) storage.NoteRepository
+
+func RunNoteRepository(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo storage.NoteRepository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"ListOrderAndIsolation", testListOrderAndIsolation},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"NotFound", testNotFound},
+		{"Forbidden", testForbidden},
+		{"Search", testSearch},
+		{"CountByUser", testCountByUser},
+		{"Usage", testUsage},
+		{"Quota", testQuota},
+		{"ConcurrentQuota", testConcurrentQuota},
+		{"Versions", testVersions},
+		{"Changes", testChanges},
+		{"ChangesAfterPrune", testChangesAfterPrune},
+		{"ClientIDs", testClientIDs},
+		{"Revisions", testRevisions},
+		{"Merge", testMerge},
+		{"Shares", testShares},
+		{"Audit", testAudit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func newNote(userID int32, title string, createdAt time.Time) models.Note {
+	return models.Note{
+		Title:     title,
+		Content:   "content of " + title,
+		UserID:    userID,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+func mustCreate(t *testing.T, repo storage.NoteRepository, note models.Note) int32 {
+	t.Helper()
+
+	id, err := repo.CreateNote(context.Background(), note)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	if id <= 0 {
+		t.Fatalf("CreateNote returned id %d, want positive", id)
+	}
+	return id
+}
+
+func testCreateAndGet(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	id := mustCreate(t, repo, newNote(UserA, "first", created))
+
+	note, err := repo.GetNoteByID(ctx, id, UserA)
+	if err != nil {
+		t.Fatalf("GetNoteByID: %v", err)
+	}
+	if note.ID != id || note.Title != "first" || note.Content != "content of first" || note.UserID != UserA {
+		t.Errorf("GetNoteByID = %+v, want the created note", note)
+	}
+	if !note.CreatedAt.Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", note.CreatedAt, created)
+	}
+
+	other := mustCreate(t, repo, newNote(UserA, "second", created))
+	if other == id {
+		t.Errorf("two notes got the same id %d", id)
+	}
+}
+
+func testListOrderAndIsolation(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	oldest := mustCreate(t, repo, newNote(UserA, "oldest", base))
+	newest := mustCreate(t, repo, newNote(UserA, "newest", base.Add(2*time.Minute)))
+	middle := mustCreate(t, repo, newNote(UserA, "middle", base.Add(time.Minute)))
+	mustCreate(t, repo, newNote(UserB, "someone else's", base.Add(3*time.Minute)))
+
+	notes, err := repo.GetUserNotes(ctx, UserA)
+	if err != nil {
+		t.Fatalf("GetUserNotes: %v", err)
+	}
+
+	want := []int32{newest, middle, oldest}
+	if len(notes) != len(want) {
+		t.Fatalf("GetUserNotes returned %d notes, want %d", len(notes), len(want))
+	}
+	for i, id := range want {
+		if notes[i].ID != id {
+			t.Errorf("notes[%d].ID = %d, want %d (newest first)", i, notes[i].ID, id)
+		}
+		if notes[i].UserID != UserA {
+			t.Errorf("notes[%d] belongs to user %d", i, notes[i].UserID)
+		}
+	}
+
+	empty, err := repo.GetUserNotes(ctx, 999999)
+	if err != nil {
+		t.Fatalf("GetUserNotes for user without notes: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("GetUserNotes for user without notes returned %d notes", len(empty))
+	}
+}
+
+func testUpdate(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	id := mustCreate(t, repo, newNote(UserA, "draft", created))
+
+	if _, err := repo.UpdateNote(ctx, id, UserA, "final", "new content", 0); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+
+	note, err := repo.GetNoteByID(ctx, id, UserA)
+	if err != nil {
+		t.Fatalf("GetNoteByID: %v", err)
+	}
+	if note.Title != "final" || note.Content != "new content" {
+		t.Errorf("after update got title %q content %q", note.Title, note.Content)
+	}
+	if !note.UpdatedAt.After(created) {
+		t.Errorf("UpdatedAt = %v, want later than %v", note.UpdatedAt, created)
+	}
+	if !note.CreatedAt.Equal(created) {
+		t.Errorf("CreatedAt changed to %v", note.CreatedAt)
+	}
+}
+
+func testDelete(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	id := mustCreate(t, repo, newNote(UserA, "doomed", time.Now()))
+	kept := mustCreate(t, repo, newNote(UserA, "kept", time.Now()))
+
+	if err := repo.DeleteNote(ctx, id, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+
+	if _, err := repo.GetNoteByID(ctx, id, UserA); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetNoteByID after delete: err = %v, want ErrNotFound", err)
+	}
+	if err := repo.DeleteNote(ctx, id, UserA, 0); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("second DeleteNote: err = %v, want ErrNotFound", err)
+	}
+
+	notes, err := repo.GetUserNotes(ctx, UserA)
+	if err != nil {
+		t.Fatalf("GetUserNotes: %v", err)
+	}
+	if len(notes) != 1 || notes[0].ID != kept {
+		t.Errorf("GetUserNotes after delete = %+v, want only note %d", notes, kept)
+	}
+}
+
+func testNotFound(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	const missing int32 = 987654
+
+	if _, err := repo.GetNoteByID(ctx, missing, UserA); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetNoteByID: err = %v, want ErrNotFound", err)
+	}
+	if _, err := repo.UpdateNote(ctx, missing, UserA, "t", "c", 0); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("UpdateNote: err = %v, want ErrNotFound", err)
+	}
+	if err := repo.DeleteNote(ctx, missing, UserA, 0); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("DeleteNote: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testForbidden(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	id := mustCreate(t, repo, newNote(UserA, "private", time.Now()))
+
+	if _, err := repo.GetNoteByID(ctx, id, UserB); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("GetNoteByID by other user: err = %v, want ErrForbidden", err)
+	}
+	if _, err := repo.UpdateNote(ctx, id, UserB, "hijacked", "x", 0); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("UpdateNote by other user: err = %v, want ErrForbidden", err)
+	}
+	if err := repo.DeleteNote(ctx, id, UserB, 0); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("DeleteNote by other user: err = %v, want ErrForbidden", err)
+	}
+
+	note, err := repo.GetNoteByID(ctx, id, UserA)
+	if err != nil {
+		t.Fatalf("GetNoteByID by owner: %v", err)
+	}
+	if note.Title != "private" {
+		t.Errorf("note was modified by another user: %+v", note)
+	}
+}
+
+func testSearch(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	now := time.Now()
+
+	create := func(userID int32, title, content string) int32 {
+		return mustCreate(t, repo, models.Note{Title: title, Content: content, UserID: userID, CreatedAt: now, UpdatedAt: now})
+	}
+
+	groceries := create(UserA, "Groceries", "Buy milk and bread")
+	recipe := create(UserA, "Recipe", "Bread: flour, water, salt")
+	create(UserA, "Meeting", "Discuss the roadmap")
+	create(UserB, "Bread", "someone else's bread")
+	russian := create(UserA, "Покупки", "Купить хлеб и молоко")
+
+	cases := []struct {
+		query string
+		want  []int32
+	}{
+		{"bread", []int32{groceries, recipe}},
+		{"BREAD milk", []int32{groceries}},
+		{"milk, bread!", []int32{groceries}},
+		{"хлеб", []int32{russian}},
+		{"missing", nil},
+		{"   ", nil},
+	}
+
+	for _, c := range cases {
+		notes, err := repo.SearchNotes(ctx, UserA, c.query)
+		if err != nil {
+			t.Fatalf("SearchNotes(%q): %v", c.query, err)
+		}
+
+		got := make(map[int32]bool)
+		for _, note := range notes {
+			if note.UserID != UserA {
+				t.Errorf("SearchNotes(%q) returned note of user %d", c.query, note.UserID)
+			}
+			got[note.ID] = true
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("SearchNotes(%q) returned %d notes, want %d", c.query, len(got), len(c.want))
+			continue
+		}
+		for _, id := range c.want {
+			if !got[id] {
+				t.Errorf("SearchNotes(%q) is missing note %d", c.query, id)
+			}
+		}
+	}
+
+	if _, err := repo.UpdateNote(ctx, recipe, UserA, "Recipe", "Pancakes", 0); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	if err := repo.DeleteNote(ctx, groceries, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	notes, err := repo.SearchNotes(ctx, UserA, "bread")
+	if err != nil {
+		t.Fatalf("SearchNotes after changes: %v", err)
+	}
+	if len(notes) != 0 {
+		t.Errorf("SearchNotes after update and delete returned %+v, want nothing", notes)
+	}
+}
+
+func testCountByUser(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	mustCreate(t, repo, newNote(UserA, "a1", now))
+	mustCreate(t, repo, newNote(UserA, "a2", now))
+	deleted := mustCreate(t, repo, newNote(UserA, "a3", now))
+	mustCreate(t, repo, newNote(UserB, "b1", now))
+	if err := repo.DeleteNote(ctx, deleted, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+
+	counts, err := repo.CountNotesByUser(ctx)
+	if err != nil {
+		t.Fatalf("CountNotesByUser: %v", err)
+	}
+	if len(counts) != 2 || counts[UserA] != 2 || counts[UserB] != 1 {
+		t.Errorf("CountNotesByUser = %v, want map[%d:2 %d:1]", counts, UserA, UserB)
+	}
+}
+
+func testUsage(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	usage, err := repo.GetUsage(ctx, UserA)
+	if err != nil {
+		t.Fatalf("GetUsage: %v", err)
+	}
+	if usage != (models.Usage{}) {
+		t.Errorf("GetUsage for new user = %+v, want zero", usage)
+	}
+
+	// "ё" занимает два байта: квота считается в байтах, а не в символах
+	first := newNote(UserA, "ёж", now)
+	first.Content = "abc"
+	firstID := mustCreate(t, repo, first)
+	second := mustCreate(t, repo, newNote(UserA, "x", now))
+	mustCreate(t, repo, newNote(UserB, "other", now))
+
+	if _, err := repo.UpdateNote(ctx, firstID, UserA, "ёж", "abcdef", 0); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	if err := repo.DeleteNote(ctx, second, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+
+	usage, err = repo.GetUsage(ctx, UserA)
+	if err != nil {
+		t.Fatalf("GetUsage: %v", err)
+	}
+	want := models.Usage{Notes: 1, Bytes: models.NoteSize("ёж", "abcdef")}
+	if usage != want {
+		t.Errorf("GetUsage = %+v, want %+v", usage, want)
+	}
+}
+
+func testQuota(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	// newNote(UserA, "a", ...) занимает 13 байт
+	quota := storage.Quota{MaxNotes: 2, MaxBytes: 30}
+
+	first, err := repo.CreateNoteWithin(ctx, newNote(UserA, "a", now), quota)
+	if err != nil {
+		t.Fatalf("CreateNoteWithin: %v", err)
+	}
+	if _, err := repo.CreateNoteWithin(ctx, newNote(UserA, "b", now), quota); err != nil {
+		t.Fatalf("CreateNoteWithin: %v", err)
+	}
+	var quotaErr *storage.QuotaError
+	if _, err := repo.CreateNoteWithin(ctx, newNote(UserA, "c", now), quota); !errors.As(err, &quotaErr) || quotaErr.Resource != "notes" {
+		t.Fatalf("CreateNoteWithin over notes quota: err = %v, want notes *QuotaError", err)
+	}
+	// Квота своя у каждого пользователя
+	if _, err := repo.CreateNoteWithin(ctx, newNote(UserB, "c", now), quota); err != nil {
+		t.Fatalf("CreateNoteWithin for another user: %v", err)
+	}
+
+	_, err = repo.UpdateNoteWithin(ctx, first, UserA, "a", "content of a, but longer", 0, quota)
+	if !errors.As(err, &quotaErr) || quotaErr.Resource != "bytes" {
+		t.Fatalf("UpdateNoteWithin over bytes quota: err = %v, want bytes *QuotaError", err)
+	}
+	// Отказ откатывает запись целиком
+	note, err := repo.GetNoteByID(ctx, first, UserA)
+	if err != nil {
+		t.Fatalf("GetNoteByID: %v", err)
+	}
+	if note.Content != "content of a" || note.Version != 1 {
+		t.Errorf("note after rejected update = %q v%d, want unchanged", note.Content, note.Version)
+	}
+
+	// Конфликт версий важнее квоты
+	if _, err := repo.UpdateNoteWithin(ctx, first, UserA, "a", "content of a, but longer", 5, quota); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("UpdateNoteWithin with stale version: err = %v, want ErrVersionConflict", err)
+	}
+	// Уменьшить заметку можно всегда
+	if _, err := repo.UpdateNoteWithin(ctx, first, UserA, "a", "", 0, storage.Quota{MaxBytes: 1}); err != nil {
+		t.Errorf("UpdateNoteWithin shrinking note: %v", err)
+	}
+}
+
+func testConcurrentQuota(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	quota := storage.Quota{MaxNotes: 3}
+
+	const attempts = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.CreateNoteWithin(ctx, newNote(UserA, fmt.Sprintf("note %d", i), now), quota)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		var quotaErr *storage.QuotaError
+		switch {
+		case err == nil:
+			created++
+		case !errors.As(err, &quotaErr):
+			t.Errorf("CreateNoteWithin: %v", err)
+		}
+	}
+	if created != int(quota.MaxNotes) {
+		t.Errorf("created %d notes, want exactly %d", created, quota.MaxNotes)
+	}
+
+	usage, err := repo.GetUsage(ctx, UserA)
+	if err != nil {
+		t.Fatalf("GetUsage: %v", err)
+	}
+	if int64(usage.Notes) != quota.MaxNotes {
+		t.Errorf("GetUsage = %+v, want %d notes", usage, quota.MaxNotes)
+	}
+}
+
+func testShares(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	const userC int32 = 3
+	id := mustCreate(t, repo, newNote(UserA, "shared", time.Now()))
+
+	if _, err := repo.GetSharedNote(ctx, id, UserB); !errors.Is(err, storage.ErrForbidden) {
+		t.Fatalf("GetSharedNote before sharing: err = %v, want ErrForbidden", err)
+	}
+	// Повтор не ошибка
+	for range 2 {
+		if err := repo.ShareNote(ctx, id, UserA, UserB); err != nil {
+			t.Fatalf("ShareNote: %v", err)
+		}
+	}
+	if err := repo.ShareNote(ctx, id, UserA, userC); err != nil {
+		t.Fatalf("ShareNote: %v", err)
+	}
+	// Делиться чужой заметкой нельзя, даже если она открыта тебе
+	if err := repo.ShareNote(ctx, id, UserB, userC); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("ShareNote by non-owner: err = %v, want ErrForbidden", err)
+	}
+	if _, err := repo.GetNoteShares(ctx, id, UserB); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("GetNoteShares by non-owner: err = %v, want ErrForbidden", err)
+	}
+
+	users, err := repo.GetNoteShares(ctx, id, UserA)
+	if err != nil {
+		t.Fatalf("GetNoteShares: %v", err)
+	}
+	if len(users) != 2 || users[0] != UserB || users[1] != userC {
+		t.Errorf("GetNoteShares = %v, want [%d %d]", users, UserB, userC)
+	}
+	for _, userID := range []int32{UserA, UserB} {
+		note, err := repo.GetSharedNote(ctx, id, userID)
+		if err != nil || note.ID != id || note.UserID != UserA {
+			t.Errorf("GetSharedNote(user %d) = %+v, %v; want note %d of user %d", userID, note, err, id, UserA)
+		}
+	}
+	// Открытая заметка остается чужой для остальных методов
+	if _, err := repo.GetNoteByID(ctx, id, UserB); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("GetNoteByID by shared user: err = %v, want ErrForbidden", err)
+	}
+
+	for range 2 {
+		if err := repo.UnshareNote(ctx, id, UserA, UserB); err != nil {
+			t.Fatalf("UnshareNote: %v", err)
+		}
+	}
+	if _, err := repo.GetSharedNote(ctx, id, UserB); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("GetSharedNote after unsharing: err = %v, want ErrForbidden", err)
+	}
+
+	// Доступ удаляется вместе с заметкой
+	if err := repo.DeleteNote(ctx, id, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	if _, err := repo.GetSharedNote(ctx, id, userC); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetSharedNote of deleted note: err = %v, want ErrNotFound", err)
+	}
+	if err := repo.ShareNote(ctx, id, UserA, userC); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("ShareNote of deleted note: err = %v, want ErrNotFound", err)
+	}
+}
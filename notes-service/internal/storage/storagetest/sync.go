@@ -0,0 +1,208 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+func testVersions(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	id := mustCreate(t, repo, newNote(UserA, "draft", time.Now()))
+
+	note, err := repo.GetNoteByID(ctx, id, UserA)
+	if err != nil {
+		t.Fatalf("GetNoteByID: %v", err)
+	}
+	if note.Version != 1 {
+		t.Fatalf("new note version = %d, want 1", note.Version)
+	}
+
+	updated, err := repo.UpdateNote(ctx, id, UserA, "v2", "second", 1)
+	if err != nil {
+		t.Fatalf("UpdateNote from current version: %v", err)
+	}
+	if updated.Version != 2 || updated.Title != "v2" || updated.Content != "second" {
+		t.Errorf("UpdateNote returned %+v, want version 2 with new text", updated)
+	}
+
+	if _, err := repo.UpdateNote(ctx, id, UserA, "stale", "stale", 1); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("UpdateNote from stale version: err = %v, want ErrVersionConflict", err)
+	}
+	if err := repo.DeleteNote(ctx, id, UserA, 1); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("DeleteNote from stale version: err = %v, want ErrVersionConflict", err)
+	}
+	if _, err := repo.UpdateNote(ctx, id, UserB, "hijacked", "x", 2); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("UpdateNote by other user: err = %v, want ErrForbidden", err)
+	}
+
+	// Без базовой версии правка применяется поверх любой
+	unconditional, err := repo.UpdateNote(ctx, id, UserA, "v3", "third", 0)
+	if err != nil {
+		t.Fatalf("UpdateNote without base version: %v", err)
+	}
+	if unconditional.Version != 3 {
+		t.Errorf("version after unconditional update = %d, want 3", unconditional.Version)
+	}
+
+	if err := repo.DeleteNote(ctx, id, UserA, 3); err != nil {
+		t.Fatalf("DeleteNote from current version: %v", err)
+	}
+	if _, err := repo.GetNoteByID(ctx, id, UserA); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetNoteByID after delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func mustChanges(t *testing.T, repo storage.NoteRepository, userID int32, afterSeq int64) models.NoteChanges {
+	t.Helper()
+
+	changes, err := repo.NoteChanges(context.Background(), userID, afterSeq)
+	if err != nil {
+		t.Fatalf("NoteChanges: %v", err)
+	}
+	return changes
+}
+
+func noteIDs(notes []models.Note) []int32 {
+	ids := make([]int32, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID
+	}
+	return ids
+}
+
+func testChanges(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	first := mustCreate(t, repo, newNote(UserA, "first", time.Now()))
+	second := mustCreate(t, repo, newNote(UserA, "second", time.Now()))
+	mustCreate(t, repo, newNote(UserB, "foreign", time.Now()))
+
+	initial := mustChanges(t, repo, UserA, -1)
+	if !initial.Reset || len(initial.Deleted) != 0 {
+		t.Errorf("first sync: reset = %v, deleted = %+v; want reset without tombstones", initial.Reset, initial.Deleted)
+	}
+	if ids := noteIDs(initial.Notes); len(ids) != 2 || ids[0] != first || ids[1] != second {
+		t.Fatalf("first sync notes = %v, want [%d %d]", ids, first, second)
+	}
+
+	if again := mustChanges(t, repo, UserA, initial.Seq); again.Reset || len(again.Notes) != 0 || len(again.Deleted) != 0 || again.Seq != initial.Seq {
+		t.Errorf("sync without changes = %+v, want nothing new at seq %d", again, initial.Seq)
+	}
+
+	// Измененная заметка приходит после созданной позже нее
+	third := mustCreate(t, repo, newNote(UserA, "third", time.Now()))
+	if _, err := repo.UpdateNote(ctx, first, UserA, "first", "edited", 0); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	if err := repo.DeleteNote(ctx, second, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+
+	delta := mustChanges(t, repo, UserA, initial.Seq)
+	if delta.Reset {
+		t.Error("delta sync: reset = true, want false")
+	}
+	if ids := noteIDs(delta.Notes); len(ids) != 2 || ids[0] != third || ids[1] != first {
+		t.Errorf("delta notes = %v, want [%d %d]", ids, third, first)
+	} else if delta.Notes[1].Content != "edited" || delta.Notes[1].Version != 2 {
+		t.Errorf("updated note in delta = %+v, want edited content at version 2", delta.Notes[1])
+	}
+	if len(delta.Deleted) != 1 || delta.Deleted[0].ID != second || delta.Deleted[0].Version != 1 {
+		t.Errorf("delta tombstones = %+v, want note %d at version 1", delta.Deleted, second)
+	}
+	if delta.Seq <= initial.Seq {
+		t.Errorf("delta seq = %d, want more than %d", delta.Seq, initial.Seq)
+	}
+
+	if again := mustChanges(t, repo, UserA, delta.Seq); len(again.Notes) != 0 || len(again.Deleted) != 0 {
+		t.Errorf("sync after delta = %+v, want nothing new", again)
+	}
+
+	// Изменения другого пользователя не сдвигают чужую выдачу
+	foreign := mustChanges(t, repo, UserB, -1)
+	if len(foreign.Notes) != 1 || foreign.Notes[0].UserID != UserB {
+		t.Errorf("other user's first sync = %+v, want only their note", foreign.Notes)
+	}
+}
+
+func testChangesAfterPrune(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	kept := mustCreate(t, repo, newNote(UserA, "kept", time.Now()))
+	doomed := mustCreate(t, repo, newNote(UserA, "doomed", time.Now()))
+	mustCreate(t, repo, newNote(UserB, "foreign", time.Now()))
+
+	stale := mustChanges(t, repo, UserA, -1).Seq
+	foreignSeq := mustChanges(t, repo, UserB, -1).Seq
+	if err := repo.DeleteNote(ctx, doomed, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+
+	if pruned, err := repo.PruneTombstones(ctx, time.Now().Add(-time.Hour)); err != nil || pruned != 0 {
+		t.Fatalf("PruneTombstones of fresh tombstones = %d, %v; want 0", pruned, err)
+	}
+	if pruned, err := repo.PruneTombstones(ctx, time.Now().Add(time.Hour)); err != nil || pruned != 1 {
+		t.Fatalf("PruneTombstones = %d, %v; want 1", pruned, err)
+	}
+
+	// Клиент, не получивший удаление, начинает заново со всеми заметками
+	reset := mustChanges(t, repo, UserA, stale)
+	if !reset.Reset {
+		t.Fatal("sync from before pruned tombstone: reset = false, want true")
+	}
+	if ids := noteIDs(reset.Notes); len(ids) != 1 || ids[0] != kept {
+		t.Errorf("notes after reset = %v, want [%d]", ids, kept)
+	}
+	if again := mustChanges(t, repo, UserA, reset.Seq); again.Reset {
+		t.Error("sync after reset: reset = true, want false")
+	}
+
+	// Удаление у одного пользователя не сбрасывает других
+	if other := mustChanges(t, repo, UserB, foreignSeq); other.Reset || len(other.Notes) != 0 {
+		t.Errorf("other user's sync after prune = %+v, want no reset and no changes", other)
+	}
+}
+
+func testClientIDs(t *testing.T, repo storage.NoteRepository) {
+	ctx := context.Background()
+	note := newNote(UserA, "offline", time.Now())
+	note.ClientID = "draft-1"
+	id := mustCreate(t, repo, note)
+
+	// Повтор того же создания не создает вторую заметку и не занимает квоту
+	var duplicate *storage.DuplicateNoteError
+	if _, err := repo.CreateNoteWithin(ctx, note, storage.Quota{MaxNotes: 1}); !errors.As(err, &duplicate) || duplicate.NoteID != id {
+		t.Fatalf("CreateNote replay: err = %v, want DuplicateNoteError for note %d", err, id)
+	}
+	if usage, err := repo.GetUsage(ctx, UserA); err != nil || usage.Notes != 1 {
+		t.Errorf("GetUsage after replay = %+v, %v; want 1 note", usage, err)
+	}
+
+	// Метка своя у каждого пользователя, а без метки заметки не сравниваются
+	foreign := note
+	foreign.UserID = UserB
+	if foreignID := mustCreate(t, repo, foreign); foreignID == id {
+		t.Errorf("CreateNote with other user's client id returned note %d", id)
+	}
+	plain := newNote(UserA, "plain", time.Now())
+	if mustCreate(t, repo, plain) == mustCreate(t, repo, plain) {
+		t.Error("CreateNote without client id returned the same note twice")
+	}
+
+	// Пока жив след удаленной заметки, повтор не воскрешает ее
+	if err := repo.DeleteNote(ctx, id, UserA, 0); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	if _, err := repo.CreateNote(ctx, note); !errors.As(err, &duplicate) || duplicate.NoteID != id {
+		t.Fatalf("CreateNote replay after delete: err = %v, want DuplicateNoteError for note %d", err, id)
+	}
+	if _, err := repo.PruneTombstones(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PruneTombstones: %v", err)
+	}
+	if again := mustCreate(t, repo, note); again == id {
+		t.Errorf("CreateNote after pruning returned deleted note %d", id)
+	}
+}
@@ -0,0 +1,221 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+// WebhookFactory возвращает пустое хранилище вебхуков для очередной проверки.
+type WebhookFactory func(t *testing.T) storage.WebhookStore
+
+// RunWebhookStore — общий набор проверок для реализаций storage.WebhookStore
+func RunWebhookStore(t *testing.T, newStore WebhookFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store storage.WebhookStore)
+	}{
+		{"CreateAndGet", testWebhookCreateAndGet},
+		{"Isolation", testWebhookIsolation},
+		{"EnqueueBySubscription", testWebhookEnqueueBySubscription},
+		{"ClaimAndFinish", testWebhookClaimAndFinish},
+		{"DeleteRemovesDeliveries", testWebhookDeleteRemovesDeliveries},
+		{"Prune", testWebhookPrune},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func mustCreateWebhook(t *testing.T, store storage.WebhookStore, userID int32, events ...string) *models.Webhook {
+	t.Helper()
+	hook, err := store.CreateWebhook(context.Background(), models.Webhook{
+		UserID: userID,
+		URL:    "https://example.com/hook",
+		Events: events,
+		Secret: "secret",
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	return hook
+}
+
+func testWebhookCreateAndGet(t *testing.T, store storage.WebhookStore) {
+	ctx := context.Background()
+	hook := mustCreateWebhook(t, store, UserA, models.WebhookEventNoteCreated, models.WebhookEventNoteDeleted)
+
+	got, err := store.GetWebhook(ctx, hook.ID, UserA)
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if got.URL != hook.URL || got.Secret != "secret" || len(got.Events) != 2 {
+		t.Errorf("GetWebhook = %+v, want url, secret and two events", got)
+	}
+
+	hooks, err := store.GetUserWebhooks(ctx, UserA)
+	if err != nil {
+		t.Fatalf("GetUserWebhooks: %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].ID != hook.ID {
+		t.Fatalf("GetUserWebhooks = %+v, want webhook %d", hooks, hook.ID)
+	}
+	if hooks[0].Secret != "" {
+		t.Error("GetUserWebhooks must not return secrets")
+	}
+}
+
+func testWebhookIsolation(t *testing.T, store storage.WebhookStore) {
+	ctx := context.Background()
+	hook := mustCreateWebhook(t, store, UserA, models.WebhookEventNoteCreated)
+
+	if _, err := store.GetWebhook(ctx, hook.ID, UserB); !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("GetWebhook by other user: err = %v, want ErrWebhookNotFound", err)
+	}
+	if err := store.DeleteWebhook(ctx, hook.ID, UserB); !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("DeleteWebhook by other user: err = %v, want ErrWebhookNotFound", err)
+	}
+	if hooks, err := store.GetUserWebhooks(ctx, UserB); err != nil || len(hooks) != 0 {
+		t.Errorf("GetUserWebhooks(UserB) = %v, %v; want none", hooks, err)
+	}
+}
+
+func testWebhookEnqueueBySubscription(t *testing.T, store storage.WebhookStore) {
+	ctx := context.Background()
+	created := mustCreateWebhook(t, store, UserA, models.WebhookEventNoteCreated)
+	deleted := mustCreateWebhook(t, store, UserA, models.WebhookEventNoteDeleted)
+	other := mustCreateWebhook(t, store, UserB, models.WebhookEventNoteCreated)
+
+	// Повтор того же события outbox (relay переотправил пачку) доставок не добавляет
+	for range 2 {
+		if err := store.EnqueueEvent(ctx, 1, UserA, models.WebhookEventNoteCreated, []byte(`{"n":1}`)); err != nil {
+			t.Fatalf("EnqueueEvent: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		hook *models.Webhook
+		want int
+	}{{created, 1}, {deleted, 0}, {other, 0}} {
+		deliveries, err := store.GetDeliveries(ctx, tc.hook.ID, 10)
+		if err != nil {
+			t.Fatalf("GetDeliveries: %v", err)
+		}
+		if len(deliveries) != tc.want {
+			t.Errorf("webhook %v: %d deliveries, want %d", tc.hook.Events, len(deliveries), tc.want)
+		}
+	}
+}
+
+func testWebhookClaimAndFinish(t *testing.T, store storage.WebhookStore) {
+	ctx := context.Background()
+	hook := mustCreateWebhook(t, store, UserA, models.WebhookEventNoteCreated)
+	id, err := store.EnqueueDelivery(ctx, hook.ID, models.WebhookEventPing, []byte(`{"ping":true}`))
+	if err != nil {
+		t.Fatalf("EnqueueDelivery: %v", err)
+	}
+
+	now := time.Now().Add(time.Second)
+	claimed, err := store.ClaimDeliveries(ctx, now, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimDeliveries: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != id || claimed[0].URL != hook.URL || claimed[0].Secret != "secret" {
+		t.Fatalf("ClaimDeliveries = %+v, want delivery %d with url and secret", claimed, id)
+	}
+	if string(claimed[0].Payload) != `{"ping":true}` {
+		t.Errorf("payload = %s", claimed[0].Payload)
+	}
+
+	// Пока действует lease, доставка не выдается повторно
+	if again, err := store.ClaimDeliveries(ctx, now, time.Minute, 10); err != nil || len(again) != 0 {
+		t.Fatalf("second ClaimDeliveries = %v, %v; want none during lease", again, err)
+	}
+
+	d := claimed[0]
+	next := now.Add(time.Hour)
+	d.Attempts, d.ResponseStatus, d.LastError, d.NextAttemptAt = 1, 500, "unexpected response status 500", &next
+	if err := store.FinishAttempt(ctx, d); err != nil {
+		t.Fatalf("FinishAttempt: %v", err)
+	}
+	if again, err := store.ClaimDeliveries(ctx, now.Add(2*time.Minute), time.Minute, 10); err != nil || len(again) != 0 {
+		t.Fatalf("ClaimDeliveries before retry = %v, %v; want none", again, err)
+	}
+
+	retry, err := store.ClaimDeliveries(ctx, next.Add(time.Second), time.Minute, 10)
+	if err != nil || len(retry) != 1 || retry[0].Attempts != 1 {
+		t.Fatalf("ClaimDeliveries at retry = %+v, %v; want one delivery with 1 attempt", retry, err)
+	}
+
+	d = retry[0]
+	delivered := time.Now()
+	d.Status, d.Attempts, d.ResponseStatus, d.LastError, d.NextAttemptAt, d.DeliveredAt = models.DeliverySucceeded, 2, 200, "", nil, &delivered
+	if err := store.FinishAttempt(ctx, d); err != nil {
+		t.Fatalf("FinishAttempt: %v", err)
+	}
+
+	log, err := store.GetDeliveries(ctx, hook.ID, 10)
+	if err != nil || len(log) != 1 {
+		t.Fatalf("GetDeliveries = %v, %v; want one delivery", log, err)
+	}
+	if log[0].Status != models.DeliverySucceeded || log[0].Attempts != 2 || log[0].ResponseStatus != 200 || log[0].DeliveredAt == nil {
+		t.Errorf("delivery log = %+v, want succeeded after 2 attempts", log[0])
+	}
+	if again, err := store.ClaimDeliveries(ctx, next.Add(time.Hour), time.Minute, 10); err != nil || len(again) != 0 {
+		t.Errorf("ClaimDeliveries after success = %v, %v; want none", again, err)
+	}
+}
+
+func testWebhookDeleteRemovesDeliveries(t *testing.T, store storage.WebhookStore) {
+	ctx := context.Background()
+	hook := mustCreateWebhook(t, store, UserA, models.WebhookEventNoteCreated)
+	if _, err := store.EnqueueDelivery(ctx, hook.ID, models.WebhookEventPing, []byte(`{}`)); err != nil {
+		t.Fatalf("EnqueueDelivery: %v", err)
+	}
+
+	if err := store.DeleteWebhook(ctx, hook.ID, UserA); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if claimed, err := store.ClaimDeliveries(ctx, time.Now().Add(time.Second), time.Minute, 10); err != nil || len(claimed) != 0 {
+		t.Errorf("ClaimDeliveries after delete = %v, %v; want none", claimed, err)
+	}
+}
+
+func testWebhookPrune(t *testing.T, store storage.WebhookStore) {
+	ctx := context.Background()
+	hook := mustCreateWebhook(t, store, UserA, models.WebhookEventNoteCreated)
+	for range 2 {
+		if _, err := store.EnqueueDelivery(ctx, hook.ID, models.WebhookEventPing, []byte(`{}`)); err != nil {
+			t.Fatalf("EnqueueDelivery: %v", err)
+		}
+	}
+
+	claimed, err := store.ClaimDeliveries(ctx, time.Now().Add(time.Second), time.Minute, 1)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimDeliveries = %v, %v; want one", claimed, err)
+	}
+	d := claimed[0]
+	d.Status, d.Attempts, d.NextAttemptAt = models.DeliveryFailed, 1, nil
+	if err := store.FinishAttempt(ctx, d); err != nil {
+		t.Fatalf("FinishAttempt: %v", err)
+	}
+
+	// Ожидающая доставка остается, даже если она старше границы
+	pruned, err := store.PruneDeliveries(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("PruneDeliveries: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("PruneDeliveries = %d, want 1", pruned)
+	}
+	if log, err := store.GetDeliveries(ctx, hook.ID, 10); err != nil || len(log) != 1 || log[0].Status != models.DeliveryPending {
+		t.Errorf("GetDeliveries after prune = %+v, %v; want the pending delivery", log, err)
+	}
+}
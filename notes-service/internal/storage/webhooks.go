@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"notes-service/internal/models"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// WebhookStore хранит вебхуки пользователей и очередь их доставок. Доставки
+// не удаляются после отправки и служат журналом, пока их не уберет PruneDeliveries.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, hook models.Webhook) (*models.Webhook, error)
+	// GetUserWebhooks возвращает вебхуки пользователя без ключей подписи
+	GetUserWebhooks(ctx context.Context, userID int32) ([]models.Webhook, error)
+	// GetWebhook и DeleteWebhook дают ErrWebhookNotFound и для чужого вебхука
+	GetWebhook(ctx context.Context, webhookID int32, userID int32) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID int32, userID int32) error
+
+	// EnqueueEvent ставит доставку события outbox eventID каждому вебхуку
+	// пользователя, подписанному на него. Повтор с тем же eventID доставок не добавляет.
+	EnqueueEvent(ctx context.Context, eventID int64, userID int32, event string, payload []byte) error
+	// EnqueueDelivery ставит доставку одному вебхуку независимо от подписки
+	EnqueueDelivery(ctx context.Context, webhookID int32, event string, payload []byte) (int64, error)
+	// ClaimDeliveries забирает до limit доставок, срок которых наступил к now, и
+	// откладывает их на lease, чтобы их не взяла другая реплика. Если отправка
+	// прервется, доставка вернется в очередь по истечении lease.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// FinishAttempt сохраняет итог попытки: Status, Attempts, ResponseStatus,
+	// LastError, NextAttemptAt и DeliveredAt
+	FinishAttempt(ctx context.Context, delivery models.WebhookDelivery) error
+	// GetDeliveries возвращает последние доставки вебхука, новые первыми
+	GetDeliveries(ctx context.Context, webhookID int32, limit int) ([]models.WebhookDelivery, error)
+	// PruneDeliveries удаляет завершенные доставки, созданные раньше before
+	PruneDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"notes-service/internal/models"
+)
+
+// MemoryWebhookStore хранит вебхуки и доставки в памяти процесса
+type MemoryWebhookStore struct {
+	mu         sync.Mutex
+	hooks      map[int32]models.Webhook
+	deliveries map[int64]models.WebhookDelivery
+	// eventIDs — событие outbox каждой доставки от EnqueueEvent
+	eventIDs       map[int64]int64
+	nextHookID     int32
+	nextDeliveryID int64
+}
+
+func NewMemoryWebhookStore() *MemoryWebhookStore {
+	return &MemoryWebhookStore{
+		hooks:      make(map[int32]models.Webhook),
+		deliveries: make(map[int64]models.WebhookDelivery),
+		eventIDs:   make(map[int64]int64),
+	}
+}
+
+func (s *MemoryWebhookStore) CreateWebhook(ctx context.Context, hook models.Webhook) (*models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextHookID++
+	hook.ID = s.nextHookID
+	hook.CreatedAt = time.Now().UTC()
+	s.hooks[hook.ID] = hook
+	return &hook, nil
+}
+
+func (s *MemoryWebhookStore) GetUserWebhooks(ctx context.Context, userID int32) ([]models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hooks []models.Webhook
+	for _, hook := range s.hooks {
+		if hook.UserID == userID {
+			hook.Secret = ""
+			hooks = append(hooks, hook)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+	return hooks, nil
+}
+
+func (s *MemoryWebhookStore) GetWebhook(ctx context.Context, webhookID int32, userID int32) (*models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hook, ok := s.hooks[webhookID]
+	if !ok || hook.UserID != userID {
+		return nil, ErrWebhookNotFound
+	}
+	return &hook, nil
+}
+
+func (s *MemoryWebhookStore) DeleteWebhook(ctx context.Context, webhookID int32, userID int32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hook, ok := s.hooks[webhookID]
+	if !ok || hook.UserID != userID {
+		return ErrWebhookNotFound
+	}
+	delete(s.hooks, webhookID)
+	for id, d := range s.deliveries {
+		if d.WebhookID == webhookID {
+			delete(s.deliveries, id)
+			delete(s.eventIDs, id)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryWebhookStore) EnqueueEvent(ctx context.Context, eventID int64, userID int32, event string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enqueued := make(map[int32]bool)
+	for id, e := range s.eventIDs {
+		if e == eventID {
+			enqueued[s.deliveries[id].WebhookID] = true
+		}
+	}
+	for _, hook := range s.hooks {
+		if hook.UserID == userID && slices.Contains(hook.Events, event) && !enqueued[hook.ID] {
+			s.eventIDs[s.enqueue(hook.ID, event, payload)] = eventID
+		}
+	}
+	return nil
+}
+
+func (s *MemoryWebhookStore) EnqueueDelivery(ctx context.Context, webhookID int32, event string, payload []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enqueue(webhookID, event, payload), nil
+}
+
+func (s *MemoryWebhookStore) enqueue(webhookID int32, event string, payload []byte) int64 {
+	now := time.Now().UTC()
+	s.nextDeliveryID++
+	s.deliveries[s.nextDeliveryID] = models.WebhookDelivery{
+		ID:            s.nextDeliveryID,
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       slices.Clone(payload),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+	return s.nextDeliveryID
+}
+
+func (s *MemoryWebhookStore) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []models.WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	leaseUntil := now.Add(lease).UTC()
+	for i, d := range due {
+		stored := s.deliveries[d.ID]
+		stored.NextAttemptAt = &leaseUntil
+		s.deliveries[d.ID] = stored
+
+		hook := s.hooks[d.WebhookID]
+		due[i].URL, due[i].Secret = hook.URL, hook.Secret
+	}
+	return due, nil
+}
+
+func (s *MemoryWebhookStore) FinishAttempt(ctx context.Context, d models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.deliveries[d.ID]
+	if !ok {
+		return nil
+	}
+	stored.Status = d.Status
+	stored.Attempts = d.Attempts
+	stored.ResponseStatus = d.ResponseStatus
+	stored.LastError = d.LastError
+	stored.NextAttemptAt = d.NextAttemptAt
+	stored.DeliveredAt = d.DeliveredAt
+	s.deliveries[d.ID] = stored
+	return nil
+}
+
+func (s *MemoryWebhookStore) GetDeliveries(ctx context.Context, webhookID int32, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.WebhookID == webhookID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (s *MemoryWebhookStore) PruneDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pruned int64
+	for id, d := range s.deliveries {
+		if d.Status != models.DeliveryPending && d.CreatedAt.Before(before) {
+			delete(s.deliveries, id)
+			delete(s.eventIDs, id)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
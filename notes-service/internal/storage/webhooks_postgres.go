@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"notes-service/internal/models"
+)
+
+// Время в таблицах вебхуков хранится в UTC
+type PostgresWebhookStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresWebhookStore(pool *pgxpool.Pool) *PostgresWebhookStore {
+	return &PostgresWebhookStore{pool: pool}
+}
+
+const deliveryColumns = "id, webhook_id, event, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, delivered_at"
+
+func (s *PostgresWebhookStore) CreateWebhook(ctx context.Context, hook models.Webhook) (*models.Webhook, error) {
+	hook.CreatedAt = time.Now().UTC()
+	err := s.pool.QueryRow(ctx,
+		"INSERT INTO webhooks (user_id, url, events, secret, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		hook.UserID, hook.URL, strings.Join(hook.Events, ","), hook.Secret, hook.CreatedAt).Scan(&hook.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting webhook: %w", err)
+	}
+	return &hook, nil
+}
+
+func (s *PostgresWebhookStore) GetUserWebhooks(ctx context.Context, userID int32) ([]models.Webhook, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT id, user_id, url, events, created_at FROM webhooks WHERE user_id = $1 ORDER BY id",
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhooks: %w", err)
+	}
+
+	hooks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Webhook, error) {
+		var hook models.Webhook
+		var events string
+		err := row.Scan(&hook.ID, &hook.UserID, &hook.URL, &events, &hook.CreatedAt)
+		hook.Events = splitEvents(events)
+		return hook, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning webhook: %w", err)
+	}
+	return hooks, nil
+}
+
+func (s *PostgresWebhookStore) GetWebhook(ctx context.Context, webhookID int32, userID int32) (*models.Webhook, error) {
+	var hook models.Webhook
+	var events string
+	err := s.pool.QueryRow(ctx,
+		"SELECT id, user_id, url, events, secret, created_at FROM webhooks WHERE id = $1 AND user_id = $2",
+		webhookID, userID).Scan(&hook.ID, &hook.UserID, &hook.URL, &events, &hook.Secret, &hook.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook: %w", err)
+	}
+	hook.Events = splitEvents(events)
+	return &hook, nil
+}
+
+func (s *PostgresWebhookStore) DeleteWebhook(ctx context.Context, webhookID int32, userID int32) error {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", webhookID, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) EnqueueEvent(ctx context.Context, eventID int64, userID int32, event string, payload []byte) error {
+	now := time.Now().UTC()
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event, payload, next_attempt_at, created_at)
+		SELECT id, $5, $2::varchar, $3::text, $4::timestamp, $4::timestamp FROM webhooks
+		WHERE user_id = $1 AND ',' || events || ',' LIKE '%,' || $2 || ',%'
+		ON CONFLICT (webhook_id, event_id) WHERE event_id IS NOT NULL DO NOTHING`,
+		userID, event, string(payload), now, eventID)
+	if err != nil {
+		return fmt.Errorf("error enqueueing webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) EnqueueDelivery(ctx context.Context, webhookID int32, event string, payload []byte) (int64, error) {
+	now := time.Now().UTC()
+	var id int64
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $4) RETURNING id`,
+		webhookID, event, string(payload), now).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error enqueueing webhook delivery: %w", err)
+	}
+	return id, nil
+}
+
+func (s *PostgresWebhookStore) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	// SKIP LOCKED: реплики разбирают очередь параллельно, не ожидая друг друга
+	rows, err := s.pool.Query(ctx,
+		`WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = $2
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_status,
+			d.last_error, d.next_attempt_at, d.created_at, d.delivered_at, w.url, w.secret`,
+		now.UTC(), now.Add(lease).UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+
+	deliveries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.WebhookDelivery, error) {
+		var d models.WebhookDelivery
+		var payload string
+		err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseStatus,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt, &d.URL, &d.Secret)
+		d.Payload = []byte(payload)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *PostgresWebhookStore) FinishAttempt(ctx context.Context, d models.WebhookDelivery) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, response_status = $4, last_error = $5, next_attempt_at = $6, delivered_at = $7
+		WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.ResponseStatus, d.LastError, utcPtr(d.NextAttemptAt), utcPtr(d.DeliveredAt))
+	if err != nil {
+		return fmt.Errorf("error updating webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresWebhookStore) GetDeliveries(ctx context.Context, webhookID int32, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2",
+		webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook deliveries: %w", err)
+	}
+
+	deliveries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.WebhookDelivery, error) {
+		var d models.WebhookDelivery
+		var payload string
+		err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseStatus,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+		d.Payload = []byte(payload)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *PostgresWebhookStore) PruneDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx,
+		"DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1",
+		before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("error pruning webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func splitEvents(events string) []string {
+	if events == "" {
+		return nil
+	}
+	return strings.Split(events, ",")
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
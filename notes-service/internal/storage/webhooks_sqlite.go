@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"notes-service/internal/models"
+)
+
+// SQLiteWebhookStore хранит вебхуки в SQLite однонодового режима; схему
+// создают миграции NewSQLiteRepository.
+type SQLiteWebhookStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteWebhookStore(db *sql.DB) *SQLiteWebhookStore {
+	return &SQLiteWebhookStore{db: db}
+}
+
+func (s *SQLiteWebhookStore) CreateWebhook(ctx context.Context, hook models.Webhook) (*models.Webhook, error) {
+	hook.CreatedAt = time.Now().UTC()
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO webhooks (user_id, url, events, secret, created_at) VALUES (?, ?, ?, ?, ?) RETURNING id",
+		hook.UserID, hook.URL, strings.Join(hook.Events, ","), hook.Secret, hook.CreatedAt).Scan(&hook.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting webhook: %w", err)
+	}
+	return &hook, nil
+}
+
+func (s *SQLiteWebhookStore) GetUserWebhooks(ctx context.Context, userID int32) ([]models.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, user_id, url, events, created_at FROM webhooks WHERE user_id = ? ORDER BY id",
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var hooks []models.Webhook
+	for rows.Next() {
+		var hook models.Webhook
+		var events string
+		if err := rows.Scan(&hook.ID, &hook.UserID, &hook.URL, &events, &hook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning webhook: %w", err)
+		}
+		hook.Events = splitEvents(events)
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *SQLiteWebhookStore) GetWebhook(ctx context.Context, webhookID int32, userID int32) (*models.Webhook, error) {
+	var hook models.Webhook
+	var events string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, user_id, url, events, secret, created_at FROM webhooks WHERE id = ? AND user_id = ?",
+		webhookID, userID).Scan(&hook.ID, &hook.UserID, &hook.URL, &events, &hook.Secret, &hook.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook: %w", err)
+	}
+	hook.Events = splitEvents(events)
+	return &hook, nil
+}
+
+func (s *SQLiteWebhookStore) DeleteWebhook(ctx context.Context, webhookID int32, userID int32) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ? AND user_id = ?", webhookID, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteWebhookStore) EnqueueEvent(ctx context.Context, eventID int64, userID int32, event string, payload []byte) error {
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event, payload, next_attempt_at, created_at)
+		SELECT id, ?, ?, ?, ?, ? FROM webhooks
+		WHERE user_id = ? AND ',' || events || ',' LIKE '%,' || ? || ',%'
+		ON CONFLICT (webhook_id, event_id) WHERE event_id IS NOT NULL DO NOTHING`,
+		eventID, event, string(payload), now, now, userID, event)
+	if err != nil {
+		return fmt.Errorf("error enqueueing webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteWebhookStore) EnqueueDelivery(ctx context.Context, webhookID int32, event string, payload []byte) (int64, error) {
+	now := time.Now().UTC()
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		webhookID, event, string(payload), now, now).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error enqueueing webhook delivery: %w", err)
+	}
+	return id, nil
+}
+
+// ClaimDeliveries выбирает и откладывает доставки в одной транзакции; другой
+// реплики у SQLite нет, так что блокировки строк не нужны.
+func (s *SQLiteWebhookStore) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_status,
+			d.last_error, d.next_attempt_at, d.created_at, d.delivered_at, w.url, w.secret
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at
+		LIMIT ?`,
+		now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload string
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseStatus,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt, &d.URL, &d.Secret)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+
+	leaseUntil := now.Add(lease).UTC()
+	for _, d := range deliveries {
+		if _, err := tx.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?", leaseUntil, d.ID); err != nil {
+			return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *SQLiteWebhookStore) FinishAttempt(ctx context.Context, d models.WebhookDelivery) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, response_status = ?, last_error = ?, next_attempt_at = ?, delivered_at = ?
+		WHERE id = ?`,
+		d.Status, d.Attempts, d.ResponseStatus, d.LastError, utcPtr(d.NextAttemptAt), utcPtr(d.DeliveredAt), d.ID)
+	if err != nil {
+		return fmt.Errorf("error updating webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteWebhookStore) GetDeliveries(ctx context.Context, webhookID int32, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?",
+		webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload string
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseStatus,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *SQLiteWebhookStore) PruneDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < ?",
+		before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("error pruning webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
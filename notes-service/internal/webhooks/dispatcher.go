@@ -0,0 +1,196 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"notes-service/internal/config"
+	"notes-service/internal/metrics"
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+const (
+	// Сколько доставок отправляется параллельно за один проход
+	batchSize     = 20
+	pruneInterval = time.Hour
+	maxErrorLen   = 500
+)
+
+// Dispatcher отправляет доставки из очереди. Реплики работают с общей
+// очередью: ClaimDeliveries не отдает одну доставку двум репликам.
+type Dispatcher struct {
+	store  storage.WebhookStore
+	cfg    config.Webhooks
+	client *http.Client
+	// Доставка, не завершенная за lease (например, реплика упала), вернется в очередь
+	lease time.Duration
+}
+
+func NewDispatcher(store storage.WebhookStore, cfg config.Webhooks) *Dispatcher {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivate {
+		dialer.Control = denyPrivate
+	}
+
+	return &Dispatcher{
+		store: store,
+		cfg:   cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// Прокси из окружения обошел бы проверку адреса в dialer
+			Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: nil},
+			// Редирект мог бы увести запрос на внутренний адрес; 3xx считается ошибкой
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		lease: cfg.Timeout + 30*time.Second,
+	}
+}
+
+// denyPrivate проверяет уже разрешенный адрес соединения
+func denyPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// Run разбирает очередь, пока не отменен ctx
+func (d *Dispatcher) Run(ctx context.Context) {
+	poll := time.NewTicker(d.cfg.PollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			d.dispatch(ctx)
+		case <-prune.C:
+			n, err := d.store.PruneDeliveries(ctx, time.Now().Add(-d.cfg.LogRetention))
+			if err != nil {
+				slog.ErrorContext(ctx, "Error pruning webhook deliveries", "error", err)
+			} else if n > 0 {
+				slog.InfoContext(ctx, "Pruned webhook deliveries", "count", n)
+			}
+		}
+	}
+}
+
+// dispatch отправляет все доставки, срок которых наступил, пачками по batchSize
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.store.ClaimDeliveries(ctx, time.Now(), d.lease, batchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error claiming webhook deliveries", "error", err)
+			return
+		}
+
+		done := make(chan struct{}, len(deliveries))
+		for _, delivery := range deliveries {
+			go func() {
+				d.attempt(ctx, delivery)
+				done <- struct{}{}
+			}()
+		}
+		for range deliveries {
+			<-done
+		}
+
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery models.WebhookDelivery) {
+	status, err := d.send(ctx, delivery)
+	// При остановке сервиса попытка не засчитывается: доставка вернется в очередь после lease
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.LastError = ""
+	delivery.NextAttemptAt = nil
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		metrics.WebhookAttempt("success")
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = truncate(err.Error(), maxErrorLen)
+		metrics.WebhookAttempt("failed")
+	default:
+		next := now.Add(backoff(delivery.Attempts, d.cfg.BackoffBase, d.cfg.BackoffMax))
+		delivery.NextAttemptAt = &next
+		delivery.LastError = truncate(err.Error(), maxErrorLen)
+		metrics.WebhookAttempt("retry")
+	}
+
+	if err := d.store.FinishAttempt(ctx, delivery); err != nil {
+		slog.ErrorContext(ctx, "Error saving webhook delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// send выполняет один запрос; успех — любой ответ 2xx
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "notes-service-webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Дочитываем немного тела, чтобы соединение могло вернуться в пул
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff — пауза перед повтором после attempts неудачных попыток:
+// base, 2·base, 4·base и так далее, но не больше max
+func backoff(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,53 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+)
+
+// Publisher ставит доставки событий outbox вебхукам их владельцев. События
+// пишет в outbox транзакция изменения заметки, поэтому вебхуки получают
+// изменения из любого источника: REST, синхронизации и совместного редактирования.
+// Relay повторяет пачку после ошибки, но доставка одного события не задваивается.
+type Publisher struct {
+	store storage.WebhookStore
+}
+
+func NewPublisher(store storage.WebhookStore) *Publisher {
+	return &Publisher{store: store}
+}
+
+func (p *Publisher) Publish(ctx context.Context, events []models.NoteEvent) error {
+	for _, event := range events {
+		data, err := eventData(event)
+		if err != nil {
+			return fmt.Errorf("error decoding note event %d: %w", event.ID, err)
+		}
+		if err := p.store.EnqueueEvent(ctx, event.ID, event.UserID, event.Type, Payload(event.Type, data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventData — данные доставки: id заметки, а для созданной и измененной
+// еще заголовок и текст
+func eventData(event models.NoteEvent) (map[string]any, error) {
+	if event.Type == models.NoteDeleted {
+		return map[string]any{"note_id": event.NoteID}, nil
+	}
+	var note models.Note
+	if err := json.Unmarshal(event.Payload, &note); err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"note_id": note.ID,
+		"title":   note.Title,
+		"content": note.Content,
+		"version": note.Version,
+	}, nil
+}
@@ -0,0 +1,88 @@
+// Package webhooks доставляет события заметок на адреса пользователей.
+// Доставки ставятся в очередь в базе (storage.WebhookStore), а Dispatcher
+// отправляет их в фоне с подписью HMAC-SHA256 и повторами.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Заголовки доставки. Подпись — HMAC-SHA256 ключом вебхука от строки
+// "<X-Webhook-Timestamp>.<тело запроса>"; получатель сверяет ее и отбрасывает
+// старые метки времени, чтобы запрос нельзя было повторить.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var (
+	ErrInvalidURL     = errors.New("webhook url must be an absolute http or https url")
+	ErrPrivateAddress = errors.New("webhook url points to a private or local address")
+)
+
+// Sign возвращает значение X-Webhook-Signature
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret создает ключ подписи нового вебхука
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Payload собирает тело доставки: событие, время и данные события
+func Payload(event string, data any) []byte {
+	body, _ := json.Marshal(map[string]any{
+		"event":      event,
+		"created_at": time.Now().UTC(),
+		"data":       data,
+	})
+	return body
+}
+
+// ValidateURL проверяет адрес при создании вебхука. Имена хостов
+// проверяются еще раз при соединении, после разрешения в IP.
+func ValidateURL(raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	if allowPrivate {
+		return nil
+	}
+	if u.Hostname() == "localhost" {
+		return ErrPrivateAddress
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && isPrivate(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// cgnat — общий адресный блок операторов (RFC 6598), тоже недоступный извне
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnat.Contains(ip)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	authapp "auth-service/app"
+	"common/config"
+	"common/health"
+	"common/httpserver"
+	"common/logging"
+	"common/metrics"
+	"common/route"
+	"common/tracing"
+	notesapp "notes-service/app"
+)
+
+// Config — настройки однонодового процесса. Секции auth и notes читают
+// те же переменные окружения, что и отдельные сервисы (JWT_SECRET, REGISTRATION_MODE, ...).
+type Config struct {
+	Port       int    `yaml:"port" env:"PORT" default:"8080"`
+	SQLitePath string `yaml:"sqlite_path" env:"SQLITE_PATH" default:"notes-manager.db"`
+	StaticDir  string `yaml:"static_dir" env:"STATIC_DIR"`
+
+	HTTP    config.HTTP    `yaml:"http"`
+	Tracing config.Tracing `yaml:"tracing"`
+	Logging config.Logging `yaml:"logging"`
+
+	Auth  authapp.Config  `yaml:"auth"`
+	Notes notesapp.Config `yaml:"notes"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	var cfg Config
+	if err := config.Load(&cfg, path); err != nil {
+		return nil, err
+	}
+
+	// Оба сервиса работают поверх общего SQLite-файла
+	cfg.Auth.Storage, cfg.Auth.SQLitePath = "sqlite", cfg.SQLitePath
+	cfg.Notes.Storage, cfg.Notes.SQLitePath = "sqlite", cfg.SQLitePath
+	// Сервер принимает запросы напрямую, без nginx: X-Real-IP задает сам клиент
+	cfg.Auth.RateLimit.TrustProxy, cfg.Notes.RateLimit.TrustProxy = false, false
+
+	var errs config.Errors
+	config.ValidatePort(&errs, cfg.Port)
+	cfg.HTTP.Validate(&errs)
+	cfg.Tracing.Validate(&errs)
+	cfg.Logging.Validate(&errs)
+	for _, err := range []error{cfg.Auth.Validate(), cfg.Notes.Validate()} {
+		if e, ok := err.(config.Errors); ok {
+			for _, msg := range e {
+				errs.Addf("%s", msg)
+			}
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Однонодовый режим: auth-service и notes-service в одном процессе поверх
+// одного SQLite-файла, без Postgres и Redis. При заданном STATIC_DIR
+// отдает и фронтенд, так что nginx тоже не нужен.
+func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to YAML config file")
+	printConfig := flag.Bool("print-config", false, "print effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *printConfig {
+		if err := config.Dump(os.Stdout, cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	logging.Setup("notes-manager", cfg.Logging)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, "notes-manager", cfg.Tracing)
+	if err != nil {
+		fatal("Error setting up tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, err := notesapp.OpenSQLite(ctx, cfg.SQLitePath)
+	if err != nil {
+		fatal("Error opening sqlite database", err)
+	}
+	defer db.Close()
+
+	checks := health.New(2 * time.Second)
+	checks.Add("shutdown", health.ShuttingDown(ctx))
+	checks.Add("sqlite", db.PingContext)
+
+	authHandler, err := authapp.NewSQLiteHandler(ctx, db, &cfg.Auth, checks)
+	if err != nil {
+		fatal("Error starting auth service", err)
+	}
+	notesHandler, waitNotes, err := notesapp.NewSQLiteHandler(ctx, db, &cfg.Notes, checks)
+	if err != nil {
+		fatal("Error starting notes service", err)
+	}
+	// Выполняется раньше db.Close: сеансы совместного редактирования дописывают текст в базу
+	defer waitNotes()
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/auth/", authHandler)
+	mux.Handle("/api/notes", notesHandler)
+	mux.Handle("/api/notes/", notesHandler)
+	mux.Handle("/api/webhooks", notesHandler)
+	mux.Handle("/api/webhooks/", notesHandler)
+	mux.HandleFunc("/healthz", checks.Liveness)
+	mux.HandleFunc("/readyz", checks.Readiness)
+	mux.HandleFunc("/health", checks.Liveness)
+	mux.Handle("/metrics", metrics.Handler())
+	if cfg.StaticDir != "" {
+		mux.Handle("/", http.FileServer(http.Dir(cfg.StaticDir)))
+	}
+
+	slog.Info("Notes manager starting in single node mode", "port", cfg.Port, "path", cfg.SQLitePath)
+	handler := tracing.Middleware("notes-manager", metrics.Middleware(logging.Middleware(route.Record(mux))))
+	if err := httpserver.Run(ctx, fmt.Sprintf(":%d", cfg.Port), handler, cfg.HTTP); err != nil {
+		slog.Error("Server error", "error", err)
+		return
+	}
+	slog.Info("Notes manager stopped")
+}
+
+// fatal пишет ошибку в журнал и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}